@@ -215,6 +215,15 @@ func completerTrueFalse() (options []readline.PrefixCompleterInterface) {
 	return
 }
 
+// Signature policy options for auto completer
+func completerSignaturePolicy() (options []readline.PrefixCompleterInterface) {
+	options = append(options, readline.PcItem(tela.SIGNATURE_OFF.String()))
+	options = append(options, readline.PcItem(tela.SIGNATURE_WARN.String()))
+	options = append(options, readline.PcItem(tela.SIGNATURE_ENFORCE.String()))
+
+	return
+}
+
 // Yes/no options for auto completer
 func completerYesNo() (options []readline.PrefixCompleterInterface) {
 	options = append(options, readline.PcItem("n"))
@@ -909,6 +918,7 @@ func (t *tela_cli) getCLIInfo() (allInfo []string) {
 
 	_, servers, local := t.getServerInfo()
 	allInfo = append(allInfo, fmt.Sprintf("Active servers: %d/%d", servers, tela.MaxServers()+1))
+	allInfo = append(allInfo, fmt.Sprintf("Max workers: %d", tela.MaxWorkers()))
 	if servers > 0 {
 		allInfo = append(allInfo, fmt.Sprintf("Local server running: %t", local))
 
@@ -917,6 +927,7 @@ func (t *tela_cli) getCLIInfo() (allInfo []string) {
 	allInfo = append(allInfo, fmt.Sprintf("Search minimum likes: %.0f%%", t.minLikes))
 	allInfo = append(allInfo, fmt.Sprintf("Open content in browser: %t", t.openInBrowser))
 	allInfo = append(allInfo, fmt.Sprintf("Updated content allowed: %t", tela.UpdatesAllowed()))
+	allInfo = append(allInfo, fmt.Sprintf("Signature policy: %s", tela.GetSignaturePolicy()))
 
 	allInfo = append(allInfo, margin)
 
@@ -1026,6 +1037,124 @@ func (t *tela_cli) paging(resultLines [][]string) (err error) {
 	return
 }
 
+// Get the TXID of a TELA-INDEX commit, commit can be a TXID or commit number
+func (t *tela_cli) getCommitTXID(scid, commit string) (txid string, err error) {
+	if len(commit) == 64 {
+		txid = commit
+		return
+	}
+
+	number, err := strconv.ParseUint(commit, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("invalid commit %q", commit)
+		return
+	}
+
+	history, err := tela.GetINDEXHistory(scid, t.endpoint)
+	if err != nil {
+		return
+	}
+
+	if number >= uint64(len(history)) {
+		err = fmt.Errorf("commit %d not found, latest commit is %d", number, len(history)-1)
+		return
+	}
+
+	txid = history[number].TXID
+
+	return
+}
+
+// Print the changes of a TELA-INDEX diff
+func printINDEXDiff(diff tela.INDEXDiff) {
+	logger.Printf("[%s] Diff %s\n", appName, diff.SCID)
+	fmt.Println(searchDivider)
+	fmt.Printf("From: %s\n", diff.From)
+	fmt.Printf("To:   %s\n", diff.To)
+	fmt.Println(searchDivider)
+
+	if diff.Empty() {
+		logger.Printf("[%s] No changes found\n", appName)
+		return
+	}
+
+	for _, h := range diff.Headers {
+		fmt.Printf("%s%s:%s %q -> %q\n", logger.Color.Grey(), h.Header.Trim(), logger.Color.End(), h.From, h.To)
+	}
+
+	for _, scid := range diff.Added {
+		fmt.Printf("%s+ %s%s\n", logger.Color.Green(), scid, logger.Color.End())
+	}
+
+	for _, scid := range diff.Removed {
+		fmt.Printf("%s- %s%s\n", logger.Color.Red(), scid, logger.Color.End())
+	}
+
+	for _, scid := range diff.Libraries.Added {
+		fmt.Printf("%sLibrary added:%s %s\n", logger.Color.Grey(), logger.Color.End(), scid)
+	}
+
+	for _, scid := range diff.Libraries.Removed {
+		fmt.Printf("%sLibrary removed:%s %s\n", logger.Color.Grey(), logger.Color.End(), scid)
+	}
+
+	for _, r := range diff.Replaced {
+		fmt.Println(searchDivider)
+		fmt.Printf("%s%s:%s %s -> %s\n", logger.Color.Grey(), r.DOCNum, logger.Color.End(), r.From, r.To)
+		if r.Diff == "" {
+			continue
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(r.Diff, "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				fmt.Println(line)
+			case strings.HasPrefix(line, "+"):
+				fmt.Printf("%s%s%s\n", logger.Color.Green(), line, logger.Color.End())
+			case strings.HasPrefix(line, "-"):
+				fmt.Printf("%s%s%s\n", logger.Color.Red(), line, logger.Color.End())
+			case strings.HasPrefix(line, "@@"):
+				fmt.Printf("%s%s%s\n", logger.Color.Cyan(), line, logger.Color.End())
+			default:
+				fmt.Println(line)
+			}
+		}
+	}
+
+	fmt.Println(searchDivider)
+}
+
+// Print the contracts of an install plan and any unsupported files
+func printInstallPlan(plan tela.InstallPlan) {
+	logger.Printf("[%s] Install plan for %s\n", appName, plan.Path)
+	fmt.Println(searchDivider)
+	fmt.Printf("dURL: %s  Entrypoint: %s\n", plan.DURL, plan.Entrypoint)
+	fmt.Println(searchDivider)
+
+	for i, doc := range plan.DOCs {
+		name := doc.File
+		if doc.Parts > 0 {
+			name = fmt.Sprintf("%s (part %d of %d)", doc.File, doc.Part, doc.Parts)
+		}
+
+		encoding := doc.Encoding
+		if encoding == "" {
+			encoding = "-"
+		}
+
+		fmt.Printf("%sDOC%d:%s %-13s  Size: %.2fKB  Gas: %-6d  Encoding: %-11s  %s\n", logger.Color.Grey(), i+1, logger.Color.End(), doc.DocType, doc.Size, doc.Gas, encoding, name)
+	}
+
+	fmt.Printf("%sINDEX:%s %s  DOCs: %d  Size: %.2fKB  Gas: %d\n", logger.Color.Grey(), logger.Color.End(), plan.INDEX.NameHdr, plan.INDEX.DOCs, plan.INDEX.Size, plan.INDEX.Gas)
+
+	for _, f := range plan.Unsupported {
+		fmt.Printf("%sUnsupported:%s %s (%s)\n", logger.Color.Red(), logger.Color.End(), f.File, f.Reason)
+	}
+
+	fmt.Println(searchDivider)
+	fmt.Printf("Contracts: %d  Size: %.2fKB  Gas: %d\n", len(plan.DOCs)+1, plan.Size, plan.Gas)
+}
+
 // Get the type of TELA contract from scid
 func getSCType(scid string) (scType string) {
 	scType = "?"
@@ -86,6 +86,7 @@ endpoint remote              - Set the network to mainnet and daemon endpoint to
 endpoint close               - Close connection with current daemon endpoint
 
 clone <scid>                 - Clone TELA content from SCID
+diff <scid> <from> <to>      - Diff TELA-INDEX commits by TXID or commit number, if <to> is not given <from> is compared to the live state
 
 mv <source> <destination>    - Move a file or directory
 rm <source>                  - Remove a file or directory, it will only remove from within the datashards/clone directory
@@ -101,7 +102,9 @@ shutdown local               - Shutdown local directory server only
 page-size <20>               - Set the max page size when displaying search results
 port-start <8082>            - Set the port to start serving TELA servers from
 max-servers <20>             - Set the maximum amount of TELA servers which can be active at once
+max-workers <4>              - Set the maximum amount of contracts fetched at once when cloning or serving
 updates <false>              - Set updates true/false to allow or deny updated TELA content when cloning or serving
+signatures <warn>            - Set signatures off/warn/enforce to verify DOC signatures against their owner when cloning or serving
 browser <true>               - Set browser true/false to open content in default browser
 colors <true>                - Set colors true/false to enable terminal colors
 
@@ -110,6 +113,7 @@ wallet close                 - Close wallet file if active
 
 rate <scid>                  - Rate a TELA smart contract
 install-doc <file.html>      - Start guided TELA-DOC smart contract install
+plan-install <dir>           - Plan the install of a directory as TELA-DOCs and a TELA-INDEX, the plan can be exported as JSON
 install-index <name>         - Start guided TELA-INDEX smart contract install
 update-index <scid>          - Start guided TELA-INDEX smart contract update
 
@@ -146,6 +150,7 @@ func main() {
 			completerNetworks(true)...,
 		),
 		readline.PcItem("clone"),
+		readline.PcItem("diff"),
 		readline.PcItem("mv",
 			completerFiles(".", completerFiles(".")),
 		),
@@ -168,9 +173,13 @@ func main() {
 		readline.PcItem("page-size"),
 		readline.PcItem("port-start"),
 		readline.PcItem("max-servers"),
+		readline.PcItem("max-workers"),
 		readline.PcItem("updates",
 			completerTrueFalse()...,
 		),
+		readline.PcItem("signatures",
+			completerSignaturePolicy()...,
+		),
 		readline.PcItem("browser",
 			readline.PcItem("true"),
 			readline.PcItem("false"),
@@ -184,6 +193,7 @@ func main() {
 		),
 		readline.PcItem("rate"),
 		readline.PcItem("install-doc", completerFiles(".")),
+		readline.PcItem("plan-install", completerFiles(".")),
 		readline.PcItem("install-index"),
 		readline.PcItem("update-index"),
 		readline.PcItem("gnomon",
@@ -473,6 +483,63 @@ func main() {
 					continue
 				}
 			}
+		case "diff":
+			if !walletapi.IsDaemonOnline() {
+				logger.Errorf("[%s] Daemon %s not online to diff\n", appName, app.endpoint)
+				continue
+			}
+
+			if args == nil {
+				line, err := app.readLine("Enter SCID", "")
+				if err != nil {
+					if readError(err) {
+						return
+					}
+					continue
+				}
+
+				args = []string{line}
+			}
+
+			if len(args[0]) != 64 {
+				logger.Errorf("[%s] Invalid SCID: %q\n", appName, args[0])
+				continue
+			}
+
+			if len(args) < 2 {
+				line, err := app.readLine("Enter commit TXID or number to diff from", "0")
+				if err != nil {
+					if readError(err) {
+						return
+					}
+					continue
+				}
+
+				args = append(args, line)
+			}
+
+			from, err := app.getCommitTXID(args[0], args[1])
+			if err != nil {
+				logger.Errorf("[%s] Diff: %s\n", appName, err)
+				continue
+			}
+
+			var to string
+			if len(args) > 2 {
+				to, err = app.getCommitTXID(args[0], args[2])
+				if err != nil {
+					logger.Errorf("[%s] Diff: %s\n", appName, err)
+					continue
+				}
+			}
+
+			diff, err := tela.DiffINDEX(args[0], from, to, app.endpoint)
+			if err != nil {
+				logger.Errorf("[%s] Diff: %s\n", appName, err)
+				continue
+			}
+
+			printINDEXDiff(diff)
 		case "mv":
 			if args == nil {
 				completer := readline.NewPrefixCompleter(completerFiles("."))
@@ -756,6 +823,27 @@ func main() {
 
 			tela.SetMaxServers(int(u))
 			logger.Printf("[%s] Max TELA servers set to: %d\n", appName, tela.MaxServers())
+		case "max-workers":
+			if args == nil {
+				line, err := app.readLine("Set max workers", "")
+				if err != nil {
+					if readError(err) {
+						return
+					}
+					continue
+				}
+
+				args = []string{line}
+			}
+
+			u, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				logger.Errorf("[%s] %s\n", appName, err)
+				continue
+			}
+
+			tela.SetMaxWorkers(int(u))
+			logger.Printf("[%s] Max TELA workers set to: %d\n", appName, tela.MaxWorkers())
 		case "updates":
 			if args == nil {
 				completer := readline.NewPrefixCompleter(completerTrueFalse()...)
@@ -778,6 +866,33 @@ func main() {
 
 			tela.AllowUpdates(b)
 			logger.Printf("[%s] Updates allowed: %t\n", appName, tela.UpdatesAllowed())
+		case "signatures":
+			if args == nil {
+				completer := readline.NewPrefixCompleter(completerSignaturePolicy()...)
+				line, err := app.readLineWithCompleter("Set signatures (off/warn/enforce)", "", completer)
+				if err != nil {
+					if readError(err) {
+						return
+					}
+					continue
+				}
+
+				args = []string{line}
+			}
+
+			policy, err := tela.ParseSignaturePolicy(args[0])
+			if err != nil {
+				logger.Errorf("[%s] Signatures: %s\n", appName, err)
+				continue
+			}
+
+			err = tela.SetSignaturePolicy(policy)
+			if err != nil {
+				logger.Errorf("[%s] Signatures: %s\n", appName, err)
+				continue
+			}
+
+			logger.Printf("[%s] Signature policy: %s\n", appName, tela.GetSignaturePolicy())
 		case "browser":
 			if args == nil {
 				completer := readline.NewPrefixCompleter(completerTrueFalse()...)
@@ -1059,6 +1174,18 @@ func main() {
 				continue
 			}
 
+			// Binary files such as images, fonts and wasm are installed base64 encoded
+			if tela.IsBinaryCode(docCode) {
+				if docType != tela.DOC_STATIC {
+					logger.Errorf("[%s] %s is a binary file and must be a %s DOC\n", appName, fileName, tela.DOC_STATIC)
+					continue
+				}
+
+				logger.Printf("[%s] %s is a binary file and will be installed base64 encoded\n", appName, fileName)
+			} else if tela.ContainsCommentEnd(docCode) {
+				logger.Printf("[%s] %s contains */ which will be escaped when installed and restored when cloned\n", appName, fileName)
+			}
+
 			headers, err := app.headersPrompt("DOC", nil)
 			if err != nil {
 				if readError(err) {
@@ -1132,6 +1259,17 @@ func main() {
 				},
 			}
 
+			// Large files are split into parts which are each signed and installed
+			parts, err := tela.SplitDOC(*doc)
+			if err != nil {
+				logger.Errorf("[%s] DOC install error: %s\n", appName, err)
+				continue
+			}
+
+			if len(parts) > 1 {
+				logger.Printf("[%s] %s is to large for a single DOC and will be installed as %d parts, add all parts to its INDEX\n", appName, fileName, len(parts))
+			}
+
 			yes, err := app.readYesNo("Confirm DOC install")
 			if err != nil {
 				if readError(err) {
@@ -1146,13 +1284,106 @@ func main() {
 			}
 
 			// Install TELA DOC
-			txid, err := tela.Installer(app.wallet.disk, ringsize, doc)
+			txids, err := tela.InstallDOC(app.wallet.disk, ringsize, doc)
+			for i, txid := range txids {
+				if len(parts) > 1 {
+					logger.Printf("[%s] DOC part %d install TXID: %s\n", appName, i+1, txid)
+				} else {
+					logger.Printf("[%s] DOC install TXID: %s\n", appName, txid)
+				}
+			}
+
 			if err != nil {
 				logger.Errorf("[%s] DOC install error: %s\n", appName, err)
 				continue
 			}
+		case "plan-install":
+			// Prompt for directory path or use arg
+			var dir string
+			if args == nil {
+				completer := readline.NewPrefixCompleter(completerFiles("."))
+				line, err := app.readLineWithCompleter("Enter directory path", "", completer)
+				if err != nil {
+					if readError(err) {
+						return
+					}
+					continue
+				}
+
+				dir = line
+			} else {
+				dir = args[0]
+			}
+
+			dURL, err := app.readLine("Enter dURL", "")
+			if err != nil {
+				if readError(err) {
+					return
+				}
+				continue
+			}
+
+			entrypoint, err := app.readLine("Enter entrypoint file, empty will use index.html or the first HTML file", "")
+			if err != nil {
+				if readError(err) {
+					return
+				}
+				continue
+			}
+
+			config := tela.PlanConfig{DURL: dURL, Entrypoint: entrypoint}
+			if app.wallet.disk != nil {
+				config.Signer = app.wallet.disk.GetAddress().String()
+			}
+
+			// Gas is only estimated when connected to a daemon
+			var endpoint string
+			if walletapi.IsDaemonOnline() {
+				endpoint = app.endpoint
+			} else {
+				logger.Warnf("[%s] Daemon %s not online, gas will not be estimated\n", appName, app.endpoint)
+			}
+
+			plan, err := tela.PlanInstall(dir, endpoint, config)
+			if err != nil {
+				logger.Errorf("[%s] Plan install: %s\n", appName, err)
+				continue
+			}
+
+			printInstallPlan(plan)
+
+			yes, err := app.readYesNo("Export plan as JSON")
+			if err != nil {
+				if readError(err) {
+					return
+				}
+				continue
+			}
+
+			if !yes {
+				continue
+			}
+
+			line, err := app.readLine("Enter JSON file path", plan.DURL+"-plan.json")
+			if err != nil {
+				if readError(err) {
+					return
+				}
+				continue
+			}
+
+			b, err := plan.JSON()
+			if err != nil {
+				logger.Errorf("[%s] Plan install: %s\n", appName, err)
+				continue
+			}
+
+			if err = os.WriteFile(line, b, 0644); err != nil {
+				logger.Errorf("[%s] Plan install: %s\n", appName, err)
+				continue
+			}
 
-			logger.Printf("[%s] DOC install TXID: %s\n", appName, txid)
+			logger.Printf("[%s] Install plan exported to %s\n", appName, line)
 		case "install-index":
 			if app.wallet.disk == nil {
 				logger.Errorf("[%s] Open a wallet file to install INDEX\n", appName)
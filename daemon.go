@@ -0,0 +1,149 @@
+package tela
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/civilware/Gnomon/rwc"
+	"github.com/civilware/tela/logger"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/channel"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/deroproject/derohe/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// Daemon provides the chain data TELA requires, it can be replaced with SetDaemon to run TELA against any source
+// Implementations should return when ctx is done
+type Daemon interface {
+	GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error)                            // DERO.GetSC
+	GetTransaction(ctx context.Context, endpoint string, params rpc.GetTransaction_Params) (result rpc.GetTransaction_Result, err error) // DERO.GetTransaction
+	GetGasEstimate(ctx context.Context, endpoint string, params rpc.GasEstimate_Params) (result rpc.GasEstimate_Result, err error)       // DERO.GetGasEstimate
+}
+
+// Daemon used by a TELA host, it has its own lock as it is read by calls made while the TELA lock is held
+type daemonSource struct {
+	sync.RWMutex
+	daemon Daemon
+}
+
+// Daemon client for TELA RPC calls, its connection is reused across calls and redialed when it fails or the endpoint changes
+type daemonClient struct {
+	sync.Mutex
+	endpoint string
+	ws       *websocket.Conn
+	rpc      *jrpc2.Client
+}
+
+// Returns the active RPC client for endpoint, dialing a new connection if required
+func (d *daemonClient) connect(ctx context.Context, endpoint string) (client *jrpc2.Client, err error) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.rpc != nil && d.endpoint == endpoint {
+		client = d.rpc
+		return
+	}
+
+	d.close()
+
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, "ws://"+endpoint+"/ws", nil)
+	if err != nil {
+		return
+	}
+
+	input_output := rwc.New(ws)
+	client = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
+
+	d.ws = ws
+	d.rpc = client
+	d.endpoint = endpoint
+
+	logger.Debugf("[TELA] Connected to daemon %s\n", endpoint)
+
+	return
+}
+
+// Close the connection if client is still the active connection
+func (d *daemonClient) reset(client *jrpc2.Client) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.rpc == client {
+		d.close()
+	}
+}
+
+// Close the active connection, caller must hold the lock
+func (d *daemonClient) close() {
+	if d.rpc != nil {
+		d.rpc.Close()
+		d.rpc = nil
+	}
+
+	if d.ws != nil {
+		d.ws.Close()
+		d.ws = nil
+	}
+
+	d.endpoint = ""
+}
+
+// Close the active connection
+func (d *daemonClient) Close() {
+	d.Lock()
+	d.close()
+	d.Unlock()
+}
+
+// Call method on the daemon at endpoint, if the connection has failed it is redialed and the call is tried once more
+func (d *daemonClient) call(ctx context.Context, endpoint, method string, params, result interface{}) (err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		var client *jrpc2.Client
+		client, err = d.connect(ctx, endpoint)
+		if err != nil {
+			return
+		}
+
+		err = client.CallResult(ctx, method, params, result)
+		if err == nil {
+			return
+		}
+
+		// Call was canceled or timed out by the caller, connection is still good
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Daemon responded with an error, connection is still good. Calls pending when the
+		// connection drops are failed with an InternalError so those are retried
+		var rpcErr *jrpc2.Error
+		if errors.As(err, &rpcErr) && rpcErr.Code != code.InternalError && rpcErr.Code != code.Cancelled {
+			return
+		}
+
+		logger.Debugf("[TELA] Daemon connection %s: %s\n", endpoint, err)
+		d.reset(client)
+	}
+
+	return
+}
+
+// Get smart contract data from the daemon at endpoint
+func (d *daemonClient) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	err = d.call(ctx, endpoint, "DERO.GetSC", params, &result)
+	return
+}
+
+// Get transactions from the daemon at endpoint
+func (d *daemonClient) GetTransaction(ctx context.Context, endpoint string, params rpc.GetTransaction_Params) (result rpc.GetTransaction_Result, err error) {
+	err = d.call(ctx, endpoint, "DERO.GetTransaction", params, &result)
+	return
+}
+
+// Get a gas estimate from the daemon at endpoint
+func (d *daemonClient) GetGasEstimate(ctx context.Context, endpoint string, params rpc.GasEstimate_Params) (result rpc.GasEstimate_Result, err error) {
+	err = d.call(ctx, endpoint, "DERO.GetGasEstimate", params, &result)
+	return
+}
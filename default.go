@@ -0,0 +1,395 @@
+package tela
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/civilware/tela/shards"
+	"github.com/deroproject/derohe/walletapi"
+)
+
+// Default TELA host used by the package level functions
+var tela = newTELA()
+
+// FindOpenPort calls FindOpenPort on the default TELA host
+func FindOpenPort() (server *http.Server, found bool) {
+	return tela.FindOpenPort()
+}
+
+// Clone calls Clone on the default TELA host
+func Clone(scid, endpoint string) (err error) {
+	return tela.Clone(scid, endpoint)
+}
+
+// CloneContext calls CloneContext on the default TELA host
+func CloneContext(ctx context.Context, scid, endpoint string) (err error) {
+	return tela.CloneContext(ctx, scid, endpoint)
+}
+
+// CloneAtCommit calls CloneAtCommit on the default TELA host
+func CloneAtCommit(scid, txid, endpoint string) (err error) {
+	return tela.CloneAtCommit(scid, txid, endpoint)
+}
+
+// CloneAtCommitContext calls CloneAtCommitContext on the default TELA host
+func CloneAtCommitContext(ctx context.Context, scid, txid, endpoint string) (err error) {
+	return tela.CloneAtCommitContext(ctx, scid, txid, endpoint)
+}
+
+// ServeTELA calls ServeTELA on the default TELA host
+func ServeTELA(scid, endpoint string) (link string, err error) {
+	return tela.ServeTELA(scid, endpoint)
+}
+
+// ServeTELAContext calls ServeTELAContext on the default TELA host
+func ServeTELAContext(ctx context.Context, scid, endpoint string) (link string, err error) {
+	return tela.ServeTELAContext(ctx, scid, endpoint)
+}
+
+// ServeAtCommit calls ServeAtCommit on the default TELA host
+func ServeAtCommit(scid, txid, endpoint string) (link string, err error) {
+	return tela.ServeAtCommit(scid, txid, endpoint)
+}
+
+// ServeAtCommitContext calls ServeAtCommitContext on the default TELA host
+func ServeAtCommitContext(ctx context.Context, scid, txid, endpoint string) (link string, err error) {
+	return tela.ServeAtCommitContext(ctx, scid, txid, endpoint)
+}
+
+// OpenTELALink calls OpenTELALink on the default TELA host
+func OpenTELALink(telaLink, endpoint string) (link string, err error) {
+	return tela.OpenTELALink(telaLink, endpoint)
+}
+
+// OpenTELALinkContext calls OpenTELALinkContext on the default TELA host
+func OpenTELALinkContext(ctx context.Context, telaLink, endpoint string) (link string, err error) {
+	return tela.OpenTELALinkContext(ctx, telaLink, endpoint)
+}
+
+// ShutdownTELA calls ShutdownTELA on the default TELA host
+func ShutdownTELA() {
+	tela.ShutdownTELA()
+}
+
+// StartGateway calls StartGateway on the default TELA host
+func StartGateway(config GatewayConfig) (gateway *Gateway, err error) {
+	return tela.StartGateway(config)
+}
+
+// GetGateway calls GetGateway on the default TELA host
+func GetGateway() *Gateway {
+	return tela.GetGateway()
+}
+
+// ShutdownGateway calls ShutdownGateway on the default TELA host
+func ShutdownGateway() {
+	tela.ShutdownGateway()
+}
+
+// NewHandler calls NewHandler on the default TELA host
+func NewHandler(scid, endpoint string) (handler *Handler, err error) {
+	return tela.NewHandler(scid, endpoint)
+}
+
+// NewHandlerContext calls NewHandlerContext on the default TELA host
+func NewHandlerContext(ctx context.Context, scid, endpoint string) (handler *Handler, err error) {
+	return tela.NewHandlerContext(ctx, scid, endpoint)
+}
+
+// NewHandlerAtCommit calls NewHandlerAtCommit on the default TELA host
+func NewHandlerAtCommit(scid, txid, endpoint string) (handler *Handler, err error) {
+	return tela.NewHandlerAtCommit(scid, txid, endpoint)
+}
+
+// NewHandlerAtCommitContext calls NewHandlerAtCommitContext on the default TELA host
+func NewHandlerAtCommitContext(ctx context.Context, scid, txid, endpoint string) (handler *Handler, err error) {
+	return tela.NewHandlerAtCommitContext(ctx, scid, txid, endpoint)
+}
+
+// ShutdownServer calls ShutdownServer on the default TELA host
+func ShutdownServer(name string) {
+	tela.ShutdownServer(name)
+}
+
+// GetPath calls GetPath on the default TELA host
+func GetPath() string {
+	return tela.GetPath()
+}
+
+// GetServerInfo calls GetServerInfo on the default TELA host
+func GetServerInfo() []ServerInfo {
+	return tela.GetServerInfo()
+}
+
+// HasServer calls HasServer on the default TELA host
+func HasServer(name string) bool {
+	return tela.HasServer(name)
+}
+
+// AllowUpdates calls AllowUpdates on the default TELA host
+func AllowUpdates(b bool) {
+	tela.AllowUpdates(b)
+}
+
+// UpdatesAllowed calls UpdatesAllowed on the default TELA host
+func UpdatesAllowed() bool {
+	return tela.UpdatesAllowed()
+}
+
+// SetUpdateRule calls SetUpdateRule on the default TELA host
+func SetUpdateRule(scid string, rule UpdateRule) (err error) {
+	return tela.SetUpdateRule(scid, rule)
+}
+
+// SetAuthorUpdateRule calls SetAuthorUpdateRule on the default TELA host
+func SetAuthorUpdateRule(author string, rule UpdateRule) (err error) {
+	return tela.SetAuthorUpdateRule(author, rule)
+}
+
+// ApproveUpdate calls ApproveUpdate on the default TELA host
+func ApproveUpdate(scid, txid string) (err error) {
+	return tela.ApproveUpdate(scid, txid)
+}
+
+// GetUpdateRule calls GetUpdateRule on the default TELA host
+func GetUpdateRule(scid, author string) UpdateRule {
+	return tela.GetUpdateRule(scid, author)
+}
+
+// GetUpdateRules calls GetUpdateRules on the default TELA host
+func GetUpdateRules() (scids, authors map[string]UpdateRule) {
+	return tela.GetUpdateRules()
+}
+
+// RenderMarkdown calls RenderMarkdown on the default TELA host
+func RenderMarkdown(b bool) {
+	tela.RenderMarkdown(b)
+}
+
+// MarkdownRendered calls MarkdownRendered on the default TELA host
+func MarkdownRendered() bool {
+	return tela.MarkdownRendered()
+}
+
+// SetSignaturePolicy calls SetSignaturePolicy on the default TELA host
+func SetSignaturePolicy(policy SignaturePolicy) (err error) {
+	return tela.SetSignaturePolicy(policy)
+}
+
+// GetSignaturePolicy calls GetSignaturePolicy on the default TELA host
+func GetSignaturePolicy() SignaturePolicy {
+	return tela.GetSignaturePolicy()
+}
+
+// SetSecurityPolicy calls SetSecurityPolicy on the default TELA host
+func SetSecurityPolicy(policy SecurityPolicy) (err error) {
+	return tela.SetSecurityPolicy(policy)
+}
+
+// GetSecurityPolicy calls GetSecurityPolicy on the default TELA host
+func GetSecurityPolicy() SecurityPolicy {
+	return tela.GetSecurityPolicy()
+}
+
+// SetAppOrigins calls SetAppOrigins on the default TELA host
+func SetAppOrigins(scid string, origins ...string) (err error) {
+	return tela.SetAppOrigins(scid, origins...)
+}
+
+// SetDaemon calls SetDaemon on the default TELA host
+func SetDaemon(daemon Daemon) {
+	tela.SetDaemon(daemon)
+}
+
+// SetPortStart calls SetPortStart on the default TELA host
+func SetPortStart(port int) (err error) {
+	return tela.SetPortStart(port)
+}
+
+// PortStart calls PortStart on the default TELA host
+func PortStart() int {
+	return tela.PortStart()
+}
+
+// SetMaxServers calls SetMaxServers on the default TELA host
+func SetMaxServers(i int) {
+	tela.SetMaxServers(i)
+}
+
+// MaxServers calls MaxServers on the default TELA host
+func MaxServers() int {
+	return tela.MaxServers()
+}
+
+// SetMaxWorkers calls SetMaxWorkers on the default TELA host
+func SetMaxWorkers(i int) {
+	tela.SetMaxWorkers(i)
+}
+
+// MaxWorkers calls MaxWorkers on the default TELA host
+func MaxWorkers() int {
+	return tela.MaxWorkers()
+}
+
+// Installer calls Installer on the default TELA host
+func Installer(wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{}) (txid string, err error) {
+	return tela.Installer(wallet, ringsize, params)
+}
+
+// InstallerContext calls InstallerContext on the default TELA host
+func InstallerContext(ctx context.Context, wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{}) (txid string, err error) {
+	return tela.InstallerContext(ctx, wallet, ringsize, params)
+}
+
+// InstallDOC calls InstallDOC on the default TELA host
+func InstallDOC(wallet *walletapi.Wallet_Disk, ringsize uint64, doc *DOC) (txids []string, err error) {
+	return tela.InstallDOC(wallet, ringsize, doc)
+}
+
+// InstallDOCContext calls InstallDOCContext on the default TELA host
+func InstallDOCContext(ctx context.Context, wallet *walletapi.Wallet_Disk, ringsize uint64, doc *DOC) (txids []string, err error) {
+	return tela.InstallDOCContext(ctx, wallet, ringsize, doc)
+}
+
+// PlanInstall calls PlanInstall on the default TELA host
+func PlanInstall(dir, endpoint string, config PlanConfig) (plan InstallPlan, err error) {
+	return tela.PlanInstall(dir, endpoint, config)
+}
+
+// PlanInstallContext calls PlanInstallContext on the default TELA host
+func PlanInstallContext(ctx context.Context, dir, endpoint string, config PlanConfig) (plan InstallPlan, err error) {
+	return tela.PlanInstallContext(ctx, dir, endpoint, config)
+}
+
+// Updater calls Updater on the default TELA host
+func Updater(wallet *walletapi.Wallet_Disk, params interface{}) (txid string, err error) {
+	return tela.Updater(wallet, params)
+}
+
+// UpdaterContext calls UpdaterContext on the default TELA host
+func UpdaterContext(ctx context.Context, wallet *walletapi.Wallet_Disk, params interface{}) (txid string, err error) {
+	return tela.UpdaterContext(ctx, wallet, params)
+}
+
+// Rate calls Rate on the default TELA host
+func Rate(wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid string, err error) {
+	return tela.Rate(wallet, scid, rating)
+}
+
+// RateContext calls RateContext on the default TELA host
+func RateContext(ctx context.Context, wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid string, err error) {
+	return tela.RateContext(ctx, wallet, scid, rating)
+}
+
+// GetRating calls GetRating on the default TELA host
+func GetRating(scid, endpoint string, height uint64) (ratings Rating_Result, err error) {
+	return tela.GetRating(scid, endpoint, height)
+}
+
+// GetRatingContext calls GetRatingContext on the default TELA host
+func GetRatingContext(ctx context.Context, scid, endpoint string, height uint64) (ratings Rating_Result, err error) {
+	return tela.GetRatingContext(ctx, scid, endpoint, height)
+}
+
+// GetDOCInfo calls GetDOCInfo on the default TELA host
+func GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
+	return tela.GetDOCInfo(scid, endpoint)
+}
+
+// GetDOCInfoContext calls GetDOCInfoContext on the default TELA host
+func GetDOCInfoContext(ctx context.Context, scid, endpoint string) (doc DOC, err error) {
+	return tela.GetDOCInfoContext(ctx, scid, endpoint)
+}
+
+// GetINDEXInfo calls GetINDEXInfo on the default TELA host
+func GetINDEXInfo(scid, endpoint string) (index INDEX, err error) {
+	return tela.GetINDEXInfo(scid, endpoint)
+}
+
+// GetINDEXInfoContext calls GetINDEXInfoContext on the default TELA host
+func GetINDEXInfoContext(ctx context.Context, scid, endpoint string) (index INDEX, err error) {
+	return tela.GetINDEXInfoContext(ctx, scid, endpoint)
+}
+
+// GetINDEXHistory calls GetINDEXHistory on the default TELA host
+func GetINDEXHistory(scid, endpoint string) (history []INDEXCommit, err error) {
+	return tela.GetINDEXHistory(scid, endpoint)
+}
+
+// GetINDEXHistoryContext calls GetINDEXHistoryContext on the default TELA host
+func GetINDEXHistoryContext(ctx context.Context, scid, endpoint string) (history []INDEXCommit, err error) {
+	return tela.GetINDEXHistoryContext(ctx, scid, endpoint)
+}
+
+// CloneAtCommitNumber calls CloneAtCommitNumber on the default TELA host
+func CloneAtCommitNumber(scid string, commit uint64, endpoint string) (err error) {
+	return tela.CloneAtCommitNumber(scid, commit, endpoint)
+}
+
+// CloneAtCommitNumberContext calls CloneAtCommitNumberContext on the default TELA host
+func CloneAtCommitNumberContext(ctx context.Context, scid string, commit uint64, endpoint string) (err error) {
+	return tela.CloneAtCommitNumberContext(ctx, scid, commit, endpoint)
+}
+
+// ServeAtCommitNumber calls ServeAtCommitNumber on the default TELA host
+func ServeAtCommitNumber(scid string, commit uint64, endpoint string) (link string, err error) {
+	return tela.ServeAtCommitNumber(scid, commit, endpoint)
+}
+
+// ServeAtCommitNumberContext calls ServeAtCommitNumberContext on the default TELA host
+func ServeAtCommitNumberContext(ctx context.Context, scid string, commit uint64, endpoint string) (link string, err error) {
+	return tela.ServeAtCommitNumberContext(ctx, scid, commit, endpoint)
+}
+
+// DiffINDEX calls DiffINDEX on the default TELA host
+func DiffINDEX(scid, from, to, endpoint string) (diff INDEXDiff, err error) {
+	return tela.DiffINDEX(scid, from, to, endpoint)
+}
+
+// DiffINDEXContext calls DiffINDEXContext on the default TELA host
+func DiffINDEXContext(ctx context.Context, scid, from, to, endpoint string) (diff INDEXDiff, err error) {
+	return tela.DiffINDEXContext(ctx, scid, from, to, endpoint)
+}
+
+// NewINDEXFS calls NewINDEXFS on the default TELA host
+func NewINDEXFS(scid, endpoint string) *INDEXFS {
+	return tela.NewINDEXFS(scid, endpoint)
+}
+
+// NewINDEXFSContext calls NewINDEXFSContext on the default TELA host
+func NewINDEXFSContext(ctx context.Context, scid, endpoint string) *INDEXFS {
+	return tela.NewINDEXFSContext(ctx, scid, endpoint)
+}
+
+// NewINDEXFSAtCommit calls NewINDEXFSAtCommit on the default TELA host
+func NewINDEXFSAtCommit(scid, txid, endpoint string) *INDEXFS {
+	return tela.NewINDEXFSAtCommit(scid, txid, endpoint)
+}
+
+// NewINDEXFSAtCommitContext calls NewINDEXFSAtCommitContext on the default TELA host
+func NewINDEXFSAtCommitContext(ctx context.Context, scid, txid, endpoint string) *INDEXFS {
+	return tela.NewINDEXFSAtCommitContext(ctx, scid, txid, endpoint)
+}
+
+// GetDependencies calls GetDependencies on the default TELA host
+func GetDependencies(scid, endpoint string) (graph *Dependency, err error) {
+	return tela.GetDependencies(scid, endpoint)
+}
+
+// GetDependenciesContext calls GetDependenciesContext on the default TELA host
+func GetDependenciesContext(ctx context.Context, scid, endpoint string) (graph *Dependency, err error) {
+	return tela.GetDependenciesContext(ctx, scid, endpoint)
+}
+
+// SetShardPath sets the storage path of the default TELA host and the shards package,
+// TELA will remove all its files from the /tela directory when servers are Shutdown
+func SetShardPath(path string) (err error) {
+	if path, err = shards.SetPath(path); err == nil {
+		tela.Lock()
+		tela.path.main = path
+		tela.rules.setPath(path)
+		tela.Unlock()
+	}
+
+	return
+}
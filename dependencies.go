@@ -0,0 +1,293 @@
+package tela
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/deroproject/derohe/dvm"
+)
+
+// Max depth of libraries embedded within a TELA-INDEX, the INDEX being resolved is depth 0
+const MAX_LIBRARY_DEPTH = 5
+
+// Library dependency graph of a TELA-INDEX, libraries are TELA-INDEXs embedded as a DOC of their parent INDEX
+type Dependency struct {
+	SCID      string        `json:"scid"`                // SCID of the INDEX
+	DURL      string        `json:"dURL"`                // dURL of the INDEX
+	Commit    string        `json:"commit"`              // Commit TXID of the INDEX content being resolved
+	Depth     int           `json:"depth"`               // Depth of the INDEX within the graph
+	DOCs      []string      `json:"docs"`                // SCIDs of all DOCs and libraries embedded in the INDEX in order
+	Libraries []*Dependency `json:"libraries,omitempty"` // Libraries embedded in the INDEX in order
+
+	entries []dependencyEntry // Contract states of the embedded DOCs and libraries in order
+}
+
+// DOC embedded in a resolved INDEX
+type dependencyEntry struct {
+	docNum  string
+	state   contractState
+	library *Dependency // Set if the DOC is a library
+}
+
+// Get the TELA-INDEX SC, dURL and commit hash from its contract state
+func indexFromState(state contractState) (sc dvm.SmartContract, dURL, hash string, err error) {
+	scid := state.scid
+
+	code, err := state.code()
+	if err != nil {
+		err = fmt.Errorf("could not get SC code: %w", err)
+		return
+	}
+
+	// Only use contracts matching TELA standard
+	sc, err = EqualSmartContracts(TELA_INDEX_1, code)
+	if err != nil {
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("does not parse as TELA-INDEX-1: %s", err))
+		return
+	}
+
+	dURL, err = state.value(HEADER_DURL.Trim())
+	if err != nil {
+		err = fmt.Errorf("could not get dURL: %w", err)
+		return
+	}
+
+	if err = validatePathName(dURL); err != nil {
+		err = newError(ErrUnsafePath, scid, "", "", fmt.Errorf("dURL %s", err))
+		return
+	}
+
+	hash, err = state.value("hash")
+	if err != nil {
+		err = fmt.Errorf("could not get commit hash: %w", withContent(err, scid, dURL, ""))
+		return
+	}
+
+	return
+}
+
+// Resolve the library dependency graph of a TELA-INDEX from its contract state, if headers is set only the treeKeys of DOCs are fetched
+func (t *TELA) resolveINDEX(ctx context.Context, state contractState, endpoint string, headers bool) (graph *Dependency, err error) {
+	sc, dURL, hash, err := indexFromState(state)
+	if err != nil {
+		return
+	}
+
+	graph = &Dependency{SCID: state.scid, DURL: dURL, Commit: hash}
+	err = t.resolveDependencies(ctx, graph, sc, endpoint, []string{graph.SCID}, headers)
+	if err != nil {
+		graph = nil
+	}
+
+	return
+}
+
+// Resolve the DOCs and libraries embedded in dep from its INDEX sc. Ancestors are the SCIDs of the INDEXs leading to dep,
+// a library found in ancestors or embedded more than once in dep will not resolve. Libraries shared by different INDEXs are
+// resolved for each INDEX as they are cloned into each INDEX's own directory. If headers is set the states of DOCs only have their
+// treeKeys and are fetched in full when they are read, libraries are always fetched in full
+func (t *TELA) resolveDependencies(ctx context.Context, dep *Dependency, sc dvm.SmartContract, endpoint string, ancestors []string, headers bool) (err error) {
+	docNums, scids, err := parseINDEXForDOCNums(sc)
+	if err != nil {
+		return
+	}
+
+	dep.DOCs = scids
+
+	// Get the code and all string keys of each scid
+	fetch := t.getContractState
+	if headers {
+		fetch = t.getContractTreeState
+	}
+
+	states, err := t.fetchContractStates(ctx, scids, endpoint, fetch)
+	if err != nil {
+		return
+	}
+
+	embedded := map[string]bool{}
+	for i, state := range states {
+		entry := dependencyEntry{docNum: docNums[i], state: state}
+
+		// Check if scid is INDEX or DOC, DOCs are validated when cloned
+		var telaVersion string
+		telaVersion, err = state.value("telaVersion")
+		if err != nil {
+			err = nil
+			dep.entries = append(dep.entries, entry)
+			continue
+		}
+
+		var libCheck string
+		libCheck, err = state.value(HEADER_DURL.Trim())
+		if err != nil {
+			err = fmt.Errorf("could not verify TELA-INDEX dURL for library embed: %w", err)
+			return
+		}
+
+		scid := state.scid
+		switch {
+		case telaVersion != TELA_VERSION:
+			err = newError(ErrVersion, scid, libCheck, "", fmt.Errorf("cannot use TELA-INDEX v%s when package is v%s", telaVersion, TELA_VERSION))
+		case Header(docNums[i]) == HEADER_DOCUMENT.Number(1):
+			err = newError(ErrEmbed, scid, libCheck, "", fmt.Errorf("cannot use TELA-INDEX as entrypoint for TELA-INDEX"))
+		case !strings.HasSuffix(libCheck, TAG_LIBRARY):
+			err = newError(ErrEmbed, scid, libCheck, "", fmt.Errorf("cannot embed TELA-INDEX without %q tag", TAG_LIBRARY))
+		case containsSCID(ancestors, scid):
+			err = newError(ErrDependency, scid, libCheck, "", fmt.Errorf("library cycle %s -> %s", strings.Join(ancestors, " -> "), scid))
+		case embedded[scid]:
+			err = newError(ErrDependency, scid, libCheck, "", fmt.Errorf("duplicate library in %s", dep.DURL))
+		case dep.Depth+1 > MAX_LIBRARY_DEPTH:
+			err = newError(ErrDependency, scid, libCheck, "", fmt.Errorf("library exceeds max depth of %d", MAX_LIBRARY_DEPTH))
+		}
+
+		if err != nil {
+			return
+		}
+
+		if headers {
+			state, err = t.getContractState(ctx, scid, endpoint)
+			if err != nil {
+				err = fmt.Errorf("could not get SC code: %w", err)
+				return
+			}
+
+			entry.state = state
+		}
+
+		var lib dvm.SmartContract
+		entry.library = &Dependency{SCID: scid, Depth: dep.Depth + 1}
+		lib, entry.library.DURL, entry.library.Commit, err = indexFromState(state)
+		if err != nil {
+			return
+		}
+
+		embedded[scid] = true
+		err = t.resolveDependencies(ctx, entry.library, lib, endpoint, append(ancestors[:len(ancestors):len(ancestors)], scid), headers)
+		if err != nil {
+			return
+		}
+
+		dep.Libraries = append(dep.Libraries, entry.library)
+		dep.entries = append(dep.entries, entry)
+	}
+
+	return
+}
+
+// Check if scids contains scid
+func containsSCID(scids []string, scid string) bool {
+	for _, s := range scids {
+		if s == scid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clone a resolved INDEX and its libraries to basePath returning the entrypoint, serve path, served DOCs and DOC signature verification results.
+// All content is cloned into a staging directory which is only moved to basePath if all DOCs and libraries are successful
+func (t *TELA) cloneDependency(dep *Dependency, basePath string) (clone Cloning, err error) {
+	clone.files = map[string]servedDOC{}
+
+	// Add a cloned DOC or chunked file, setting the entrypoint and serve path if it is DOC1
+	add := func(c Cloning, isDOC1 bool) {
+		clone.Verifications = append(clone.Verifications, c.Verifications...)
+		for name, doc := range c.files {
+			clone.files[name] = doc
+		}
+
+		if isDOC1 {
+			clone.Entrypoint = c.Entrypoint
+			clone.ServePath = c.ServePath
+		}
+	}
+
+	err = stageClone(basePath, func(stagePath string) (err error) {
+		// Parts of chunked files by their path, each file is cloned once all DOCs are cloned
+		var chunked []string
+		parts := map[string][]dependencyEntry{}
+
+		for _, entry := range dep.entries {
+			if entry.library != nil {
+				var lib Cloning
+				lib, err = t.cloneDependency(entry.library, filepath.Join(stagePath, entry.library.DURL))
+				if err != nil {
+					return
+				}
+
+				clone.Verifications = append(clone.Verifications, lib.Verifications...)
+				for name, doc := range lib.files {
+					clone.files[path.Join(entry.library.DURL, name)] = doc
+				}
+
+				continue
+			}
+
+			if name, ok := docPartPath(entry.state); ok {
+				if _, ok := parts[name]; !ok {
+					chunked = append(chunked, name)
+				}
+
+				parts[name] = append(parts[name], entry)
+				continue
+			}
+
+			var c Cloning
+			c, err = t.cloneDOCFromState(entry.state, entry.docNum, stagePath)
+			if err != nil {
+				return
+			}
+
+			add(c, Header(entry.docNum) == HEADER_DOCUMENT.Number(1))
+		}
+
+		for _, name := range chunked {
+			var c Cloning
+			c, err = t.cloneDOCParts(parts[name], stagePath)
+			if err != nil {
+				return
+			}
+
+			add(c, c.Entrypoint != "")
+		}
+
+		return
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) && !errors.Is(err, ErrFileExists) {
+			err = newError(ErrFileExists, dep.SCID, dep.DURL, dep.Commit, err)
+		}
+
+		err = fmt.Errorf("cloning %s@%s was not successful: %w", dep.DURL, dep.SCID, err)
+	}
+
+	return
+}
+
+// GetDependencies resolves the library dependency graph of a TELA-INDEX SCID from endpoint without cloning any content
+func (t *TELA) GetDependencies(scid, endpoint string) (graph *Dependency, err error) {
+	return t.GetDependenciesContext(context.Background(), scid, endpoint)
+}
+
+// GetDependenciesContext is GetDependencies using ctx for all daemon requests
+func (t *TELA) GetDependenciesContext(ctx context.Context, scid, endpoint string) (graph *Dependency, err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	state, err := t.getContractState(ctx, scid, endpoint)
+	if err != nil {
+		err = fmt.Errorf("could not get SC code: %w", err)
+		return
+	}
+
+	return t.resolveINDEX(ctx, state, endpoint, false)
+}
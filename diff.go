@@ -0,0 +1,452 @@
+package tela
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deroproject/derohe/dvm"
+)
+
+// Lines of context surrounding each change in a DOCDiff
+const DIFF_CONTEXT_LINES = 3
+
+// Max size of the line table used to diff the changed lines of a DOC, larger changes are reported as differing files
+const MAX_DIFF_SIZE = 1 << 22
+
+// Structural diff between two commits of a TELA-INDEX
+type INDEXDiff struct {
+	SCID      string       `json:"scid"`               // SCID of the INDEX
+	From      string       `json:"from"`               // Commit TXID being compared from
+	To        string       `json:"to"`                 // Commit TXID being compared to, the current commit hash when compared to the live state
+	Headers   []HeaderDiff `json:"headers,omitempty"`  // Headers changed between commits
+	Added     []string     `json:"added,omitempty"`    // DOC SCIDs added to the INDEX
+	Removed   []string     `json:"removed,omitempty"`  // DOC SCIDs removed from the INDEX
+	Replaced  []DOCDiff    `json:"replaced,omitempty"` // DOC SCIDs replaced by another SCID at the same DOC#
+	Libraries LibraryDiff  `json:"libraries"`          // Libraries embedded or removed from the INDEX
+}
+
+// Header value changed between two INDEX commits
+type HeaderDiff struct {
+	Header Header `json:"header"` // Header key
+	From   string `json:"from"`   // Header value in the from commit
+	To     string `json:"to"`     // Header value in the to commit
+}
+
+// DOC replaced at a DOC# between two INDEX commits
+type DOCDiff struct {
+	DOCNum string `json:"docNum"`         // DOC# of the INDEX that was replaced
+	From   string `json:"from"`           // DOC SCID in the from commit
+	To     string `json:"to"`             // DOC SCID in the to commit
+	Diff   string `json:"diff,omitempty"` // Unified diff of the docCode, empty if either DOC is a library or its docCode is equal
+}
+
+// Libraries changed between two INDEX commits
+type LibraryDiff struct {
+	Added   []string `json:"added,omitempty"`   // Library SCIDs embedded in the INDEX
+	Removed []string `json:"removed,omitempty"` // Library SCIDs removed from the INDEX
+}
+
+// Headers compared by DiffINDEX
+var diffHeaders = []Header{HEADER_NAME, HEADER_DESCRIPTION, HEADER_ICON_URL, HEADER_DURL}
+
+// Returns if the diff has no changes
+func (d INDEXDiff) Empty() bool {
+	return len(d.Headers) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Replaced) == 0
+}
+
+// Parse the InitializePrivate STORE values of a TELA-INDEX SC by their key
+func parseINDEXStores(sc dvm.SmartContract) (stores map[string]string) {
+	stores = map[string]string{}
+	function, ok := sc.Functions[DVM_FUNC_INIT_PRIVATE]
+	if !ok {
+		return
+	}
+
+	for _, num := range function.LineNumbers {
+		line := function.Lines[num]
+		if len(line) < 5 || line[0] != "STORE" {
+			continue
+		}
+
+		value, err := strconv.Unquote(line[4])
+		if err != nil {
+			value = strings.Trim(line[4], `"`)
+		}
+
+		stores[strings.Trim(line[2], `"`)] = value
+	}
+
+	return
+}
+
+// Get the TELA-INDEX SC of scid at commit txid, if txid is empty the live SC is used and txid is the current commit hash
+func (t *TELA) getINDEXAtCommit(ctx context.Context, scid, txid, endpoint string) (sc dvm.SmartContract, commit string, err error) {
+	var code string
+	if txid == "" {
+		var state contractState
+		state, err = t.getContractState(ctx, scid, endpoint)
+		if err != nil {
+			return
+		}
+
+		code, err = state.code()
+		if err != nil {
+			return
+		}
+
+		commit, err = state.value("hash")
+		if err != nil {
+			return
+		}
+	} else {
+		if len(txid) != 64 {
+			err = newError(ErrInvalidCommit, scid, "", txid, nil)
+			return
+		}
+
+		var txidAsHex string
+		txidAsHex, err = t.getTXID(ctx, txid, endpoint)
+		if err != nil {
+			err = fmt.Errorf("could not get TXID: %w", withContent(err, scid, "", txid))
+			return
+		}
+
+		code, err = extractCodeFromTXID(txidAsHex)
+		if err != nil {
+			err = newError(ErrNotTELA, scid, "", txid, fmt.Errorf("could not get SC code: %s", err))
+			return
+		}
+
+		commit = txid
+	}
+
+	sc, err = EqualSmartContracts(TELA_INDEX_1, code)
+	if err != nil {
+		err = newError(ErrNotTELA, scid, "", commit, fmt.Errorf("does not parse as TELA-INDEX-1: %s", err))
+		return
+	}
+
+	return
+}
+
+// Diff the DOCs of two INDEX commits by their DOC# keys and SCIDs. DOCs not found in the other commit are
+// added or removed, unless they share a DOC# in which case the DOC was replaced
+func diffDOCs(fromNums, fromSCIDs, toNums, toSCIDs []string) (added, removed []string, replaced []DOCDiff) {
+	inFrom := map[string]bool{}
+	for _, scid := range fromSCIDs {
+		inFrom[scid] = true
+	}
+
+	inTo := map[string]bool{}
+	for _, scid := range toSCIDs {
+		inTo[scid] = true
+	}
+
+	// DOC# of each DOC not found in the other commit
+	gone := map[string]string{}
+	for i, scid := range fromSCIDs {
+		if !inTo[scid] {
+			gone[fromNums[i]] = scid
+		}
+	}
+
+	replacedNums := map[string]bool{}
+	for i, scid := range toSCIDs {
+		if inFrom[scid] {
+			continue
+		}
+
+		if old, ok := gone[toNums[i]]; ok {
+			replaced = append(replaced, DOCDiff{DOCNum: strings.Trim(toNums[i], `"`), From: old, To: scid})
+			replacedNums[toNums[i]] = true
+			continue
+		}
+
+		added = append(added, scid)
+	}
+
+	for i, scid := range fromSCIDs {
+		if !inTo[scid] && !replacedNums[fromNums[i]] {
+			removed = append(removed, scid)
+		}
+	}
+
+	return
+}
+
+// DiffINDEX compares the TELA-INDEX SCID at commit from with commit to, if to is empty from is compared with the live state.
+// The DOCs and headers of each commit are parsed from their SC code, docCode of replaced DOCs is compared as a unified diff
+func (t *TELA) DiffINDEX(scid, from, to, endpoint string) (diff INDEXDiff, err error) {
+	return t.DiffINDEXContext(context.Background(), scid, from, to, endpoint)
+}
+
+// DiffINDEXContext is DiffINDEX using ctx for all daemon requests
+func (t *TELA) DiffINDEXContext(ctx context.Context, scid, from, to, endpoint string) (diff INDEXDiff, err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	if from == "" {
+		err = newError(ErrInvalidCommit, scid, "", "", fmt.Errorf("missing commit to compare from"))
+		return
+	}
+
+	fromSC, from, err := t.getINDEXAtCommit(ctx, scid, from, endpoint)
+	if err != nil {
+		return
+	}
+
+	toSC, to, err := t.getINDEXAtCommit(ctx, scid, to, endpoint)
+	if err != nil {
+		return
+	}
+
+	diff = INDEXDiff{SCID: scid, From: from, To: to}
+
+	fromStores := parseINDEXStores(fromSC)
+	toStores := parseINDEXStores(toSC)
+	for _, h := range diffHeaders {
+		if fromStores[h.Trim()] != toStores[h.Trim()] {
+			diff.Headers = append(diff.Headers, HeaderDiff{Header: h, From: fromStores[h.Trim()], To: toStores[h.Trim()]})
+		}
+	}
+
+	fromNums, fromSCIDs, err := parseINDEXForDOCNums(fromSC)
+	if err != nil {
+		err = withContent(err, scid, "", from)
+		return
+	}
+
+	toNums, toSCIDs, err := parseINDEXForDOCNums(toSC)
+	if err != nil {
+		err = withContent(err, scid, "", to)
+		return
+	}
+
+	diff.Added, diff.Removed, diff.Replaced = diffDOCs(fromNums, fromSCIDs, toNums, toSCIDs)
+
+	// Get the state of all changed DOCs to find libraries and docCode
+	var scids []string
+	scids = append(scids, diff.Added...)
+	scids = append(scids, diff.Removed...)
+	for _, r := range diff.Replaced {
+		scids = append(scids, r.From, r.To)
+	}
+
+	if len(scids) < 1 {
+		return
+	}
+
+	states, err := t.getContractStates(ctx, scids, endpoint)
+	if err != nil {
+		return
+	}
+
+	libraries := map[string]bool{}
+	docCodes := map[string]string{}
+	for _, state := range states {
+		if _, err := state.value("telaVersion"); err == nil {
+			libraries[state.scid] = true
+			continue
+		}
+
+		if docCode, err := state.docCode(); err == nil {
+			docCodes[state.scid] = docCode
+		}
+	}
+
+	for _, scid := range diff.Added {
+		if libraries[scid] {
+			diff.Libraries.Added = append(diff.Libraries.Added, scid)
+		}
+	}
+
+	for _, scid := range diff.Removed {
+		if libraries[scid] {
+			diff.Libraries.Removed = append(diff.Libraries.Removed, scid)
+		}
+	}
+
+	for i, r := range diff.Replaced {
+		if libraries[r.From] {
+			diff.Libraries.Removed = append(diff.Libraries.Removed, r.From)
+		}
+
+		if libraries[r.To] {
+			diff.Libraries.Added = append(diff.Libraries.Added, r.To)
+		}
+
+		if libraries[r.From] || libraries[r.To] {
+			continue
+		}
+
+		diff.Replaced[i].Diff = unifiedDiff(r.From, r.To, docCodes[r.From], docCodes[r.To])
+	}
+
+	return
+}
+
+// Line of a diff, kind is ' ' for equal, '-' for removed and '+' for added
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// Split text into lines for diffing
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
+
+// Diff lines a and b using the longest common subsequence of the lines between their common prefix and suffix,
+// returns false if those lines exceed MAX_DIFF_SIZE
+func diffLines(a, b []string) (lines []diffLine, ok bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	head, tail := a[:prefix], a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(a)+1)*(len(b)+1) > MAX_DIFF_SIZE {
+		return
+	}
+
+	ok = true
+	for _, line := range head {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+
+	for _, line := range tail {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	return
+}
+
+// Create a unified diff of text a and b labeled with fromName and toName, returns empty if a and b are equal.
+// Binary content is not diffed by line
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	if IsBinaryCode(a) || IsBinaryCode(b) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", fromName, toName)
+	}
+
+	lines, ok := diffLines(splitLines(a), splitLines(b))
+	if !ok {
+		return fmt.Sprintf("Files %s and %s differ\n", fromName, toName)
+	}
+
+	// Line position in a and b before each diff line
+	aPos := make([]int, len(lines)+1)
+	bPos := make([]int, len(lines)+1)
+	var changes []int
+	for i, l := range lines {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if l.kind != '+' {
+			aPos[i+1]++
+		}
+
+		if l.kind != '-' {
+			bPos[i+1]++
+		}
+
+		if l.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+
+	for c := 0; c < len(changes); {
+		start := changes[c] - DIFF_CONTEXT_LINES
+		if start < 0 {
+			start = 0
+		}
+
+		// Merge changes with overlapping context into one hunk
+		end := changes[c] + DIFF_CONTEXT_LINES + 1
+		for c++; c < len(changes) && changes[c]-DIFF_CONTEXT_LINES <= end; c++ {
+			end = changes[c] + DIFF_CONTEXT_LINES + 1
+		}
+
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		aStart, aCount := aPos[start]+1, aPos[end]-aPos[start]
+		if aCount == 0 {
+			aStart--
+		}
+
+		bStart, bCount := bPos[start]+1, bPos[end]-bPos[start]
+		if bCount == 0 {
+			bStart--
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount))
+		for _, l := range lines[start:end] {
+			sb.WriteByte(l.kind)
+			sb.WriteString(l.text)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package tela
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned when cloning and serving TELA content, check for them with errors.Is
+var (
+	ErrInvalidSCID   = errors.New("invalid SCID")                                         // SCID is not a valid length
+	ErrInvalidCommit = errors.New("invalid commit TXID")                                  // Commit TXID is not a valid length
+	ErrNotTELA       = errors.New("not a TELA contract")                                  // Contract does not parse as a TELA standard
+	ErrVersion       = errors.New("TELA version mismatch")                                // Contract TELA version is not supported by this package
+	ErrUpdated       = errors.New("content has been updated and updates are not allowed") // Content has been updated since its original install and AllowUpdates is false
+	ErrFileExists    = errors.New("file already exists")                                  // File being cloned already exists
+	ErrLibrary       = errors.New("library cannot be served")                             // Content is a library and can only be cloned or embedded
+	ErrEmbed         = errors.New("invalid library embed")                                // INDEX embedded in an INDEX is not a valid library
+	ErrNoOpenPort    = errors.New("no open port")                                         // No port available within the serving range
+	ErrMissingHeader = errors.New("missing header")                                       // A required header or key is not stored in the contract
+	ErrLanguage      = errors.New("not an accepted language")                             // DOC docType is not an accepted language
+	ErrSignature     = errors.New("signature could not be verified")                      // DOC signature could not be verified against its owner
+	ErrDaemon        = errors.New("daemon unreachable")                                   // Daemon request was not successful
+	ErrUnsafePath    = errors.New("unsafe path")                                          // dURL, subDir or nameHdr could write outside of its directory
+	ErrDependency    = errors.New("invalid library dependency")                           // Library is a cycle, duplicate or exceeds MAX_LIBRARY_DEPTH
+	ErrParts         = errors.New("invalid file parts")                                   // Parts of a chunked file are missing, duplicated or do not match
+)
+
+// Error from cloning or serving TELA content, it carries the content the error applies to.
+// Err is one of the TELA sentinel errors and can be checked with errors.Is, use errors.As to get the Error
+type Error struct {
+	Err    error  // TELA sentinel error
+	SCID   string // SCID of the INDEX or DOC the error applies to
+	DURL   string // dURL of the content the error applies to
+	Commit string // Commit TXID the error applies to
+	Cause  error  // Underlying error if any
+}
+
+// Create a TELA Error
+func newError(sentinel error, scid, dURL, commit string, cause error) *Error {
+	return &Error{Err: sentinel, SCID: scid, DURL: dURL, Commit: commit, Cause: cause}
+}
+
+// Create a TELA Error for a daemon request on scid or commit, if the request was stopped by ctx its error is the cause
+func daemonError(ctx context.Context, scid, commit string, err error) *Error {
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	return newError(ErrDaemon, scid, "", commit, err)
+}
+
+// Fill in any content that is not already set on a TELA Error within err
+func withContent(err error, scid, dURL, commit string) error {
+	var e *Error
+	if errors.As(err, &e) {
+		if e.SCID == "" {
+			e.SCID = scid
+		}
+
+		if e.DURL == "" {
+			e.DURL = dURL
+		}
+
+		if e.Commit == "" {
+			e.Commit = commit
+		}
+	}
+
+	return err
+}
+
+// Returns the error message prefixed by the content it applies to
+func (e *Error) Error() string {
+	msg := e.Err.Error()
+	if e.Cause != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.Cause)
+	}
+
+	content := e.DURL
+	if e.SCID != "" {
+		if content != "" {
+			content += "@"
+		}
+		content += e.SCID
+	}
+
+	if e.Commit != "" && e.Commit != e.SCID {
+		if content != "" {
+			content += " "
+		}
+		content += fmt.Sprintf("commit %s", e.Commit)
+	}
+
+	if content == "" {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", content, msg)
+}
+
+// Returns the sentinel and cause of the error
+func (e *Error) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Err, e.Cause}
+}
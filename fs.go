@@ -0,0 +1,514 @@
+package tela
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Read-only fs.FS over a TELA-INDEX SCID, create with NewINDEXFS or NewINDEXFSAtCommit.
+// Directories are the DOC subDirs and embedded libraries of the INDEX and files are the DOC code, chunked files are joined from their parts.
+// Nothing is written to disk. The INDEX and the headers of its DOCs are resolved from the daemon when the FS is first used, the code of
+// each DOC is fetched when its file is first read and file contents are cached in memory. The daemon has no block times so ModTime is zero
+type INDEXFS struct {
+	sync.Mutex
+	tela     *TELA
+	ctx      context.Context // Used for all daemon requests made by the FS
+	scid     string
+	txid     string // Commit TXID of the INDEX, empty uses its current state
+	endpoint string
+	root     *fsNode
+	entry    string // Path of the INDEX entrypoint
+}
+
+// File or directory within an INDEXFS
+type fsNode struct {
+	name     string
+	scid     string             // SCID of the DOC, or of the INDEX for directories
+	children map[string]*fsNode // Set if the node is a directory
+	parts    []string           // SCIDs of the DOCs if the file is chunked across multiple DOCs
+	data     []byte             // Cached file contents
+	loaded   bool
+}
+
+// fs.FileInfo of a fsNode
+type fsInfo struct {
+	node *fsNode
+	size int64
+}
+
+// Open file of an INDEXFS
+type fsFile struct {
+	*bytes.Reader
+	info fsInfo
+}
+
+// Directory entry of an INDEXFS, its file contents are not read until Info is called
+type fsDirEntry struct {
+	fs   *INDEXFS
+	node *fsNode
+	path string
+}
+
+// Open directory of an INDEXFS
+type fsDir struct {
+	info    fsInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+var _ fs.ReadFileFS = (*INDEXFS)(nil)
+var _ fs.ReadDirFS = (*INDEXFS)(nil)
+var _ fs.StatFS = (*INDEXFS)(nil)
+
+// NewINDEXFS returns a fs.FS over the current state of a TELA-INDEX SCID from endpoint,
+// update rules are not applied so use NewINDEXFSAtCommit for a specific commit
+func (t *TELA) NewINDEXFS(scid, endpoint string) *INDEXFS {
+	return t.NewINDEXFSContext(context.Background(), scid, endpoint)
+}
+
+// NewINDEXFSContext is NewINDEXFS using ctx for all daemon requests made by the FS
+func (t *TELA) NewINDEXFSContext(ctx context.Context, scid, endpoint string) *INDEXFS {
+	return &INDEXFS{tela: t, ctx: ctx, scid: scid, endpoint: endpoint}
+}
+
+// NewINDEXFSAtCommit returns a fs.FS over a TELA-INDEX SCID at commit TXID from endpoint
+func (t *TELA) NewINDEXFSAtCommit(scid, txid, endpoint string) *INDEXFS {
+	return t.NewINDEXFSAtCommitContext(context.Background(), scid, txid, endpoint)
+}
+
+// NewINDEXFSAtCommitContext is NewINDEXFSAtCommit using ctx for all daemon requests made by the FS
+func (t *TELA) NewINDEXFSAtCommitContext(ctx context.Context, scid, txid, endpoint string) *INDEXFS {
+	return &INDEXFS{tela: t, ctx: ctx, scid: scid, txid: txid, endpoint: endpoint}
+}
+
+// Resolve the INDEX and build its file tree if it has not been built, caller must hold the lock
+func (f *INDEXFS) resolve() (err error) {
+	if f.root != nil {
+		return
+	}
+
+	if len(f.scid) != 64 {
+		err = newError(ErrInvalidSCID, f.scid, "", f.txid, fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	var graph *Dependency
+	if f.txid != "" {
+		graph, err = f.tela.resolveINDEXAtCommit(f.ctx, f.scid, f.txid, f.endpoint, true)
+	} else {
+		var state contractState
+		state, err = f.tela.getContractState(f.ctx, f.scid, f.endpoint)
+		if err != nil {
+			err = fmt.Errorf("could not get SC code: %w", err)
+			return
+		}
+
+		graph, err = f.tela.resolveINDEX(f.ctx, state, f.endpoint, true)
+	}
+
+	if err != nil {
+		return
+	}
+
+	root := &fsNode{name: ".", scid: graph.SCID, children: map[string]*fsNode{}}
+	if err = f.build(root, graph); err != nil {
+		return
+	}
+
+	f.root = root
+
+	return
+}
+
+// Add the DOCs and libraries of dep to the dir node using the headers of each DOC
+func (f *INDEXFS) build(dir *fsNode, dep *Dependency) (err error) {
+	for _, entry := range dep.entries {
+		if entry.library != nil {
+			if _, ok := dir.children[entry.library.DURL]; ok {
+				err = newError(ErrFileExists, entry.library.SCID, entry.library.DURL, entry.library.Commit, fmt.Errorf("%s", entry.library.DURL))
+				return
+			}
+
+			lib := &fsNode{name: entry.library.DURL, scid: entry.library.SCID, children: map[string]*fsNode{}}
+			dir.children[lib.name] = lib
+			if err = f.build(lib, entry.library); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		var file docFile
+		file, err = docHeadersFromState(entry.state)
+		if err != nil {
+			return
+		}
+
+		// Create any subDirs for the DOC
+		parent := dir
+		if file.subDir != "" {
+			for _, name := range strings.Split(file.subDir, "/") {
+				child, ok := parent.children[name]
+				if !ok {
+					child = &fsNode{name: name, scid: dep.SCID, children: map[string]*fsNode{}}
+					parent.children[name] = child
+				} else if child.children == nil {
+					err = newError(ErrFileExists, file.scid, file.dURL, "", fmt.Errorf("%s", path.Join(file.subDir, file.name)))
+					return
+				}
+
+				parent = child
+			}
+		}
+
+		node, ok := parent.children[file.name]
+		switch {
+		case ok && file.parts > 0 && node.parts != nil:
+			// Parts of a chunked file are joined when it is loaded
+			node.parts = append(node.parts, file.scid)
+			if file.part == 1 {
+				node.scid = file.scid
+			}
+		case ok:
+			err = newError(ErrFileExists, file.scid, file.dURL, "", fmt.Errorf("%s", path.Join(file.subDir, file.name)))
+			return
+		case file.parts > 0:
+			parent.children[file.name] = &fsNode{name: file.name, scid: file.scid, parts: []string{file.scid}}
+		default:
+			parent.children[file.name] = &fsNode{name: file.name, scid: file.scid}
+		}
+
+		// Set entrypoint of the INDEX being resolved
+		if dep.Depth == 0 && Header(entry.docNum) == HEADER_DOCUMENT.Number(1) {
+			f.entry = path.Join(file.subDir, file.name)
+		}
+	}
+
+	return
+}
+
+// Get the contents of a file node fetching its DOCs, verifying their signatures as required by the signature policy. Caller must hold the lock
+func (f *INDEXFS) load(node *fsNode) (data []byte, err error) {
+	if node.loaded {
+		return node.data, nil
+	}
+
+	var content string
+	if node.parts != nil {
+		var states []contractState
+		if states, err = f.tela.getContractStates(f.ctx, node.parts, f.endpoint); err != nil {
+			return
+		}
+
+		var parts docParts
+		if parts, err = f.tela.joinDOCParts(states); err != nil {
+			return
+		}
+
+		content = parts.content
+	} else {
+		var state contractState
+		state, err = f.tela.getContractState(f.ctx, node.scid, f.endpoint)
+		if err != nil {
+			err = fmt.Errorf("could not get SC code: %w", err)
+			return
+		}
+
+		var file docFile
+		if file, err = docFromState(state); err != nil {
+			return
+		}
+
+		if _, err = f.tela.verifyDOCFile(state, file); err != nil {
+			return
+		}
+
+		content, err = parseTELADoc(file.code, file.docType, file.encoding)
+		if err != nil {
+			err = fmt.Errorf("error parsing %s: %s", file.name, err)
+			return
+		}
+	}
+
+	node.data = []byte(content)
+	node.loaded = true
+
+	return node.data, nil
+}
+
+// Get the node at name, caller must hold the lock
+func (f *INDEXFS) lookup(op, name string) (node *fsNode, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+		return
+	}
+
+	if err = f.resolve(); err != nil {
+		err = &fs.PathError{Op: op, Path: name, Err: err}
+		return
+	}
+
+	node = f.root
+	if name == "." {
+		return
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if node.children == nil {
+			node = nil
+			break
+		}
+
+		node = node.children[elem]
+		if node == nil {
+			break
+		}
+	}
+
+	if node == nil {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return
+}
+
+// Get the fs.FileInfo of node, caller must hold the lock
+func (f *INDEXFS) info(op, name string, node *fsNode) (info fsInfo, err error) {
+	info.node = node
+	if node.children != nil {
+		return
+	}
+
+	data, err := f.load(node)
+	if err != nil {
+		err = &fs.PathError{Op: op, Path: name, Err: err}
+		return
+	}
+
+	info.size = int64(len(data))
+
+	return
+}
+
+// Get the sorted directory entries of a dir node
+func (f *INDEXFS) entries(op, name string, node *fsNode) (entries []fs.DirEntry, err error) {
+	if node.children == nil {
+		err = &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("not a directory")}
+		return
+	}
+
+	for _, child := range node.children {
+		entries = append(entries, fsDirEntry{fs: f, node: child, path: path.Join(name, child.name)})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return
+}
+
+// Open opens the named file or directory of the INDEX
+func (f *INDEXFS) Open(name string) (file fs.File, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	node, err := f.lookup("open", name)
+	if err != nil {
+		return
+	}
+
+	info, err := f.info("open", name, node)
+	if err != nil {
+		return
+	}
+
+	if node.children != nil {
+		var entries []fs.DirEntry
+		entries, err = f.entries("open", name, node)
+		if err != nil {
+			return
+		}
+
+		return &fsDir{info: info, entries: entries}, nil
+	}
+
+	return &fsFile{Reader: bytes.NewReader(node.data), info: info}, nil
+}
+
+// ReadFile returns the contents of the named file of the INDEX
+func (f *INDEXFS) ReadFile(name string) (data []byte, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	node, err := f.lookup("readfile", name)
+	if err != nil {
+		return
+	}
+
+	if node.children != nil {
+		err = &fs.PathError{Op: "readfile", Path: name, Err: fmt.Errorf("is a directory")}
+		return
+	}
+
+	cached, err := f.load(node)
+	if err != nil {
+		err = &fs.PathError{Op: "readfile", Path: name, Err: err}
+		return
+	}
+
+	// Callers may modify the returned data
+	data = append([]byte(nil), cached...)
+
+	return
+}
+
+// ReadDir returns the sorted entries of the named directory of the INDEX
+func (f *INDEXFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	node, err := f.lookup("readdir", name)
+	if err != nil {
+		return
+	}
+
+	return f.entries("readdir", name, node)
+}
+
+// Stat returns the fs.FileInfo of the named file or directory of the INDEX
+func (f *INDEXFS) Stat(name string) (info fs.FileInfo, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	node, err := f.lookup("stat", name)
+	if err != nil {
+		return
+	}
+
+	return f.info("stat", name, node)
+}
+
+// Entrypoint returns the path of the INDEX entrypoint DOC within the FS
+func (f *INDEXFS) Entrypoint() (entrypoint string, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err = f.resolve(); err != nil {
+		return
+	}
+
+	return f.entry, nil
+}
+
+// Name returns the base name of the file or directory
+func (i fsInfo) Name() string {
+	return i.node.name
+}
+
+// Size returns the length of the file contents
+func (i fsInfo) Size() int64 {
+	return i.size
+}
+
+// Mode returns the read-only file mode bits
+func (i fsInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+// ModTime returns the zero time as the daemon has no block times
+func (i fsInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+// IsDir reports whether the info describes a directory
+func (i fsInfo) IsDir() bool {
+	return i.node.children != nil
+}
+
+// Sys returns the SCID of the DOC, the first part of a chunked file, or of the INDEX for directories
+func (i fsInfo) Sys() interface{} {
+	return i.node.scid
+}
+
+// Name returns the name of the file or directory
+func (e fsDirEntry) Name() string {
+	return e.node.name
+}
+
+// IsDir reports whether the entry describes a directory
+func (e fsDirEntry) IsDir() bool {
+	return e.node.children != nil
+}
+
+// Type returns the type bits of the entry
+func (e fsDirEntry) Type() fs.FileMode {
+	return fsInfo{node: e.node}.Mode().Type()
+}
+
+// Info returns the fs.FileInfo of the entry, reading the file contents if they have not been read
+func (e fsDirEntry) Info() (fs.FileInfo, error) {
+	e.fs.Lock()
+	defer e.fs.Unlock()
+
+	return e.fs.info("stat", e.path, e.node)
+}
+
+// Stat returns the fs.FileInfo of the file
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the file
+func (f *fsFile) Close() error {
+	return nil
+}
+
+// Stat returns the fs.FileInfo of the directory
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read returns an error as directories cannot be read
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fmt.Errorf("is a directory")}
+}
+
+// Close closes the directory
+func (d *fsDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the next n entries of the directory, all remaining entries are returned if n <= 0
+func (d *fsDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	remaining := len(d.entries) - d.offset
+	if n <= 0 {
+		entries = d.entries[d.offset:]
+		d.offset = len(d.entries)
+		return
+	}
+
+	if remaining == 0 {
+		err = io.EOF
+		return
+	}
+
+	if n > remaining {
+		n = remaining
+	}
+
+	entries = d.entries[d.offset : d.offset+n]
+	d.offset += n
+
+	return
+}
@@ -0,0 +1,568 @@
+package tela
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/civilware/tela/logger"
+)
+
+// Route TELA content is served on by a Gateway
+type GatewayRoute uint8
+
+const (
+	GATEWAY_SUBDOMAIN GatewayRoute = iota // Content is served at <scid[:32]>.<scid[32:]>.localhost:port as a DNS label is limited to 63 characters, each app has its own origin
+	GATEWAY_PATH                          // Content is served at localhost:port/tela/<scid>/, or by dURL once loaded, all apps share the gateway origin
+)
+
+// Path prefix of content served with GATEWAY_PATH
+const GATEWAY_PATH_PREFIX = "/tela/"
+
+// Config for starting a Gateway with StartGateway, zero values will use the TELA host defaults
+type GatewayConfig struct {
+	Port        int           // Port to listen on, defaults to the first open port from PortStart
+	Route       GatewayRoute  // Route content is served on
+	Endpoint    string        // Daemon endpoint content is cloned from
+	MaxApps     int           // Max amount of apps kept cloned, the least recently used app is evicted when exceeded. Apps still loading are not counted. Defaults to MaxServers
+	IdleTimeout time.Duration // Apps not requested within IdleTimeout are evicted, zero will not evict idle apps
+}
+
+// Gateway serving all TELA content from a single listener, content is cloned on demand when first requested
+type Gateway struct {
+	sync.Mutex
+	tela   *TELA
+	config GatewayConfig
+	server *http.Server
+	port   int
+	path   string
+	apps   map[string]*gatewayApp
+	ctx    context.Context // Apps are cloned using ctx so they are not canceled with the request that started them
+	cancel context.CancelFunc
+}
+
+// TELA content loaded by a Gateway
+type gatewayApp struct {
+	scid     string
+	commit   string // Commit TXID the app was cloned at, empty if the app follows its update rule
+	path     string // Directory the app is cloned into, each load of the app has its own directory
+	clone    Cloning
+	handler  http.Handler
+	lastUsed time.Time
+	loaded   chan struct{} // Closed once the app has been cloned
+	err      error
+	active   int  // Requests being served from the app's files
+	evicted  bool // App has been evicted, its files are removed once it has no active requests
+}
+
+// Returns TELA gateway path
+func (s ds) gateway() string {
+	return filepath.Join(s.main, "gateway")
+}
+
+// Returns the string representation of GatewayRoute
+func (r GatewayRoute) String() string {
+	switch r {
+	case GATEWAY_SUBDOMAIN:
+		return "subdomain"
+	case GATEWAY_PATH:
+		return "path"
+	default:
+		return "unknown"
+	}
+}
+
+// StartGateway starts a Gateway serving all TELA content on a single port in place of a server per app.
+// Content is cloned from config.Endpoint when first requested and evicted when MaxApps is exceeded or IdleTimeout passes
+func (t *TELA) StartGateway(config GatewayConfig) (gateway *Gateway, err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.gateway != nil {
+		err = fmt.Errorf("gateway is already running on port %d", t.gateway.port)
+		return
+	}
+
+	switch config.Route {
+	case GATEWAY_SUBDOMAIN, GATEWAY_PATH:
+	default:
+		err = fmt.Errorf("unknown gateway route %d", config.Route)
+		return
+	}
+
+	if config.MaxApps < 1 {
+		config.MaxApps = t.max
+	}
+
+	var listener net.Listener
+	if config.Port == 0 {
+		server, found := t.FindOpenPort()
+		if !found {
+			err = newError(ErrNoOpenPort, "", "", "", nil)
+			return
+		}
+
+		config.Port, _ = strconv.Atoi(strings.TrimPrefix(server.Addr, ":"))
+	}
+
+	listener, err = net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
+	if err != nil {
+		return
+	}
+
+	gateway = &Gateway{
+		tela:   t,
+		config: config,
+		port:   listener.Addr().(*net.TCPAddr).Port,
+		path:   t.path.gateway(),
+		apps:   map[string]*gatewayApp{},
+	}
+
+	gateway.ctx, gateway.cancel = context.WithCancel(context.Background())
+
+	// Remove any residual gateway files
+	os.RemoveAll(gateway.path)
+
+	gateway.server = &http.Server{Handler: gateway}
+	t.gateway = gateway
+
+	go func() {
+		logger.Printf("[TELA] Gateway serving %s routes at :%d\n", config.Route, gateway.port)
+		err := gateway.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errorf("[TELA] Gateway :%d %s\n", gateway.port, err)
+		}
+	}()
+
+	return
+}
+
+// GetGateway returns the running Gateway of the TELA host, or nil if no Gateway is running
+func (t *TELA) GetGateway() *Gateway {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.gateway
+}
+
+// ShutdownGateway shuts down the running Gateway and removes all of its content
+func (t *TELA) ShutdownGateway() {
+	t.Lock()
+	gateway := t.gateway
+	t.gateway = nil
+	t.Unlock()
+
+	if gateway != nil {
+		gateway.shutdown()
+	}
+}
+
+// Shutdown the gateway server and remove all of its content
+func (g *Gateway) shutdown() {
+	logger.Printf("[TELA] Gateway shutdown :%d\n", g.port)
+	g.cancel()
+	err := g.server.Shutdown(context.Background())
+	if err != nil {
+		logger.Errorf("[TELA] Gateway shutdown: %s\n", err)
+	}
+
+	g.Lock()
+	g.apps = map[string]*gatewayApp{}
+	g.Unlock()
+
+	os.RemoveAll(g.path)
+}
+
+// Port returns the port the Gateway is listening on
+func (g *Gateway) Port() int {
+	return g.port
+}
+
+// Route returns the GatewayRoute content is served on
+func (g *Gateway) Route() GatewayRoute {
+	return g.config.Route
+}
+
+// Link returns the link to a TELA-INDEX SCID on the Gateway, the content is cloned when the link is first requested
+func (g *Gateway) Link(scid string) string {
+	return g.link(scid, "")
+}
+
+// LinkAtCommit returns the link to a TELA-INDEX SCID at commit TXID on the Gateway, the content is cloned when the link is first
+// requested and is served with immutable cache headers. GATEWAY_PATH links are at localhost:port/tela/<scid>@<txid>/ and
+// GATEWAY_SUBDOMAIN links have the two labels of the TXID before the SCID
+func (g *Gateway) LinkAtCommit(scid, txid string) string {
+	return g.link(scid, txid)
+}
+
+// Get the link to scid at commit, an empty commit is the content of the SCID's update rule
+func (g *Gateway) link(scid, commit string) string {
+	if g.config.Route == GATEWAY_PATH {
+		return fmt.Sprintf("http://localhost:%d%s%s/", g.port, GATEWAY_PATH_PREFIX, appKey(scid, commit))
+	}
+
+	host := subdomain(scid)
+	if commit != "" {
+		host = subdomain(commit) + "." + host
+	}
+
+	return fmt.Sprintf("http://%s.localhost:%d/", host, g.port)
+}
+
+// Get the key of the app for scid at commit
+func appKey(scid, commit string) string {
+	if commit == "" {
+		return scid
+	}
+
+	return scid + "@" + commit
+}
+
+// Get the subdomain of scid, the SCID is split into two labels as a DNS label is limited to 63 characters
+func subdomain(scid string) string {
+	if len(scid) != 64 {
+		return scid
+	}
+
+	return scid[:32] + "." + scid[32:]
+}
+
+// Load clones a TELA-INDEX SCID on the Gateway if it is not already loaded and returns a link to its entrypoint
+func (g *Gateway) Load(ctx context.Context, scid string) (link string, err error) {
+	return g.loadLink(ctx, scid, "")
+}
+
+// LoadAtCommit clones a TELA-INDEX SCID at commit TXID on the Gateway if it is not already loaded and returns a link to its entrypoint,
+// the commit must be allowed the same as ServeAtCommit
+func (g *Gateway) LoadAtCommit(ctx context.Context, scid, txid string) (link string, err error) {
+	return g.loadLink(ctx, scid, txid)
+}
+
+// Load scid at commit and get the link to its entrypoint
+func (g *Gateway) loadLink(ctx context.Context, scid, commit string) (link string, err error) {
+	app, err := g.load(ctx, scid, commit)
+	if err != nil {
+		return
+	}
+
+	g.release(app)
+
+	link = g.link(scid, commit) + strings.TrimPrefix(path.Join(app.clone.ServePath, app.clone.Entrypoint), "/")
+
+	return
+}
+
+// Evict removes a TELA-INDEX SCID and any of its commits from the Gateway, they will be cloned again when next requested
+func (g *Gateway) Evict(scid string) {
+	g.Lock()
+	defer g.Unlock()
+
+	for _, app := range g.apps {
+		if app.scid == scid && app.done() {
+			g.evict(app)
+		}
+	}
+}
+
+// Apps returns the info of all content loaded on the Gateway
+func (g *Gateway) Apps() (apps []ServerInfo) {
+	g.Lock()
+	defer g.Unlock()
+
+	for _, app := range g.apps {
+		if !app.done() || app.err != nil {
+			continue
+		}
+
+		apps = append(apps, ServerInfo{
+			Name:          app.clone.DURL,
+			Address:       g.link(app.scid, app.commit),
+			SCID:          app.scid,
+			Entrypoint:    app.clone.Entrypoint,
+			Commit:        app.clone.Hash,
+			Verifications: app.clone.Verifications,
+		})
+	}
+
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Address < apps[j].Address })
+
+	return
+}
+
+// Check if the app has finished loading
+func (a *gatewayApp) done() bool {
+	select {
+	case <-a.loaded:
+		return true
+	default:
+		return false
+	}
+}
+
+// Remove an app from the gateway, its files are removed once it has no active requests. Caller must hold the lock
+func (g *Gateway) evict(app *gatewayApp) {
+	logger.Printf("[TELA] Gateway evicting %s\n", app.clone.DURL)
+	if key := appKey(app.scid, app.commit); g.apps[key] == app {
+		delete(g.apps, key)
+	}
+
+	app.evicted = true
+	g.removeFiles(app)
+}
+
+// Remove the files of an evicted app if it has no active requests, caller must hold the lock
+func (g *Gateway) removeFiles(app *gatewayApp) {
+	if !app.evicted || app.active > 0 {
+		return
+	}
+
+	os.RemoveAll(app.path)
+	// SCID directory is only removed if no other load of the app is using it
+	os.Remove(filepath.Dir(app.path))
+}
+
+// Release an app returned by load once its files are no longer being served
+func (g *Gateway) release(app *gatewayApp) {
+	g.Lock()
+	defer g.Unlock()
+
+	app.active--
+	g.removeFiles(app)
+}
+
+// Evict apps that have been idle longer than IdleTimeout and the least recently used apps if more than MaxApps
+// are loaded, keep is not evicted. Apps that are still loading are not counted, caller must hold the lock
+func (g *Gateway) evictApps(keep *gatewayApp) {
+	var loaded []*gatewayApp
+	for _, app := range g.apps {
+		if !app.done() || app.err != nil {
+			continue
+		}
+
+		if app != keep && g.config.IdleTimeout > 0 && time.Since(app.lastUsed) > g.config.IdleTimeout {
+			g.evict(app)
+			continue
+		}
+
+		loaded = append(loaded, app)
+	}
+
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].lastUsed.Before(loaded[j].lastUsed) })
+
+	over := len(loaded) - g.config.MaxApps
+	for i := 0; over > 0 && i < len(loaded); i++ {
+		if loaded[i] != keep {
+			g.evict(loaded[i])
+			over--
+		}
+	}
+}
+
+// Get a loaded app by scid at commit, cloning it if it is not loaded. An empty commit clones the content of the SCID's update rule.
+// Concurrent loads of the same app will wait on a single clone and each load only waits until its own ctx is done.
+// The app's files are kept until the app is released
+func (g *Gateway) load(ctx context.Context, scid, commit string) (app *gatewayApp, err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	if commit != "" && len(commit) != 64 {
+		err = newError(ErrInvalidCommit, scid, "", commit, nil)
+		return
+	}
+
+	key := appKey(scid, commit)
+
+	g.Lock()
+	app, ok := g.apps[key]
+	if !ok {
+		g.evictApps(nil)
+		app, err = g.newApp(scid, commit)
+		if err != nil {
+			g.Unlock()
+			return
+		}
+
+		g.apps[key] = app
+		go g.clone(app)
+	}
+	g.Unlock()
+
+	select {
+	case <-app.loaded:
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
+
+	if app.err != nil {
+		err = app.err
+		return
+	}
+
+	g.Lock()
+	app.lastUsed = time.Now()
+	app.active++
+	g.Unlock()
+
+	return
+}
+
+// Create an app for scid at commit with its own directory to be cloned into, caller must hold the lock
+func (g *Gateway) newApp(scid, commit string) (app *gatewayApp, err error) {
+	dir := filepath.Join(g.path, scid)
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+
+	path, err := os.MkdirTemp(dir, "")
+	if err != nil {
+		return
+	}
+
+	app = &gatewayApp{scid: scid, commit: commit, path: path, loaded: make(chan struct{})}
+
+	return
+}
+
+// Clone app using the gateway's context, waiting loads are signaled once the clone is done.
+// Apps at a commit are pinned and served with immutable cache headers
+func (g *Gateway) clone(app *gatewayApp) {
+	if app.commit != "" {
+		app.clone, app.err = g.tela.cloneAllowedCommit(g.ctx, app.scid, app.commit, app.path, g.config.Endpoint)
+	} else {
+		app.clone, app.err = g.tela.cloneINDEX(g.ctx, app.scid, app.path, g.config.Endpoint)
+	}
+
+	if app.err == nil && strings.HasSuffix(app.clone.DURL, TAG_LIBRARY) {
+		app.err = newError(ErrLibrary, app.scid, app.clone.DURL, "", nil)
+	}
+
+	// Gateway was shutdown while cloning
+	if app.err == nil && g.ctx.Err() != nil {
+		app.err = g.ctx.Err()
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if app.err != nil {
+		if key := appKey(app.scid, app.commit); g.apps[key] == app {
+			delete(g.apps, key)
+		}
+
+		app.evicted = true
+		g.removeFiles(app)
+	} else {
+		app.handler = g.tela.secureHandler(app.scid, g.config.Endpoint, g.tela.contentHandler(app.clone))
+		app.lastUsed = time.Now()
+		logger.Printf("[TELA] Gateway loaded %s at %s\n", app.clone.DURL, g.link(app.scid, app.commit))
+	}
+
+	close(app.loaded)
+
+	if app.err == nil {
+		g.evictApps(app)
+	}
+}
+
+// Get the scid, commit and path prefix requested, GATEWAY_PATH routes can use the dURL of content that has been loaded
+func (g *Gateway) route(r *http.Request) (scid, commit, prefix string, ok bool) {
+	if g.config.Route == GATEWAY_PATH {
+		if !strings.HasPrefix(r.URL.Path, GATEWAY_PATH_PREFIX) {
+			return
+		}
+
+		key, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, GATEWAY_PATH_PREFIX), "/")
+		if key == "" {
+			return
+		}
+
+		prefix = GATEWAY_PATH_PREFIX + key
+		scid, commit, _ = strings.Cut(key, "@")
+		if len(scid) != 64 && commit == "" {
+			g.Lock()
+			for _, app := range g.apps {
+				if app.commit == "" && app.done() && app.err == nil && app.clone.DURL == key {
+					scid = app.scid
+					break
+				}
+			}
+			g.Unlock()
+		}
+
+		return scid, commit, prefix, true
+	}
+
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	// Each SCID and commit is split into two labels
+	labels := strings.Split(host, ".")
+	split := func(i int) bool {
+		return len(labels) > i+2 && len(labels[i]) == 32 && len(labels[i+1]) == 32
+	}
+
+	switch {
+	case split(0) && split(2):
+		return labels[2] + labels[3], labels[0] + labels[1], "", true
+	case split(0):
+		return labels[0] + labels[1], "", "", true
+	default:
+		return
+	}
+}
+
+// ServeHTTP serves the TELA content requested, cloning it if it is not loaded
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scid, commit, prefix, ok := g.route(r)
+	if !ok {
+		http.Error(w, "no TELA content requested", http.StatusNotFound)
+		return
+	}
+
+	// Content is served relative to its root
+	if prefix != "" && r.URL.Path == prefix {
+		http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	// Error details stay in the log as they can include local paths and daemon responses
+	app, err := g.load(r.Context(), scid, commit)
+	if err != nil {
+		status := gatewayStatus(err)
+		logger.Errorf("[TELA] Gateway %s %s: %s\n", r.Method, r.URL.Path, err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	defer g.release(app)
+
+	http.StripPrefix(prefix, app.handler).ServeHTTP(w, r)
+}
+
+// HTTP status code for errors loading content on the gateway
+func gatewayStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidSCID), errors.Is(err, ErrNotTELA), errors.Is(err, ErrMissingHeader):
+		return http.StatusNotFound
+	case errors.Is(err, ErrLibrary), errors.Is(err, ErrUpdated), errors.Is(err, ErrSignature), errors.Is(err, ErrUnsafePath):
+		return http.StatusForbidden
+	case errors.Is(err, ErrDaemon):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
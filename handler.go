@@ -0,0 +1,142 @@
+package tela
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/civilware/tela/logger"
+)
+
+// Handler serving TELA content from a host application's own HTTP server, create with NewHandler or NewHandlerAtCommit.
+// The Handler can be mounted under any route with http.StripPrefix, requests to its root are redirected to the entrypoint
+type Handler struct {
+	sync.Mutex
+	tela    *TELA
+	info    ServerInfo
+	path    string // Directory the content was cloned into
+	handler http.Handler
+	closed  bool
+}
+
+// Returns TELA handler path
+func (s ds) handler() string {
+	return filepath.Join(s.main, "handler")
+}
+
+// NewHandler clones a TELA-INDEX-1 SC from endpoint and returns a Handler serving its content,
+// the commit cloned follows the SCID's update rule the same as ServeTELA. Close the Handler when done to remove its files
+func (t *TELA) NewHandler(scid, endpoint string) (handler *Handler, err error) {
+	return t.NewHandlerContext(context.Background(), scid, endpoint)
+}
+
+// NewHandlerContext is NewHandler using ctx for all daemon requests
+func (t *TELA) NewHandlerContext(ctx context.Context, scid, endpoint string) (handler *Handler, err error) {
+	return t.newHandler(scid, endpoint, func(path string) (Cloning, error) {
+		return t.cloneINDEX(ctx, scid, path, endpoint)
+	})
+}
+
+// NewHandlerAtCommit clones a TELA-INDEX-1 SC from endpoint at commit TXID and returns a Handler serving its content,
+// the commit must be allowed the same as ServeAtCommit. Close the Handler when done to remove its files
+func (t *TELA) NewHandlerAtCommit(scid, txid, endpoint string) (handler *Handler, err error) {
+	return t.NewHandlerAtCommitContext(context.Background(), scid, txid, endpoint)
+}
+
+// NewHandlerAtCommitContext is NewHandlerAtCommit using ctx for all daemon requests
+func (t *TELA) NewHandlerAtCommitContext(ctx context.Context, scid, txid, endpoint string) (handler *Handler, err error) {
+	return t.newHandler(scid, endpoint, func(path string) (Cloning, error) {
+		return t.cloneAllowedCommit(ctx, scid, txid, path, endpoint)
+	})
+}
+
+// Create a Handler for scid with the content from clone of endpoint, each Handler is cloned into its own directory
+func (t *TELA) newHandler(scid, endpoint string, clone func(path string) (Cloning, error)) (handler *Handler, err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	if err = os.MkdirAll(t.path.handler(), os.ModePerm); err != nil {
+		return
+	}
+
+	path, err := os.MkdirTemp(t.path.handler(), scid[:16]+"-")
+	if err != nil {
+		return
+	}
+
+	c, err := clone(path)
+	if err == nil && strings.HasSuffix(c.DURL, TAG_LIBRARY) {
+		err = newError(ErrLibrary, scid, c.DURL, "", nil)
+	}
+
+	if err != nil {
+		os.RemoveAll(path)
+		return
+	}
+
+	handler = &Handler{
+		tela:    t,
+		info:    ServerInfo{Name: c.DURL, SCID: scid, Entrypoint: c.Entrypoint, Commit: c.Hash, Verifications: c.Verifications},
+		path:    path,
+		handler: t.secureHandler(scid, endpoint, t.contentHandler(c)),
+	}
+
+	t.Lock()
+	if t.handlers == nil {
+		t.handlers = make(map[*Handler]struct{})
+	}
+	t.handlers[handler] = struct{}{}
+	t.Unlock()
+
+	logger.Printf("[TELA] Handler created for %s\n", c.DURL)
+
+	return
+}
+
+// ServeHTTP serves the TELA content of the Handler, content is not served once the Handler is closed
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	closed := h.closed
+	h.Unlock()
+
+	if closed {
+		http.Error(w, "TELA content is closed", http.StatusGone)
+		return
+	}
+
+	h.handler.ServeHTTP(w, r)
+}
+
+// Info returns the info of the content served by the Handler, Address is empty as the host application owns the server
+func (h *Handler) Info() ServerInfo {
+	return h.info
+}
+
+// Close stops the Handler from serving content and removes its files
+func (h *Handler) Close() {
+	h.tela.Lock()
+	delete(h.tela.handlers, h)
+	h.tela.Unlock()
+
+	h.close()
+}
+
+// Close the Handler and remove its files
+func (h *Handler) close() {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.closed = true
+	logger.Printf("[TELA] Handler closed for %s\n", h.info.Name)
+	os.RemoveAll(h.path)
+}
@@ -31,6 +31,9 @@ const (
 	HEADER_DOCUMENT     Header = `"DOC` // append with Number()
 	HEADER_SUBDIR       Header = `"subDir"`
 	HEADER_DOCTYPE      Header = `"docType"`
+	HEADER_PART         Header = `"docPart"`
+	HEADER_PARTS        Header = `"docParts"`
+	HEADER_ENCODING     Header = `"docEncoding"`
 	HEADER_COLLECTION   Header = `"collection"`
 	HEADER_TYPE         Header = `"typeHdr"`
 	HEADER_TAGS         Header = `"tagsHdr"`
@@ -0,0 +1,172 @@
+package tela
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deroproject/derohe/rpc"
+)
+
+// Commit of a TELA-INDEX, commit 0 is the original install and each UpdateCode call adds a commit
+type INDEXCommit struct {
+	Number uint64   `json:"number"` // Commit number of the INDEX
+	TXID   string   `json:"txid"`   // TXID of the commit
+	Height int64    `json:"height"` // Block height of the commit TXID
+	DOCs   []string `json:"docs"`   // DOC SCIDs of the INDEX at this commit
+}
+
+// Get the commit TXIDs of a TELA-INDEX in order of commit number
+func (t *TELA) getCommitTXIDs(ctx context.Context, scid, endpoint string) (txids []string, err error) {
+	var params = rpc.GetSC_Params{SCID: scid, Variables: true, Code: false}
+	var result rpc.GetSC_Result
+
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
+	if err != nil {
+		err = daemonError(ctx, scid, "", err)
+		return
+	}
+
+	c, _ := result.VariableStringKeys["C"].(string)
+	if _, err = EqualSmartContracts(TELA_INDEX_1, decodeHexString(c)); err != nil {
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("does not parse as TELA-INDEX-1: %s", err))
+		return
+	}
+
+	commit, ok := result.VariableStringKeys["commit"].(float64)
+	if !ok {
+		err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("invalid string value for %q", "commit"))
+		return
+	}
+
+	for n := uint64(0); n <= uint64(commit); n++ {
+		txid, ok := result.VariableUint64Keys[n].(string)
+		if !ok || txid == "" {
+			err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("invalid value for commit %d", n))
+			return
+		}
+
+		txids = append(txids, decodeHexString(txid))
+	}
+
+	return
+}
+
+// Get the commit TXID of a TELA-INDEX by its commit number
+func (t *TELA) getCommitTXID(ctx context.Context, scid string, commit uint64, endpoint string) (txid string, err error) {
+	var params = rpc.GetSC_Params{SCID: scid, Variables: false, Code: false, KeysUint64: []uint64{commit}}
+	var result rpc.GetSC_Result
+
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
+	if err != nil {
+		err = daemonError(ctx, scid, "", err)
+		return
+	}
+
+	res := result.ValuesUint64
+	if len(res) < 1 || res[0] == "" || strings.Contains(res[0], "NOT AVAILABLE err:") {
+		err = newError(ErrInvalidCommit, scid, "", "", fmt.Errorf("commit %d not found", commit))
+		return
+	}
+
+	txid = decodeHexString(res[0])
+
+	return
+}
+
+// Get transactions as hex and their related info from daemon endpoint
+func (t *TELA) getTransactions(ctx context.Context, txids []string, endpoint string) (result rpc.GetTransaction_Result, err error) {
+	var params = rpc.GetTransaction_Params{Tx_Hashes: txids}
+
+	result, err = t.getDaemon().GetTransaction(ctx, endpoint, params)
+	if err != nil {
+		err = daemonError(ctx, "", "", err)
+		return
+	}
+
+	if len(result.Txs_as_hex) != len(txids) {
+		err = newError(ErrInvalidCommit, "", "", "", fmt.Errorf("expected %d TXIDs and got %d", len(txids), len(result.Txs_as_hex)))
+		return
+	}
+
+	return
+}
+
+// GetINDEXHistory returns every commit of a TELA-INDEX SCID from endpoint with the DOCs it embedded at that commit
+func (t *TELA) GetINDEXHistory(scid, endpoint string) (history []INDEXCommit, err error) {
+	return t.GetINDEXHistoryContext(context.Background(), scid, endpoint)
+}
+
+// GetINDEXHistoryContext is GetINDEXHistory using ctx for all daemon requests
+func (t *TELA) GetINDEXHistoryContext(ctx context.Context, scid, endpoint string) (history []INDEXCommit, err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	txids, err := t.getCommitTXIDs(ctx, scid, endpoint)
+	if err != nil {
+		return
+	}
+
+	result, err := t.getTransactions(ctx, txids, endpoint)
+	if err != nil {
+		err = withContent(err, scid, "", "")
+		return
+	}
+
+	for i, txid := range txids {
+		commit := INDEXCommit{Number: uint64(i), TXID: txid}
+		if i < len(result.Txs) {
+			commit.Height = result.Txs[i].Block_Height
+		}
+
+		var code string
+		code, err = extractCodeFromTXID(result.Txs_as_hex[i])
+		if err != nil {
+			err = newError(ErrNotTELA, scid, "", txid, fmt.Errorf("could not get SC code: %s", err))
+			return
+		}
+
+		commit.DOCs, err = ParseINDEXForDOCs(code)
+		if err != nil {
+			err = newError(ErrNotTELA, scid, "", txid, err)
+			return
+		}
+
+		history = append(history, commit)
+	}
+
+	return
+}
+
+// CloneAtCommitNumber clones a TELA-INDEX SC at its commit number from endpoint
+func (t *TELA) CloneAtCommitNumber(scid string, commit uint64, endpoint string) (err error) {
+	return t.CloneAtCommitNumberContext(context.Background(), scid, commit, endpoint)
+}
+
+// CloneAtCommitNumberContext is CloneAtCommitNumber using ctx for all daemon requests
+func (t *TELA) CloneAtCommitNumberContext(ctx context.Context, scid string, commit uint64, endpoint string) (err error) {
+	txid, err := t.getCommitTXID(ctx, scid, commit, endpoint)
+	if err != nil {
+		return
+	}
+
+	return t.CloneAtCommitContext(ctx, scid, txid, endpoint)
+}
+
+// ServeAtCommitNumber clones and serves a TELA-INDEX-1 SC from endpoint at its commit number, the commit must be allowed
+// by the SCID's update rule, with no rule set AllowUpdates must be true to serve any commit other than the original install
+func (t *TELA) ServeAtCommitNumber(scid string, commit uint64, endpoint string) (link string, err error) {
+	return t.ServeAtCommitNumberContext(context.Background(), scid, commit, endpoint)
+}
+
+// ServeAtCommitNumberContext is ServeAtCommitNumber using ctx for all daemon requests
+func (t *TELA) ServeAtCommitNumberContext(ctx context.Context, scid string, commit uint64, endpoint string) (link string, err error) {
+	txid, err := t.getCommitTXID(ctx, scid, commit, endpoint)
+	if err != nil {
+		return
+	}
+
+	return t.ServeAtCommitContext(ctx, scid, txid, endpoint)
+}
@@ -0,0 +1,752 @@
+package tela
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Stylesheet of TELA-MD-1 DOCs rendered to HTML
+const MARKDOWN_STYLE = `body{margin:0;background:#fff;color:#1f2328;font:16px/1.6 -apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif}
+main{max-width:860px;margin:0 auto;padding:32px 24px}
+h1,h2{padding-bottom:.3em;border-bottom:1px solid #d1d9e0}
+h1,h2,h3,h4,h5,h6{margin:24px 0 16px;line-height:1.25}
+a{color:#0969da}
+code,pre{font:85% ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;background:#f6f8fa;border-radius:6px}
+code{padding:.2em .4em}
+pre{padding:16px;overflow:auto}
+pre code{padding:0;background:none;font-size:100%}
+blockquote{margin:0 0 16px;padding:0 1em;color:#59636e;border-left:.25em solid #d1d9e0}
+table{border-collapse:collapse;margin-bottom:16px}
+th,td{padding:6px 13px;border:1px solid #d1d9e0}
+hr{height:.25em;border:0;background:#d1d9e0}
+img{max-width:100%}
+@media (prefers-color-scheme:dark){body{background:#0d1117;color:#f0f6fc}a{color:#4493f8}code,pre{background:#151b23}h1,h2,th,td{border-color:#3d444d}blockquote{color:#9198a1;border-color:#3d444d}hr{background:#3d444d}}`
+
+var (
+	mdHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdBreak     = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFence     = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+	mdQuote     = regexp.MustCompile(`^ {0,3}> ?`)
+	mdItem      = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	mdSetext    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdTableRule = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdReference = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*<?([^ \t>]+)>?(?:[ \t]+["'(](.*)["')])?[ \t]*$`)
+	mdScheme    = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+	mdAutolink  = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\s]*)>`)
+	mdEmail     = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*)>`)
+)
+
+// Link schemes allowed in rendered markdown, links with any other scheme are not rendered
+var markdownSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "tela": true}
+
+// Renders markdown to sanitized HTML, raw HTML within the markdown is escaped and links are resolved with resolve
+type markdownRenderer struct {
+	resolve    func(link string) string
+	references map[string]markdownLink
+	ids        map[string]int
+	title      string
+}
+
+// Link destination and title of a markdown link
+type markdownLink struct {
+	dest  string
+	title string
+}
+
+// Render markdown source to a sanitized HTML document with MARKDOWN_STYLE. Relative links are passed to resolve,
+// name is the document title if the markdown has no heading
+func renderMarkdown(source, name string, resolve func(link string) string) string {
+	m := &markdownRenderer{resolve: resolve, references: map[string]markdownLink{}, ids: map[string]int{}}
+
+	// Reference definitions can be used before they are defined
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		if match := mdReference.FindStringSubmatch(line); match != nil {
+			label := strings.ToLower(strings.TrimSpace(match[1]))
+			if _, ok := m.references[label]; !ok {
+				m.references[label] = markdownLink{dest: match[2], title: match[3]}
+			}
+			continue
+		}
+
+		lines = append(lines, strings.ReplaceAll(line, "\t", "    "))
+	}
+
+	body := m.blocks(lines, false)
+
+	title := m.title
+	if title == "" {
+		title = name
+	}
+
+	return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<main>\n%s</main>\n</body>\n</html>\n",
+		html.EscapeString(title), MARKDOWN_STYLE, body)
+}
+
+// Render block level markdown lines, paragraphs of tight list items are not wrapped
+func (m *markdownRenderer) blocks(lines []string, tight bool) string {
+	var out strings.Builder
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFence.MatchString(line):
+			match := mdFence.FindStringSubmatch(line)
+			indent, fence := len(match[1]), match[2]
+			var code []string
+			for i++; i < len(lines); i++ {
+				trimmed := strings.TrimSpace(lines[i])
+				if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+					i++
+					break
+				}
+
+				code = append(code, trimIndent(lines[i], indent))
+			}
+
+			class := ""
+			if lang := strings.Fields(match[3]); len(lang) > 0 {
+				class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(lang[0]))
+			}
+
+			fmt.Fprintf(&out, "<pre><code%s>%s</code></pre>\n", class, codeBlock(code))
+
+		case mdHeading.MatchString(line):
+			match := mdHeading.FindStringSubmatch(line)
+			m.heading(&out, len(match[1]), match[2])
+			i++
+
+		case mdBreak.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+
+		case mdQuote.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdQuote.ReplaceAllString(lines[i], ""))
+			}
+
+			fmt.Fprintf(&out, "<blockquote>\n%s</blockquote>\n", m.blocks(quote, false))
+
+		case mdItem.MatchString(line):
+			i = m.list(&out, lines, i)
+
+		case strings.HasPrefix(line, "    "):
+			var code []string
+			for ; i < len(lines) && (strings.HasPrefix(lines[i], "    ") || strings.TrimSpace(lines[i]) == ""); i++ {
+				code = append(code, trimIndent(lines[i], 4))
+			}
+
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+
+			fmt.Fprintf(&out, "<pre><code>%s</code></pre>\n", codeBlock(code))
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && mdTableRule.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = m.table(&out, lines, i)
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if len(para) > 0 && (strings.TrimSpace(l) == "" || mdFence.MatchString(l) || mdHeading.MatchString(l) || mdQuote.MatchString(l) || mdItem.MatchString(l) || (mdBreak.MatchString(l) && !mdSetext.MatchString(l))) {
+					break
+				}
+
+				// Setext headings underline their paragraph
+				if len(para) > 0 && mdSetext.MatchString(l) {
+					level := 1
+					if strings.TrimSpace(l)[0] == '-' {
+						level = 2
+					}
+
+					m.heading(&out, level, strings.Join(para, "\n"))
+					para = nil
+					i++
+					break
+				}
+
+				para = append(para, strings.TrimLeft(l, " "))
+			}
+
+			if len(para) > 0 {
+				text := m.inline(strings.TrimRight(strings.Join(para, "\n"), " "))
+				if tight {
+					out.WriteString(text + "\n")
+				} else {
+					fmt.Fprintf(&out, "<p>%s</p>\n", text)
+				}
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// Render a heading with a unique id from its text
+func (m *markdownRenderer) heading(out *strings.Builder, level int, text string) {
+	text = strings.TrimSpace(text)
+	if level == 1 && m.title == "" {
+		m.title = text
+	}
+
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		case r == ' ':
+			return '-'
+		default:
+			return -1
+		}
+	}, text)
+
+	if n := m.ids[id]; n > 0 {
+		m.ids[id]++
+		id = fmt.Sprintf("%s-%d", id, n)
+	} else {
+		m.ids[id] = 1
+	}
+
+	fmt.Fprintf(out, "<h%d id=\"%s\">%s</h%d>\n", level, id, m.inline(text), level)
+}
+
+// Render the list starting at lines[i], returning the index of the line after the list
+func (m *markdownRenderer) list(out *strings.Builder, lines []string, i int) int {
+	first := mdItem.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	marker := first[2][len(first[2])-1:]
+
+	var items [][]string
+	tight, gap := true, false
+	for i < len(lines) {
+		match := mdItem.FindStringSubmatch(lines[i])
+		if match == nil || (match[2][0] >= '0' && match[2][0] <= '9') != ordered || match[2][len(match[2])-1:] != marker || mdBreak.MatchString(lines[i]) {
+			break
+		}
+
+		// Items separated by blank lines make the list loose
+		if gap {
+			tight = false
+		}
+
+		// Content of the item is indented to the start of its text
+		indent := len(match[0])
+		if strings.TrimSpace(lines[i]) == strings.TrimSpace(match[0]) {
+			indent = len(match[1]) + len(match[2]) + 1
+		}
+
+		item := []string{lines[i][len(match[0]):]}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// Blank lines within an item only continue it when followed by indented content
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+
+				if j < len(lines) && countIndent(lines[j]) >= indent {
+					tight = false
+					item = append(item, "")
+					continue
+				}
+
+				break
+			}
+
+			if countIndent(l) >= indent {
+				item = append(item, trimIndent(l, indent))
+				continue
+			}
+
+			// Lazy continuation of the item's paragraph
+			if !mdItem.MatchString(l) && !mdHeading.MatchString(l) && !mdFence.MatchString(l) && !mdQuote.MatchString(l) && !mdBreak.MatchString(l) && strings.TrimSpace(item[len(item)-1]) != "" {
+				item = append(item, strings.TrimSpace(l))
+				continue
+			}
+
+			break
+		}
+
+		items = append(items, item)
+
+		// Skip blank lines between items
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+
+		if j >= len(lines) || !mdItem.MatchString(lines[j]) {
+			break
+		}
+
+		gap = j > i
+		i = j
+	}
+
+	tag := "ul"
+	start := ""
+	if ordered {
+		tag = "ol"
+		if n, err := strconv.Atoi(first[2][:len(first[2])-1]); err == nil && n != 1 {
+			start = fmt.Sprintf(` start="%d"`, n)
+		}
+	}
+
+	fmt.Fprintf(out, "<%s%s>\n", tag, start)
+	for _, item := range items {
+		fmt.Fprintf(out, "<li>%s</li>\n", strings.TrimSuffix(m.blocks(item, tight), "\n"))
+	}
+	fmt.Fprintf(out, "</%s>\n", tag)
+
+	return i
+}
+
+// Render the table starting at lines[i], returning the index of the line after the table
+func (m *markdownRenderer) table(out *strings.Builder, lines []string, i int) int {
+	header := tableCells(lines[i])
+	var align []string
+	for _, cell := range tableCells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			align = append(align, ` style="text-align:center"`)
+		case strings.HasSuffix(cell, ":"):
+			align = append(align, ` style="text-align:right"`)
+		case strings.HasPrefix(cell, ":"):
+			align = append(align, ` style="text-align:left"`)
+		default:
+			align = append(align, "")
+		}
+	}
+
+	row := func(tag string, cells []string) {
+		out.WriteString("<tr>")
+		for c := range header {
+			var text, style string
+			if c < len(cells) {
+				text = cells[c]
+			}
+
+			if c < len(align) {
+				style = align[c]
+			}
+
+			fmt.Fprintf(out, "<%s%s>%s</%s>", tag, style, m.inline(text), tag)
+		}
+		out.WriteString("</tr>\n")
+	}
+
+	out.WriteString("<table>\n<thead>\n")
+	row("th", header)
+	out.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row("td", tableCells(lines[i]))
+	}
+	out.WriteString("</tbody>\n</table>\n")
+
+	return i
+}
+
+// Split a table row into its trimmed cells
+func tableCells(line string) (cells []string) {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// Count the leading spaces of line
+func countIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// Remove up to n leading spaces from line
+func trimIndent(line string, n int) string {
+	for i := 0; i < n && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+
+	return line
+}
+
+// Escape the lines of a code block
+func codeBlock(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return html.EscapeString(strings.Join(lines, "\n")) + "\n"
+}
+
+// Render inline markdown, all text is escaped
+func (m *markdownRenderer) inline(s string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			out.WriteString("<br>\n")
+			i += 2
+
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0:
+			out.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if end := strings.Index(s[i+n:], strings.Repeat("`", n)); end >= 0 && runLength(s, i+n+end, '`') == n {
+				code := strings.ReplaceAll(s[i+n:i+n+end], "\n", " ")
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+
+				fmt.Fprintf(&out, "<code>%s</code>", html.EscapeString(code))
+				i += n + end + n
+			} else {
+				out.WriteString(s[i : i+n])
+				i += n
+			}
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, link, n, ok := m.link(s[i+1:]); ok {
+				src := m.url(link.dest)
+				if src != "" {
+					fmt.Fprintf(&out, `<img src="%s" alt="%s"%s>`, html.EscapeString(src), html.EscapeString(plainText(text)), titleAttr(link.title))
+				} else {
+					out.WriteString(html.EscapeString(plainText(text)))
+				}
+				i += 1 + n
+			} else {
+				out.WriteString("!")
+				i++
+			}
+
+		case c == '[':
+			if text, link, n, ok := m.link(s[i:]); ok {
+				href := m.url(link.dest)
+				if href != "" {
+					fmt.Fprintf(&out, `<a href="%s"%s>%s</a>`, html.EscapeString(href), titleAttr(link.title), m.inline(text))
+				} else {
+					out.WriteString(m.inline(text))
+				}
+				i += n
+			} else {
+				out.WriteString("[")
+				i++
+			}
+
+		case c == '<':
+			if match := mdAutolink.FindStringSubmatch(s[i:]); match != nil {
+				if href := m.url(match[1]); href != "" {
+					fmt.Fprintf(&out, `<a href="%s">%s</a>`, html.EscapeString(href), html.EscapeString(match[1]))
+				} else {
+					out.WriteString(html.EscapeString(match[0]))
+				}
+				i += len(match[0])
+			} else if match := mdEmail.FindStringSubmatch(s[i:]); match != nil {
+				fmt.Fprintf(&out, `<a href="mailto:%s">%s</a>`, html.EscapeString(match[1]), html.EscapeString(match[1]))
+				i += len(match[0])
+			} else {
+				out.WriteString("&lt;")
+				i++
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			n, rendered := m.emphasis(s, i)
+			out.WriteString(rendered)
+			i += n
+
+		case c == '\n':
+			// Two trailing spaces are a hard line break
+			text := out.String()
+			if strings.HasSuffix(text, "  ") {
+				out.Reset()
+				out.WriteString(strings.TrimRight(text, " ") + "<br>")
+			}
+			out.WriteString("\n")
+			i++
+
+		default:
+			out.WriteString(html.EscapeString(s[i : i+1]))
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// Render emphasis delimited by the run of s[i], returning the length of s used and its HTML
+func (m *markdownRenderer) emphasis(s string, i int) (used int, rendered string) {
+	c := s[i]
+	n := runLength(s, i, c)
+
+	// Delimiters must be followed by text, and _ must not be within a word
+	opens := i+n < len(s) && !isSpace(s[i+n])
+	if c == '_' && i > 0 && isWordChar(s[i-1]) {
+		opens = false
+	}
+
+	var tags []string
+	switch {
+	case c == '~' && n == 2:
+		tags = []string{"del"}
+	case c != '~' && n == 1:
+		tags = []string{"em"}
+	case c != '~' && n == 2:
+		tags = []string{"strong"}
+	case c != '~' && n == 3:
+		tags = []string{"em", "strong"}
+	default:
+		opens = false
+	}
+
+	if opens {
+		if end := closingDelimiter(s, i+n, c, n); end >= 0 {
+			rendered = m.inline(s[i+n : end])
+			for t := len(tags) - 1; t >= 0; t-- {
+				rendered = fmt.Sprintf("<%s>%s</%s>", tags[t], rendered, tags[t])
+			}
+
+			return end + n - i, rendered
+		}
+	}
+
+	return n, html.EscapeString(s[i : i+n])
+}
+
+// Find the closing delimiter run of exactly n c characters from start, skipping code spans and escapes
+func closingDelimiter(s string, start int, c byte, n int) int {
+	for j := start; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+		case '`':
+			r := runLength(s, j, '`')
+			if end := strings.Index(s[j+r:], strings.Repeat("`", r)); end >= 0 {
+				j += r + end + r
+			} else {
+				j += r
+			}
+		case c:
+			r := runLength(s, j, c)
+			closes := r == n && j > start && !isSpace(s[j-1])
+			if c == '_' && j+r < len(s) && isWordChar(s[j+r]) {
+				closes = false
+			}
+
+			if closes {
+				return j
+			}
+
+			j += r
+		default:
+			j++
+		}
+	}
+
+	return -1
+}
+
+// Parse a link of the form [text](dest "title") or a reference link [text][label] at the start of s, returning its length
+func (m *markdownRenderer) link(s string) (text string, link markdownLink, n int, ok bool) {
+	// Find the closing bracket of the text
+	depth := 0
+	end := -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			r := runLength(s, j, '`')
+			if e := strings.Index(s[j+r:], strings.Repeat("`", r)); e >= 0 {
+				j += r + e + r - 1
+			} else {
+				j += r - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+
+	if end < 0 {
+		return
+	}
+
+	text = s[1:end]
+	rest := s[end+1:]
+
+	// Inline link
+	if strings.HasPrefix(rest, "(") {
+		if dest, title, length, found := linkDestination(rest); found {
+			return text, markdownLink{dest: dest, title: title}, end + 1 + length, true
+		}
+	}
+
+	// Full, collapsed and shortcut reference links
+	label := text
+	n = end + 1
+	if strings.HasPrefix(rest, "[") {
+		if close := strings.IndexByte(rest, ']'); close >= 0 {
+			if close > 1 {
+				label = rest[1:close]
+			}
+			n += close + 1
+		}
+	}
+
+	link, ok = m.references[strings.ToLower(strings.TrimSpace(label))]
+
+	return
+}
+
+// Parse the (dest "title") of an inline link at the start of s, returning its length
+func linkDestination(s string) (dest, title string, n int, ok bool) {
+	i := 1
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return
+		}
+
+		dest = s[i+1 : i+end]
+		i += end + 1
+	} else {
+		depth := 0
+		start := i
+		for ; i < len(s) && !isSpace(s[i]); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+
+			if s[i] == '(' {
+				depth++
+			} else if s[i] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+
+		if i > len(s) {
+			return
+		}
+
+		dest = s[start:i]
+	}
+
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		quote := s[i]
+		end := strings.IndexByte(s[i+1:], quote)
+		if end < 0 {
+			return
+		}
+
+		title = s[i+1 : i+1+end]
+		i += end + 2
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return
+	}
+
+	return dest, title, i + 1, true
+}
+
+// Get the URL of a link destination, links with a scheme not in markdownSchemes are dropped and relative links are resolved
+func (m *markdownRenderer) url(dest string) string {
+	dest = strings.TrimSpace(dest)
+	if scheme := mdScheme.FindString(dest); scheme != "" {
+		if !markdownSchemes[strings.ToLower(strings.TrimSuffix(scheme, ":"))] {
+			return ""
+		}
+
+		return dest
+	}
+
+	if strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "//") || m.resolve == nil {
+		return dest
+	}
+
+	return m.resolve(dest)
+}
+
+// Title attribute of a link, empty if it has no title
+func titleAttr(title string) string {
+	if title == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(` title="%s"`, html.EscapeString(title))
+}
+
+// Get the text of inline markdown without its formatting characters
+func plainText(s string) string {
+	return strings.NewReplacer("*", "", "_", "", "`", "", "~", "", "[", "", "]", "").Replace(s)
+}
+
+// Get the length of the run of c at s[i]
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+
+	return n
+}
+
+// Check if b is whitespace
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n'
+}
+
+// Check if b is a letter or digit
+func isWordChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
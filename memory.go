@@ -0,0 +1,246 @@
+package tela
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/deroproject/derohe/rpc"
+)
+
+// MemoryDaemon is an in-memory Daemon fed with smart contract code, string and uint64 keys and transactions,
+// it can be used with SetDaemon to run TELA without a DERO daemon
+type MemoryDaemon struct {
+	sync.RWMutex
+	contracts map[string]map[string]interface{} // String keys of each SCID, SC code is stored at "C"
+	uints     map[string]map[uint64]interface{} // Uint64 keys of each SCID
+	txs       map[string]memoryTX               // Transactions by TXID
+}
+
+// Transaction stored by the MemoryDaemon
+type memoryTX struct {
+	hex    string
+	height int64
+}
+
+// Create a new empty MemoryDaemon
+func NewMemoryDaemon() *MemoryDaemon {
+	return &MemoryDaemon{
+		contracts: make(map[string]map[string]interface{}),
+		uints:     make(map[string]map[uint64]interface{}),
+		txs:       make(map[string]memoryTX),
+	}
+}
+
+// Convert a key value to the string or uint64 type stored by the DVM
+func memoryValue(value interface{}) (stored interface{}, err error) {
+	switch v := value.(type) {
+	case string, uint64:
+		stored = v
+	case int:
+		if v < 0 {
+			err = fmt.Errorf("negative value %d", v)
+			return
+		}
+		stored = uint64(v)
+	default:
+		err = fmt.Errorf("unsupported value type %T", value)
+	}
+
+	return
+}
+
+// Add a smart contract with code and string keys to the MemoryDaemon, any existing SCID will be replaced.
+// Key values must be string or uint64, values are stored as is and encoded the same as a daemon when retrieved
+func (m *MemoryDaemon) AddSC(scid, code string, keys map[string]interface{}) (err error) {
+	if len(scid) != 64 {
+		err = fmt.Errorf("invalid SCID: %s", scid)
+		return
+	}
+
+	contract := map[string]interface{}{"C": code}
+	for k, v := range keys {
+		if contract[k], err = memoryValue(v); err != nil {
+			err = fmt.Errorf("invalid value for %q: %s", k, err)
+			return
+		}
+	}
+
+	m.Lock()
+	m.contracts[scid] = contract
+	m.uints[scid] = make(map[uint64]interface{})
+	m.Unlock()
+
+	return
+}
+
+// Store a string key value on an existing SCID, value must be string or uint64
+func (m *MemoryDaemon) StoreString(scid, key string, value interface{}) (err error) {
+	stored, err := memoryValue(value)
+	if err != nil {
+		err = fmt.Errorf("invalid value for %q: %s", key, err)
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	contract, ok := m.contracts[scid]
+	if !ok {
+		err = fmt.Errorf("scid %s not found", scid)
+		return
+	}
+
+	contract[key] = stored
+
+	return
+}
+
+// Store a uint64 key value on an existing SCID, value must be string or uint64
+func (m *MemoryDaemon) StoreUint64(scid string, key uint64, value interface{}) (err error) {
+	stored, err := memoryValue(value)
+	if err != nil {
+		err = fmt.Errorf("invalid value for %d: %s", key, err)
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	uints, ok := m.uints[scid]
+	if !ok {
+		err = fmt.Errorf("scid %s not found", scid)
+		return
+	}
+
+	uints[key] = stored
+
+	return
+}
+
+// Delete a string key from an existing SCID
+func (m *MemoryDaemon) DeleteString(scid, key string) {
+	m.Lock()
+	if contract, ok := m.contracts[scid]; ok {
+		delete(contract, key)
+	}
+	m.Unlock()
+}
+
+// Add a transaction as hex mined at block height to the MemoryDaemon
+func (m *MemoryDaemon) AddTransaction(txid, txAsHex string, height int64) {
+	m.Lock()
+	m.txs[txid] = memoryTX{hex: txAsHex, height: height}
+	m.Unlock()
+}
+
+// Get smart contract data, string values are hex encoded and the result is returned as a daemon would send it over RPC
+func (m *MemoryDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	// As with a daemon, a SCID that is not found returns an empty result
+	contract := m.contracts[params.SCID]
+	uints := m.uints[params.SCID]
+
+	sent := rpc.GetSC_Result{
+		VariableStringKeys: map[string]interface{}{},
+		VariableUint64Keys: map[uint64]interface{}{},
+		Balances:           map[string]uint64{},
+		Status:             "OK",
+	}
+
+	if params.Code {
+		sent.Code, _ = contract["C"].(string)
+	}
+
+	encode := func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			return fmt.Sprintf("%x", []byte(s))
+		}
+
+		return value
+	}
+
+	if params.Variables {
+		for k, v := range contract {
+			sent.VariableStringKeys[k] = encode(v)
+		}
+
+		for k, v := range uints {
+			sent.VariableUint64Keys[k] = encode(v)
+		}
+	}
+
+	for _, k := range params.KeysUint64 {
+		v, ok := uints[k]
+		if !ok {
+			sent.ValuesUint64 = append(sent.ValuesUint64, "NOT AVAILABLE err: leaf not found")
+			continue
+		}
+
+		sent.ValuesUint64 = append(sent.ValuesUint64, fmt.Sprintf("%v", encode(v)))
+	}
+
+	for _, k := range params.KeysString {
+		v, ok := contract[k]
+		if !ok {
+			sent.ValuesString = append(sent.ValuesString, "NOT AVAILABLE err: leaf not found")
+			continue
+		}
+
+		sent.ValuesString = append(sent.ValuesString, fmt.Sprintf("%v", encode(v)))
+	}
+
+	// Values are decoded as they would be from a daemon, numbers become float64
+	b, err := json.Marshal(sent)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(b, &result)
+
+	return
+}
+
+// Get transactions as hex by TXID
+func (m *MemoryDaemon) GetTransaction(ctx context.Context, endpoint string, params rpc.GetTransaction_Params) (result rpc.GetTransaction_Result, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, txid := range params.Tx_Hashes {
+		tx, ok := m.txs[txid]
+		if !ok {
+			err = fmt.Errorf("TXID %s not found", txid)
+			return
+		}
+
+		result.Txs_as_hex = append(result.Txs_as_hex, tx.hex)
+		result.Txs = append(result.Txs, rpc.Tx_Related_Info{Tx_hash: txid, Block_Height: tx.height})
+	}
+
+	result.Status = "OK"
+
+	return
+}
+
+// Get a gas estimate, the MemoryDaemon will always estimate the MINIMUM_GAS_FEE
+func (m *MemoryDaemon) GetGasEstimate(ctx context.Context, endpoint string, params rpc.GasEstimate_Params) (result rpc.GasEstimate_Result, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	result.GasStorage = MINIMUM_GAS_FEE
+	result.Status = "OK"
+
+	return
+}
@@ -1,12 +1,18 @@
 package tela
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math/big"
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/deroproject/derohe/dvm"
 	"github.com/deroproject/derohe/rpc"
@@ -19,19 +25,25 @@ const (
 	MAX_DOC_CODE_SIZE      = float64(18)    // DOC SC template file size is +1.2KB with headers
 	MAX_DOC_INSTALL_SIZE   = float64(19.2)  // DOC SC total file size (including docCode) should be below this
 	MAX_INDEX_INSTALL_SIZE = float64(11.64) // INDEX SC file size should be below this
+	MAX_DOC_DECODED_SIZE   = float64(1024)  // Encoded docCode size when decoded should be below this
 )
 
-// Append docCode to TELA-DOC-1 smart contract
-func appendDocCode(code, docCode string) (newCode string, err error) {
+// Append docCode to TELA-DOC-1 smart contract, raw is the docCode before it was encoded
+func appendDocCode(code, docCode, raw string) (newCode string, err error) {
 	docSize := GetCodeSizeInKB(docCode)
+	sizes := fmt.Sprintf("%.5f", docSize)
+	if docCode != raw {
+		sizes = fmt.Sprintf("encoded %.5f, raw %.5f", docSize, GetCodeSizeInKB(raw))
+	}
+
 	if docSize > MAX_DOC_CODE_SIZE {
-		err = fmt.Errorf("docCode size is to large, max %.2fKB (%.5f)", MAX_DOC_CODE_SIZE, docSize)
+		err = fmt.Errorf("docCode size is to large, max %.2fKB (%s)", MAX_DOC_CODE_SIZE, sizes)
 		return
 	}
 
 	scSize := GetCodeSizeInKB(code)
 	if scSize+docSize > MAX_DOC_INSTALL_SIZE {
-		err = fmt.Errorf("DOC SC size is to large, max %.2fKB (%.5f)", MAX_DOC_INSTALL_SIZE, scSize+docSize)
+		err = fmt.Errorf("DOC SC size is to large, max %.2fKB (%.5f, docCode %s)", MAX_DOC_INSTALL_SIZE, scSize+docSize, sizes)
 		return
 	}
 
@@ -40,6 +52,183 @@ func appendDocCode(code, docCode string) (newCode string, err error) {
 	return
 }
 
+// IsBinaryCode checks if DOC code is binary content such as an image, font or wasm file which must be encoded to be installed
+func IsBinaryCode(code string) bool {
+	return !utf8.ValidString(code) || strings.ContainsRune(code, 0)
+}
+
+// ContainsCommentEnd checks if DOC code contains a */ comment terminator which must be escaped to be installed
+func ContainsCommentEnd(code string) bool {
+	return strings.Contains(code, "*/")
+}
+
+// Escape the comment terminators of docCode so it can be stored in a comment block. A backslash is added after the * of
+// any * followed by zero or more backslashes and a /, which is reversed by unescapeDocCode
+func escapeDocCode(docCode string) string {
+	var sb strings.Builder
+	for i := 0; i < len(docCode); i++ {
+		sb.WriteByte(docCode[i])
+		if escapesAt(docCode, i) {
+			sb.WriteByte('\\')
+		}
+	}
+
+	return sb.String()
+}
+
+// Check if escapeDocCode adds a backslash after the byte at i of docCode
+func escapesAt(docCode string, i int) bool {
+	if docCode[i] != '*' {
+		return false
+	}
+
+	j := i + 1
+	for j < len(docCode) && docCode[j] == '\\' {
+		j++
+	}
+
+	return j < len(docCode) && docCode[j] == '/'
+}
+
+// Reverse escapeDocCode, removing the backslash after the * of any * followed by one or more backslashes and a /
+func unescapeDocCode(docCode string) string {
+	var sb strings.Builder
+	for i := 0; i < len(docCode); i++ {
+		sb.WriteByte(docCode[i])
+		if docCode[i] != '*' {
+			continue
+		}
+
+		j := i + 1
+		for j < len(docCode) && docCode[j] == '\\' {
+			j++
+		}
+
+		if j > i+1 && j < len(docCode) && docCode[j] == '/' {
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+// Encode docCode for install with encoding, an empty encoding uses DOC_ENCODING_GZIP if it is smaller than docCode.
+// Binary docCode cannot be installed as it is, an empty encoding uses the smaller of DOC_ENCODING_BASE64 and DOC_ENCODING_GZIP.
+// docCode containing comment terminators cannot be installed as it is, an empty encoding uses DOC_ENCODING_ESCAPED if it is not compressed
+func encodeDocCode(docCode, encoding string) (encoded, used string, err error) {
+	binary := IsBinaryCode(docCode)
+	switch encoding {
+	case DOC_ENCODING_NONE:
+		if binary {
+			err = fmt.Errorf("binary docCode must be encoded")
+			return
+		}
+
+		if ContainsCommentEnd(docCode) {
+			err = fmt.Errorf("docCode contains */ and must be encoded")
+			return
+		}
+
+		encoded = docCode
+	case DOC_ENCODING_ESCAPED:
+		if binary {
+			err = fmt.Errorf("binary docCode cannot be %s", DOC_ENCODING_ESCAPED)
+			return
+		}
+
+		encoded, used = escapeDocCode(docCode), DOC_ENCODING_ESCAPED
+	case DOC_ENCODING_BASE64:
+		encoded, used = base64.StdEncoding.EncodeToString([]byte(docCode)), DOC_ENCODING_BASE64
+	case DOC_ENCODING_GZIP, "":
+		var buf bytes.Buffer
+		gz, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if _, err = gz.Write([]byte(docCode)); err != nil {
+			return
+		}
+
+		if err = gz.Close(); err != nil {
+			return
+		}
+
+		encoded, used = base64.StdEncoding.EncodeToString(buf.Bytes()), DOC_ENCODING_GZIP
+		if encoding == "" {
+			smallest, fallback := docCode, ""
+			switch {
+			case binary:
+				smallest, fallback = base64.StdEncoding.EncodeToString([]byte(docCode)), DOC_ENCODING_BASE64
+			case ContainsCommentEnd(docCode):
+				smallest, fallback = escapeDocCode(docCode), DOC_ENCODING_ESCAPED
+			}
+
+			if GetCodeSizeInKB(encoded) >= GetCodeSizeInKB(smallest) {
+				encoded, used = smallest, fallback
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown docEncoding %q", encoding)
+	}
+
+	return
+}
+
+// Check if docCode installed with encoding is stored as text in its comment block
+func rawEncoding(encoding string) bool {
+	return encoding == "" || encoding == DOC_ENCODING_NONE || encoding == DOC_ENCODING_ESCAPED
+}
+
+// Decode docCode installed with encoding, decoded docCode cannot exceed MAX_DOC_DECODED_SIZE.
+// Encoded docCode is decoded to the exact content it was installed with
+func decodeDocCode(docCode, encoding string) (decoded string, err error) {
+	switch encoding {
+	case "", DOC_ENCODING_NONE:
+		decoded = docCode
+	case DOC_ENCODING_ESCAPED:
+		decoded = unescapeDocCode(docCode)
+	case DOC_ENCODING_BASE64:
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(strings.TrimSpace(docCode))
+		if err != nil {
+			err = fmt.Errorf("could not decode docCode: %s", err)
+			return
+		}
+
+		decoded = string(b)
+	case DOC_ENCODING_GZIP:
+		var compressed []byte
+		compressed, err = base64.StdEncoding.DecodeString(strings.TrimSpace(docCode))
+		if err != nil {
+			err = fmt.Errorf("could not decode docCode: %s", err)
+			return
+		}
+
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			err = fmt.Errorf("could not decompress docCode: %s", err)
+			return
+		}
+
+		limit := int64(MAX_DOC_DECODED_SIZE * 1024)
+		var b []byte
+		b, err = io.ReadAll(io.LimitReader(gz, limit+1))
+		if err != nil {
+			err = fmt.Errorf("could not decompress docCode: %s", err)
+			return
+		}
+
+		if int64(len(b)) > limit {
+			err = fmt.Errorf("decoded docCode exceeds %.2fKB", MAX_DOC_DECODED_SIZE)
+			return
+		}
+
+		decoded = string(b)
+	default:
+		err = fmt.Errorf("unknown docEncoding %q", encoding)
+	}
+
+	return
+}
+
 // Check if Header key requires a value STORE that is not empty
 func requiredHeader(value string, key Header) bool {
 	return value == `""` && (key == HEADER_NAME || key == HEADER_CHECK_C || key == HEADER_CHECK_S)
@@ -115,6 +304,47 @@ func ParseDocType(fileName string) (language string) {
 	return
 }
 
+// Validate a single path element stored on-chain such as a nameHdr or dURL, it must not be able to leave the directory it is joined to
+func validatePathName(name string) (err error) {
+	switch {
+	case name == "":
+		err = fmt.Errorf("name is empty")
+	case name == "." || name == "..":
+		err = fmt.Errorf("%q is a relative path", name)
+	case strings.ContainsAny(name, `/\:`):
+		err = fmt.Errorf("%q contains a path separator", name)
+	case strings.IndexFunc(name, unicode.IsControl) != -1:
+		err = fmt.Errorf("%q contains a control character", name)
+	}
+
+	return
+}
+
+// Validate and normalise a subDir stored on-chain, empty elements are removed and clean is a relative path using / separators
+func cleanSubDir(subDir string) (clean string, err error) {
+	if strings.HasPrefix(subDir, "/") {
+		err = fmt.Errorf("%q is an absolute path", subDir)
+		return
+	}
+
+	var elems []string
+	for _, elem := range strings.Split(subDir, "/") {
+		if elem == "" {
+			continue
+		}
+
+		if err = validatePathName(elem); err != nil {
+			return
+		}
+
+		elems = append(elems, elem)
+	}
+
+	clean = strings.Join(elems, "/")
+
+	return
+}
+
 // Parse a INDEX contract for its DOC SCIDs
 func ParseINDEXForDOCs(code string) (scids []string, err error) {
 	sc, err := EqualSmartContracts(TELA_INDEX_1, code)
@@ -151,62 +381,25 @@ func ParseINDEXForDOCs(code string) (scids []string, err error) {
 	return
 }
 
-func parseAndCloneINDEXForDOCs(sc dvm.SmartContract, basePath, endpoint string) (entrypoint, servePath string, err error) {
-	// Parse INDEX SC for valid DOCs
+// Parse a INDEX SC for its DOC# keys and DOC SCIDs in order of the INDEX
+func parseINDEXForDOCNums(sc dvm.SmartContract) (docNums, scids []string, err error) {
 	for name, function := range sc.Functions {
-		// Find initialize function and parse lines
+		// Find initialize function and parse lines in order
 		if name == DVM_FUNC_INIT_PRIVATE {
-			for _, line := range function.Lines {
+			for _, num := range function.LineNumbers {
+				line := function.Lines[num]
 				// Parse the contents of the line
 				for i, parts := range line {
-					if strings.Contains(parts, string(HEADER_DOCUMENT)) {
-						// Line STORE is a DOC#, find scid and get its document data
+					if strings.Contains(parts, string(HEADER_DOCUMENT)) && i+2 < len(line) {
+						// Line STORE is a DOC#, find scid
 						scid := strings.Trim(line[i+2], `"`)
-						isDOC1 := Header(parts) == HEADER_DOCUMENT.Number(1)
-
-						// Check if scid is INDEX or DOC and handle accordingly
-						var c Cloning
-						var telaVersion string
-						telaVersion, err = getContractVar(scid, "telaVersion", endpoint)
-						if err != nil {
-							c, err = cloneDOC(scid, parts, basePath, endpoint)
-							if err != nil {
-								return
-							}
-
-							// If DOC is entrypoint set it, and if serving from subDir point to it
-							if isDOC1 {
-								entrypoint = c.Entrypoint
-								servePath = c.ServePath
-							}
-						} else {
-							if telaVersion != TELA_VERSION {
-								err = fmt.Errorf("cannot use TELA-INDEX v%s when package is v%s", telaVersion, TELA_VERSION)
-								return
-							}
-
-							if isDOC1 {
-								err = fmt.Errorf("cannot use TELA-INDEX as entrypoint for TELA-INDEX")
-								return
-							}
-
-							var libCheck string
-							libCheck, err = getContractVar(scid, "dURL", endpoint)
-							if err != nil {
-								err = fmt.Errorf("could not verify TELA-INDEX dURL for library embed: %s", err)
-								return
-							}
-
-							if !strings.HasSuffix(libCheck, TAG_LIBRARY) {
-								err = fmt.Errorf("cannot embed TELA-INDEX without %q tag", TAG_LIBRARY)
-								return
-							}
-
-							c, err = cloneINDEX(scid, basePath, endpoint)
-							if err != nil {
-								return
-							}
+						if len(scid) != 64 {
+							err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid DOC SCID"))
+							return
 						}
+
+						docNums = append(docNums, parts)
+						scids = append(scids, scid)
 					}
 				}
 			}
@@ -330,6 +523,16 @@ func ParseHeaders(code string, headerType interface{}) (formatted string, err er
 			HEADER_CHECK_C:     formatValue(h.CheckC),
 			HEADER_CHECK_S:     formatValue(h.CheckS),
 		}
+
+		// Chunked files store their part number and total parts
+		if h.Parts > 0 {
+			headers[HEADER_PART] = formatValue(h.Part)
+			headers[HEADER_PARTS] = formatValue(h.Parts)
+		}
+
+		if h.Encoding != "" && h.Encoding != DOC_ENCODING_NONE {
+			headers[HEADER_ENCODING] = formatValue(h.Encoding)
+		}
 	case *Headers:
 		headers = map[Header]string{
 			HEADER_NAME:        formatValue(h.NameHdr),
@@ -0,0 +1,334 @@
+package tela
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/walletapi"
+)
+
+// File chunked across multiple DOCs, joined from its parts
+type docParts struct {
+	file          docFile  // First part of the file
+	content       string   // Joined content of all parts
+	scids         []string // SCIDs of the parts in order
+	verifications []DOCVerification
+}
+
+// SplitDOC splits a DOC with code too large to install into parts sharing its headers, each part carries its part number and the total parts.
+// A DOC which can be installed is returned as its only part. Parts are not signed, the code of each part must be signed before it is installed
+func SplitDOC(doc DOC) (parts []DOC, err error) {
+	if doc.Parts > 0 {
+		err = fmt.Errorf("DOC is already part %d of %d", doc.Part, doc.Parts)
+		return
+	}
+
+	// DOC can be installed as it is, or once encoded
+	signed := doc
+	signed.Signature = sizingSignature()
+	if _, errr := NewInstallArgs(&signed); errr == nil {
+		parts = []DOC{doc}
+		return
+	}
+
+	// Use the largest part headers a part could have, parts are split by their size before they are encoded
+	doc.Part, doc.Parts = math.MaxUint32, math.MaxUint32
+	size, err := docHeadersSize(doc)
+	if err != nil {
+		return
+	}
+
+	limit := math.Min(MAX_DOC_CODE_SIZE, MAX_DOC_INSTALL_SIZE-size)
+	if limit < 1 {
+		err = fmt.Errorf("DOC headers are to large to split code, %.2fKB left for code", limit)
+		return
+	}
+
+	// Binary parts are each base64 encoded as a chunk of a binary file could be valid text
+	chunkSize := int(limit * 1024)
+	if IsBinaryCode(doc.Code) && doc.Encoding == "" {
+		doc.Encoding = DOC_ENCODING_BASE64
+	}
+
+	// Leave room for encoding, gzip adds its header to content that does not compress.
+	// Raw parts may be escaped so each chunk is sized with its own escaping
+	escaped := false
+	switch doc.Encoding {
+	case DOC_ENCODING_BASE64, DOC_ENCODING_GZIP:
+		chunkSize = chunkSize*3/4 - 64
+	default:
+		escaped = true
+	}
+
+	chunks, err := splitCode(doc.Code, chunkSize, escaped)
+	if err != nil {
+		return
+	}
+
+	for i, chunk := range chunks {
+		part := doc
+		part.Code = chunk
+		part.Part = uint64(i + 1)
+		part.Parts = uint64(len(chunks))
+		part.Signature = Signature{}
+		parts = append(parts, part)
+	}
+
+	return
+}
+
+// Signature with the largest values a DOC could have, used to size a DOC before it is signed
+func sizingSignature() Signature {
+	return Signature{CheckC: strings.Repeat("f", 64), CheckS: strings.Repeat("f", 64)}
+}
+
+// Get the size of a DOC SC without its code, using the largest signature the DOC could have
+func docHeadersSize(doc DOC) (size float64, err error) {
+	doc.Code = ""
+	doc.Signature = sizingSignature()
+	args, err := NewInstallArgs(&doc)
+	if err != nil {
+		return
+	}
+
+	code, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+	size = GetCodeSizeInKB(code)
+
+	return
+}
+
+// Split code into chunks of at most max size as counted by GetCodeSizeInKB, characters are never split. If escaped, the size of
+// each chunk includes the backslashes escapeDocCode would add to it. Chunks do not start or end with whitespace where possible
+// as whitespace may be trimmed from installed docCode
+func splitCode(code string, max int, escaped bool) (chunks []string, err error) {
+	if max < 1 {
+		err = fmt.Errorf("invalid chunk size %d", max)
+		return
+	}
+
+	for code != "" {
+		end, size := 0, 0
+		for end < len(code) {
+			r, n := utf8.DecodeRuneInString(code[end:])
+			cost := n
+			if r == '\n' {
+				cost++
+			}
+
+			// Counted against the whole code, a terminator split between chunks is overestimated
+			if escaped && escapesAt(code, end) {
+				cost++
+			}
+
+			if size+cost > max {
+				break
+			}
+
+			size += cost
+			end += n
+		}
+
+		if end < len(code) {
+			for cut := end; cut > end/2; {
+				before, n := utf8.DecodeLastRuneInString(code[:cut])
+				after, _ := utf8.DecodeRuneInString(code[cut:])
+				if !unicode.IsSpace(before) && !unicode.IsSpace(after) {
+					end = cut
+					break
+				}
+
+				cut -= n
+			}
+		}
+
+		if end == 0 {
+			err = fmt.Errorf("chunk size %d is to small to split code", max)
+			return
+		}
+
+		chunks = append(chunks, code[:end])
+		code = code[end:]
+	}
+
+	return
+}
+
+// Parse, verify and join the parts of a chunked file from their contract states. Every part must be found once and all parts must share
+// the nameHdr, subDir, docType and total parts of the file. Each part's signature is verified as required by the signature policy
+func (t *TELA) joinDOCParts(states []contractState) (parts docParts, err error) {
+	files := make([]docFile, len(states))
+	for i, state := range states {
+		files[i], err = docFromState(state)
+		if err != nil {
+			return
+		}
+
+		if files[i].parts == 0 {
+			err = newError(ErrParts, files[i].scid, files[i].dURL, "", fmt.Errorf("%s is not a part of a chunked file", files[i].name))
+			return
+		}
+
+		var result *DOCVerification
+		result, err = t.verifyDOCFile(state, files[i])
+		if err != nil {
+			return
+		}
+
+		if result != nil {
+			parts.verifications = append(parts.verifications, *result)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].part < files[j].part
+	})
+
+	parts.file = files[0]
+	var content strings.Builder
+	for i, f := range files {
+		switch {
+		case f.parts != parts.file.parts || f.docType != parts.file.docType || f.name != parts.file.name || f.subDir != parts.file.subDir:
+			err = newError(ErrParts, f.scid, f.dURL, "", fmt.Errorf("part %d of %s does not match part %d", f.part, f.name, parts.file.part))
+		case f.part < uint64(i+1):
+			err = newError(ErrParts, f.scid, f.dURL, "", fmt.Errorf("part %d of %s is duplicated", f.part, f.name))
+		case f.part > uint64(i+1):
+			err = newError(ErrParts, parts.file.scid, parts.file.dURL, "", fmt.Errorf("part %d of %s is missing", i+1, f.name))
+		}
+
+		if err != nil {
+			return
+		}
+
+		var docCode string
+		docCode, err = parseDocCode(f.code)
+		if err == nil {
+			docCode, err = decodeDocCode(docCode, f.encoding)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("error parsing part %d of %s: %s", f.part, f.name, err)
+			return
+		}
+
+		content.WriteString(docCode)
+		parts.scids = append(parts.scids, f.scid)
+	}
+
+	if uint64(len(files)) != parts.file.parts {
+		err = newError(ErrParts, parts.file.scid, parts.file.dURL, "", fmt.Errorf("part %d of %s is missing", len(files)+1, parts.file.name))
+		return
+	}
+
+	// Only content that is not encoded may have whitespace trimmed at the start or end of the file
+	parts.content = content.String()
+	if rawEncoding(files[0].encoding) {
+		parts.content = strings.TrimLeftFunc(parts.content, unicode.IsSpace)
+	}
+
+	if rawEncoding(files[len(files)-1].encoding) {
+		parts.content = strings.TrimRightFunc(parts.content, unicode.IsSpace)
+	}
+
+	return
+}
+
+// Get the ID of a chunked file's content, it changes if any of its parts change
+func (p docParts) id() string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(p.scids, ""))))
+}
+
+// Clone a file chunked across the DOCs of entries to path, the file is only written once all of its parts are joined and verified
+func (t *TELA) cloneDOCParts(entries []dependencyEntry, path string) (clone Cloning, err error) {
+	var isDOC1 bool
+	states := make([]contractState, len(entries))
+	for i, entry := range entries {
+		states[i] = entry.state
+		if Header(entry.docNum) == HEADER_DOCUMENT.Number(1) {
+			isDOC1 = true
+		}
+	}
+
+	parts, err := t.joinDOCParts(states)
+	if err != nil {
+		return
+	}
+
+	clone, err = saveDOCFile(parts.file, parts.content, isDOC1, path)
+	if err != nil {
+		return
+	}
+
+	clone.Verifications = parts.verifications
+	clone.files[filepath.ToSlash(filepath.Join(parts.file.subDir, parts.file.name))] = servedDOC{scid: parts.id(), docType: parts.file.docType}
+
+	return
+}
+
+// Get the path of the chunked file a DOC is a part of from its contract state, false if the DOC is not a part
+func docPartPath(state contractState) (name string, ok bool) {
+	if _, err := state.value(HEADER_PART.Trim()); err != nil {
+		return
+	}
+
+	subDir, _ := state.value(HEADER_SUBDIR.Trim())
+	subDir, _ = cleanSubDir(subDir)
+	nameHdr, _ := state.value(HEADER_NAME.Trim())
+
+	return subDir + "/" + nameHdr, true
+}
+
+// InstallDOC installs a TELA-DOC with DERO walletapi, a DOC too large to install is split into parts with SplitDOC and each part
+// is signed by wallet and installed. TXIDs are returned in part order and any parts installed before an error are returned with it
+func (t *TELA) InstallDOC(wallet *walletapi.Wallet_Disk, ringsize uint64, doc *DOC) (txids []string, err error) {
+	return t.InstallDOCContext(context.Background(), wallet, ringsize, doc)
+}
+
+// InstallDOCContext is InstallDOC using ctx for each part's install
+func (t *TELA) InstallDOCContext(ctx context.Context, wallet *walletapi.Wallet_Disk, ringsize uint64, doc *DOC) (txids []string, err error) {
+	if wallet == nil {
+		err = fmt.Errorf("no wallet for TELA Installer")
+		return
+	}
+
+	if doc == nil {
+		err = fmt.Errorf("no DOC to install")
+		return
+	}
+
+	parts, err := SplitDOC(*doc)
+	if err != nil {
+		return
+	}
+
+	for i := range parts {
+		// A DOC that is not split is installed as it is
+		if len(parts) > 1 {
+			_, parts[i].CheckC, parts[i].CheckS, err = ParseSignature(wallet.SignData([]byte(parts[i].Code)))
+			if err != nil {
+				err = fmt.Errorf("could not sign part %d of %s: %s", parts[i].Part, doc.NameHdr, err)
+				return
+			}
+		}
+
+		var txid string
+		txid, err = t.InstallerContext(ctx, wallet, ringsize, &parts[i])
+		if err != nil {
+			if len(parts) > 1 {
+				err = fmt.Errorf("part %d of %d: %s", parts[i].Part, parts[i].Parts, err)
+			}
+			return
+		}
+
+		txids = append(txids, txid)
+	}
+
+	return
+}
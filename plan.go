@@ -0,0 +1,341 @@
+package tela
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/civilware/tela/logger"
+	"github.com/deroproject/derohe/rpc"
+)
+
+// Config for planning the install of a directory as a TELA app
+type PlanConfig struct {
+	DURL       string  // dURL of the DOCs and INDEX
+	Entrypoint string  // Path of the file to install as DOC1, defaults to index.html, the first HTML file found or the first file
+	Headers    Headers // Headers of the INDEX, NameHdr defaults to the directory name
+	Ringsize   uint64  // Ringsize used for gas estimates, defaults to 2
+	Signer     string  // Address the contracts will be installed from, used for gas estimates with a ringsize of 2
+}
+
+// Install plan for the files of a directory, created with PlanInstall to be reviewed before anything is installed
+type InstallPlan struct {
+	Path        string            `json:"path"`                  // Directory the plan was created from
+	DURL        string            `json:"dURL"`                  // dURL of the DOCs and INDEX
+	Entrypoint  string            `json:"entrypoint"`            // File installed as DOC1
+	DOCs        []PlannedDOC      `json:"docs"`                  // DOC contracts in install order, a chunked file has a DOC for each part
+	INDEX       PlannedINDEX      `json:"index"`                 // INDEX embedding all DOCs
+	Unsupported []UnsupportedFile `json:"unsupported,omitempty"` // Files that will not be installed
+	Size        float64           `json:"size"`                  // Total size of all contracts in KB
+	Gas         uint64            `json:"gas"`                   // Total estimated gas of all contracts, 0 if gas was not estimated
+}
+
+// DOC contract of an InstallPlan
+type PlannedDOC struct {
+	File     string  `json:"file"`               // Path of the file relative to the plan directory
+	DocType  string  `json:"docType"`            // docType from ParseDocType
+	SubDir   string  `json:"subDir,omitempty"`   // subDir of the file
+	NameHdr  string  `json:"nameHdr"`            // File name
+	Part     uint64  `json:"part,omitempty"`     // Part number if the file is chunked across multiple DOCs
+	Parts    uint64  `json:"parts,omitempty"`    // Total parts if the file is chunked across multiple DOCs
+	Encoding string  `json:"encoding,omitempty"` // docEncoding the code will be installed with
+	Size     float64 `json:"size"`               // Size of the DOC SC in KB
+	Gas      uint64  `json:"gas"`                // Estimated gas to install the DOC, 0 if gas was not estimated
+}
+
+// INDEX contract of an InstallPlan
+type PlannedINDEX struct {
+	NameHdr string  `json:"nameHdr"` // Name of the INDEX
+	DOCs    int     `json:"docs"`    // Amount of DOCs embedded in the INDEX
+	Size    float64 `json:"size"`    // Size of the INDEX SC in KB
+	Gas     uint64  `json:"gas"`     // Estimated gas to install the INDEX, 0 if gas was not estimated
+}
+
+// File of a directory that is not part of an InstallPlan
+type UnsupportedFile struct {
+	File   string `json:"file"`   // Path of the file relative to the plan directory
+	Reason string `json:"reason"` // Why the file will not be installed
+}
+
+// Planned DOCs of a single file
+type plannedFile struct {
+	file string
+	docs []PlannedDOC
+}
+
+// JSON returns the InstallPlan as indented JSON for review
+func (p InstallPlan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// PlanInstall creates an InstallPlan for the files of dir. Each file is mapped to a docType and subDir and checked against the
+// install size limits, chunking it into parts if required. Gas is estimated for each contract from the daemon at endpoint, an
+// empty endpoint will not estimate gas. Files which cannot be installed are listed as unsupported, nothing is installed
+func (t *TELA) PlanInstall(dir, endpoint string, config PlanConfig) (plan InstallPlan, err error) {
+	return t.PlanInstallContext(context.Background(), dir, endpoint, config)
+}
+
+// PlanInstallContext is PlanInstall using ctx for the gas estimates
+func (t *TELA) PlanInstallContext(ctx context.Context, dir, endpoint string, config PlanConfig) (plan InstallPlan, err error) {
+	if err = validatePathName(config.DURL); err != nil {
+		err = fmt.Errorf("invalid dURL: %s", err)
+		return
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", dir)
+		return
+	}
+
+	plan.Path = dir
+	plan.DURL = config.DURL
+
+	ringsize := clampRingsize(config.Ringsize)
+	estimate := func(args rpc.Arguments) (gas uint64, err error) {
+		if endpoint == "" {
+			return
+		}
+
+		return t.estimateGas(ctx, endpoint, config.Signer, ringsize, scTransfers(), args)
+	}
+
+	var files []plannedFile
+	err = filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if filePath == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		unsupported := func(reason string) {
+			plan.Unsupported = append(plan.Unsupported, UnsupportedFile{File: rel, Reason: reason})
+		}
+
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				unsupported("hidden directory")
+				return filepath.SkipDir
+			}
+
+			unsupported("hidden file")
+			return nil
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			unsupported("not a regular file")
+			return nil
+		}
+
+		planned, reason, err := planFile(dir, rel, config.DURL, estimate)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			unsupported(reason)
+			return nil
+		}
+
+		files = append(files, planned)
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if len(files) < 1 {
+		err = fmt.Errorf("no files to install in %s", dir)
+		return
+	}
+
+	entry, err := planEntrypoint(files, config.Entrypoint)
+	if err != nil {
+		return
+	}
+
+	// DOC1 is the entrypoint followed by all other files in path order
+	plan.Entrypoint = files[entry].file
+	files = append(append([]plannedFile{files[entry]}, files[:entry]...), files[entry+1:]...)
+	for _, f := range files {
+		for _, doc := range f.docs {
+			plan.DOCs = append(plan.DOCs, doc)
+			plan.Size += doc.Size
+			plan.Gas += doc.Gas
+		}
+	}
+
+	// INDEX is sized with placeholder SCIDs for all of its DOCs
+	index := INDEX{DURL: config.DURL, Headers: config.Headers}
+	if index.NameHdr == "" {
+		index.NameHdr = filepath.Base(filepath.Clean(dir))
+	}
+
+	for range plan.DOCs {
+		index.DOCs = append(index.DOCs, strings.Repeat("f", 64))
+	}
+
+	plan.INDEX = PlannedINDEX{NameHdr: index.NameHdr, DOCs: len(index.DOCs)}
+
+	args, err := NewInstallArgs(&index)
+	if err != nil {
+		err = fmt.Errorf("INDEX embedding %d DOCs cannot be installed: %s", len(index.DOCs), err)
+		return
+	}
+
+	code, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+	plan.INDEX.Size = GetCodeSizeInKB(code)
+	plan.INDEX.Gas, err = estimate(args)
+	if err != nil {
+		return
+	}
+
+	plan.Size += plan.INDEX.Size
+	plan.Gas += plan.INDEX.Gas
+
+	logger.Printf("[TELA] Planned %d DOCs for %s with %d unsupported files\n", len(plan.DOCs), config.DURL, len(plan.Unsupported))
+
+	return
+}
+
+// Plan the DOCs of the file at rel in dir, reason is returned if the file cannot be read or installed.
+// Only gas estimate errors are returned as err
+func planFile(dir, rel, dURL string, estimate func(rpc.Arguments) (uint64, error)) (planned plannedFile, reason string, err error) {
+	planned.file = rel
+	name := path.Base(rel)
+	subDir := path.Dir(rel)
+	if subDir == "." {
+		subDir = ""
+	}
+
+	if nameErr := validatePathName(name); nameErr != nil {
+		reason = fmt.Sprintf("invalid nameHdr: %s", nameErr)
+		return
+	}
+
+	if _, subDirErr := cleanSubDir(subDir); subDirErr != nil {
+		reason = fmt.Sprintf("invalid subDir: %s", subDirErr)
+		return
+	}
+
+	docType := ParseDocType(name)
+	if !IsAcceptedLanguage(docType) {
+		reason = "no docType for file"
+		return
+	}
+
+	data, readErr := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	if readErr != nil {
+		reason = fmt.Sprintf("file cannot be read: %s", readErr)
+		return
+	}
+
+	code := string(data)
+	if strings.TrimSpace(code) == "" {
+		reason = "file is empty"
+		return
+	}
+
+	if docType != DOC_STATIC && IsBinaryCode(code) {
+		reason = fmt.Sprintf("binary file is not %s", DOC_STATIC)
+		return
+	}
+
+	parts, splitErr := SplitDOC(DOC{DocType: docType, Code: code, SubDir: subDir, DURL: dURL, Headers: Headers{NameHdr: name}})
+	if splitErr != nil {
+		reason = splitErr.Error()
+		return
+	}
+
+	for _, part := range parts {
+		part.Signature = sizingSignature()
+		args, argsErr := NewInstallArgs(&part)
+		if argsErr != nil {
+			reason = argsErr.Error()
+			return
+		}
+
+		sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+		doc := PlannedDOC{
+			File:    rel,
+			DocType: docType,
+			SubDir:  subDir,
+			NameHdr: name,
+			Part:    part.Part,
+			Parts:   part.Parts,
+			Size:    GetCodeSizeInKB(sc),
+		}
+
+		if doc.Size > MAX_DOC_INSTALL_SIZE {
+			reason = fmt.Sprintf("DOC SC size is to large, max %.2fKB (%.5f)", MAX_DOC_INSTALL_SIZE, doc.Size)
+			return
+		}
+
+		_, doc.Encoding, _ = encodeDocCode(part.Code, part.Encoding)
+
+		doc.Gas, err = estimate(args)
+		if err != nil {
+			return
+		}
+
+		planned.docs = append(planned.docs, doc)
+	}
+
+	return
+}
+
+// Get the index of the entrypoint in files, entrypoint defaults to index.html or the first HTML file found at the top most level.
+// Files without any HTML such as libraries use their first file
+func planEntrypoint(files []plannedFile, entrypoint string) (entry int, err error) {
+	if entrypoint != "" {
+		entrypoint = path.Clean(filepath.ToSlash(entrypoint))
+		for i, f := range files {
+			if f.file == entrypoint {
+				return i, nil
+			}
+		}
+
+		err = fmt.Errorf("entrypoint %s is not a file to install", entrypoint)
+		return
+	}
+
+	entry, depth := -1, 0
+	for i, f := range files {
+		if f.file == "index.html" {
+			return i, nil
+		}
+
+		d := strings.Count(f.file, "/")
+		if f.docs[0].DocType == DOC_HTML && (entry < 0 || d < depth) {
+			entry, depth = i, d
+		}
+	}
+
+	if entry < 0 {
+		entry = 0
+	}
+
+	return
+}
@@ -0,0 +1,265 @@
+package tela
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Security headers sent with all served TELA content, zero values will use the TELA defaults.
+// The default Content-Security-Policy only allows content from the app itself, data: and blob: URLs, any other origin must be allowed.
+// Apps can connect to XSWD_ORIGINS and the daemon they were cloned from by default so wallet connected apps keep working
+type SecurityPolicy struct {
+	Disabled              bool                // Send no security headers
+	NoWalletOrigins       bool                // Do not allow apps to connect to XSWD_ORIGINS and their daemon by default
+	Allowlist             []string            // Origins all apps are allowed to use, such as "https://example.com" or "ws://127.0.0.1:44326"
+	Apps                  map[string][]string // Origins allowed for individual apps by their INDEX SCID, in addition to Allowlist
+	ContentSecurityPolicy string              // Replaces the generated Content-Security-Policy when set
+	ReferrerPolicy        string              // Defaults to DEFAULT_REFERRER_POLICY
+	PermissionsPolicy     string              // Defaults to DEFAULT_PERMISSIONS_POLICY
+}
+
+// Security policy of a TELA host, it has its own lock so content can be served while TELA is locked
+type securityPolicy struct {
+	sync.RWMutex
+	policy SecurityPolicy
+}
+
+// Default Referrer-Policy of served content, no URLs are leaked to any origin
+const DEFAULT_REFERRER_POLICY = "no-referrer"
+
+// Default Permissions-Policy of served content, device and tracking features are disabled
+const DEFAULT_PERMISSIONS_POLICY = "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=(), browsing-topics=()"
+
+// Origins of the default XSWD websocket server
+var XSWD_ORIGINS = []string{"ws://localhost:44326", "ws://127.0.0.1:44326"}
+
+// DaemonOrigins returns the origins to allow for apps connecting to a daemon at endpoint
+func DaemonOrigins(endpoint string) []string {
+	return []string{"http://" + endpoint, "ws://" + endpoint}
+}
+
+// Get the origins apps cloned from endpoint can connect to by default, endpoints that are not host:port have no daemon origins
+func walletOrigins(endpoint string) (origins []string) {
+	origins = append(origins, XSWD_ORIGINS...)
+	if _, _, err := net.SplitHostPort(endpoint); err == nil {
+		for _, origin := range DaemonOrigins(endpoint) {
+			if validOrigin(origin) == nil {
+				origins = append(origins, origin)
+			}
+		}
+	}
+
+	return
+}
+
+// Append the origins which are not already in sources
+func appendOrigins(sources []string, origins ...string) []string {
+	for _, origin := range origins {
+		found := false
+		for _, source := range sources {
+			if source == origin {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			sources = append(sources, origin)
+		}
+	}
+
+	return sources
+}
+
+// Validate a CSP source origin, only scheme://host[:port] is accepted
+func validOrigin(origin string) (err error) {
+	if strings.ContainsAny(origin, " \t\r\n;,'\"") {
+		err = fmt.Errorf("origin %q contains invalid characters", origin)
+		return
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		err = fmt.Errorf("invalid origin %q: %s", origin, err)
+		return
+	}
+
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		err = fmt.Errorf("origin %q must be http, https, ws or wss", origin)
+		return
+	}
+
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		err = fmt.Errorf("origin %q must be scheme://host[:port]", origin)
+	}
+
+	return
+}
+
+// Validate the origins and header values of a SecurityPolicy
+func validSecurityPolicy(policy SecurityPolicy) (err error) {
+	for _, origin := range policy.Allowlist {
+		if err = validOrigin(origin); err != nil {
+			return
+		}
+	}
+
+	for scid, origins := range policy.Apps {
+		if len(scid) != 64 {
+			err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID for app origins"))
+			return
+		}
+
+		for _, origin := range origins {
+			if err = validOrigin(origin); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, value := range []string{policy.ContentSecurityPolicy, policy.ReferrerPolicy, policy.PermissionsPolicy} {
+		if strings.ContainsAny(value, "\r\n") {
+			err = fmt.Errorf("security header %q contains a line break", value)
+			return
+		}
+	}
+
+	return
+}
+
+// Copy a SecurityPolicy so it is not modified by the caller
+func (p SecurityPolicy) copy() SecurityPolicy {
+	p.Allowlist = append([]string(nil), p.Allowlist...)
+	apps := make(map[string][]string, len(p.Apps))
+	for scid, origins := range p.Apps {
+		apps[scid] = append([]string(nil), origins...)
+	}
+
+	p.Apps = apps
+
+	return p
+}
+
+// Get the Content-Security-Policy for the app at scid cloned from endpoint
+func (p SecurityPolicy) csp(scid, endpoint string) string {
+	if p.ContentSecurityPolicy != "" {
+		return p.ContentSecurityPolicy
+	}
+
+	sources := appendOrigins([]string{"'self'"}, p.Allowlist...)
+	sources = appendOrigins(sources, p.Apps[scid]...)
+	src := strings.Join(sources, " ")
+
+	// Wallet connections are only allowed for connect-src
+	connect := src
+	if !p.NoWalletOrigins {
+		connect = strings.Join(appendOrigins(sources, walletOrigins(endpoint)...), " ")
+	}
+
+	// Inline code does not reach any other origin and is used by many single DOC apps
+	directives := []string{
+		"default-src " + src,
+		"script-src " + src + " 'unsafe-inline' 'unsafe-eval' 'wasm-unsafe-eval' blob:",
+		"style-src " + src + " 'unsafe-inline'",
+		"img-src " + src + " data: blob:",
+		"font-src " + src + " data:",
+		"media-src " + src + " data: blob:",
+		"connect-src " + connect,
+		"worker-src " + src + " blob:",
+		"form-action " + src,
+		"object-src 'none'",
+		"base-uri 'self'",
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// Set the security headers of policy for the app at scid cloned from endpoint
+func (p SecurityPolicy) setHeaders(h http.Header, scid, endpoint string) {
+	if p.Disabled {
+		return
+	}
+
+	referrer := p.ReferrerPolicy
+	if referrer == "" {
+		referrer = DEFAULT_REFERRER_POLICY
+	}
+
+	permissions := p.PermissionsPolicy
+	if permissions == "" {
+		permissions = DEFAULT_PERMISSIONS_POLICY
+	}
+
+	h.Set("Content-Security-Policy", p.csp(scid, endpoint))
+	h.Set("Referrer-Policy", referrer)
+	h.Set("Permissions-Policy", permissions)
+	h.Set("X-Content-Type-Options", "nosniff")
+}
+
+// Wrap handler serving the app at scid cloned from endpoint to send the security headers of the TELA host with all responses
+func (t *TELA) secureHandler(scid, endpoint string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.security.RLock()
+		t.security.policy.setHeaders(w.Header(), scid, endpoint)
+		t.security.RUnlock()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// SetSecurityPolicy sets the security headers sent with all served TELA content, it applies to content already being served
+func (t *TELA) SetSecurityPolicy(policy SecurityPolicy) (err error) {
+	if err = validSecurityPolicy(policy); err != nil {
+		return
+	}
+
+	t.security.Lock()
+	t.security.policy = policy.copy()
+	t.security.Unlock()
+
+	return
+}
+
+// GetSecurityPolicy returns the security headers sent with all served TELA content
+func (t *TELA) GetSecurityPolicy() SecurityPolicy {
+	t.security.RLock()
+	defer t.security.RUnlock()
+
+	return t.security.policy.copy()
+}
+
+// SetAppOrigins sets the origins allowed for the app at a TELA-INDEX SCID in addition to the Allowlist,
+// such as DaemonOrigins of another daemon. No origins removes the app's exceptions
+func (t *TELA) SetAppOrigins(scid string, origins ...string) (err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	for _, origin := range origins {
+		if err = validOrigin(origin); err != nil {
+			return
+		}
+	}
+
+	t.security.Lock()
+	defer t.security.Unlock()
+
+	if t.security.policy.Apps == nil {
+		t.security.policy.Apps = map[string][]string{}
+	}
+
+	if len(origins) == 0 {
+		delete(t.security.policy.Apps, scid)
+	} else {
+		t.security.policy.Apps[scid] = append([]string(nil), origins...)
+	}
+
+	return
+}
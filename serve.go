@@ -0,0 +1,317 @@
+package tela
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content-Type served for each docType, DOC_STATIC uses the type of its file extension
+var docContentTypes = map[string]string{
+	DOC_HTML: "text/html; charset=utf-8",
+	DOC_JSON: "application/json; charset=utf-8",
+	DOC_CSS:  "text/css; charset=utf-8",
+	DOC_JS:   "text/javascript; charset=utf-8",
+	DOC_MD:   "text/markdown; charset=utf-8",
+}
+
+// Cache-Control of content that may change when its INDEX is updated, it is revalidated using its ETag
+const CACHE_CONTROL_LIVE = "no-cache"
+
+// Cache-Control of content served at a commit, which will not change
+const CACHE_CONTROL_PINNED = "public, max-age=31536000, immutable"
+
+// Minimum size of content to be gzip compressed
+const GZIP_MIN_SIZE = 512
+
+// DOC served by a content handler
+type servedDOC struct {
+	scid    string
+	docType string
+}
+
+// Serves cloned DOCs with their docType Content-Type, SCID ETag and cache headers, compressing them if accepted
+type docServer struct {
+	sync.Mutex
+	tela     *TELA
+	clone    Cloning
+	rendered map[string][]byte // Markdown rendered to HTML by DOC path
+	gzipped  map[string][]byte // Compressed content by ETag and DOC path
+}
+
+// Handler serving cloned TELA content from its base path, requests to the root are redirected to the entrypoint
+func (t *TELA) contentHandler(clone Cloning) http.Handler {
+	files := http.FileServer(http.Dir(clone.BasePath))
+	docs := &docServer{tela: t, clone: clone, rendered: map[string][]byte{}, gzipped: map[string][]byte{}}
+	entrypoint := path.Join("/", clone.ServePath, clone.Entrypoint)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handler is mounted with http.StripPrefix and the prefix was requested without a trailing slash
+		if r.URL.Path == "" {
+			if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+				w.Header().Set("Location", "./"+path.Base(u.Path)+"/")
+				w.WriteHeader(http.StatusMovedPermanently)
+				return
+			}
+		}
+
+		// index.html is served at the root by the file server, redirecting it would loop
+		if r.URL.Path == "/" && entrypoint != "/index.html" {
+			// Relative location keeps any prefix the handler is mounted under
+			w.Header().Set("Location", "."+entrypoint)
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+
+		if docs.serve(w, r) {
+			return
+		}
+
+		// Directories and anything that is not a DOC
+		files.ServeHTTP(w, r)
+	})
+}
+
+// Get the Content-Type for a DOC of docType at name, empty if it should be detected from its content
+func docContentType(docType, name string) string {
+	if ctype, ok := docContentTypes[docType]; ok {
+		return ctype
+	}
+
+	return mime.TypeByExtension(path.Ext(name))
+}
+
+// Check if a Content-Type benefits from compression
+func compressible(ctype string) bool {
+	ctype, _, _ = strings.Cut(ctype, ";")
+	switch {
+	case strings.HasPrefix(ctype, "text/"):
+		return true
+	case ctype == "application/json", ctype == "application/javascript", ctype == "application/xml", ctype == "image/svg+xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// Check if the request accepts gzip encoding
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(enc, ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, "gzip") && name != "*" {
+			continue
+		}
+
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+
+		weight, err := strconv.ParseFloat(q, 64)
+
+		return err == nil && weight > 0
+	}
+
+	return false
+}
+
+// Serve the DOC requested if it was cloned, returns false if the request is not for a DOC
+func (d *docServer) serve(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+
+	doc, ok := d.clone.files[name]
+	if !ok {
+		return false
+	}
+
+	file, err := os.Open(filepath.Join(d.clone.BasePath, filepath.FromSlash(name)))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+
+	ctype := docContentType(doc.docType, name)
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	// DOCs are immutable so their SCID identifies the content, the path only changes DOC when its INDEX is updated
+	cache := CACHE_CONTROL_LIVE
+	if d.clone.pinned {
+		cache = CACHE_CONTROL_PINNED
+	}
+
+	w.Header().Set("Cache-Control", cache)
+
+	// Markdown is rendered to HTML unless the raw file is requested
+	var content io.ReadSeeker = file
+	size := stat.Size()
+	tag := doc.scid
+	if doc.docType == DOC_MD && d.tela.MarkdownRendered() && !r.URL.Query().Has("raw") {
+		rendered, err := d.markdown(name, file)
+		if err != nil {
+			return false
+		}
+
+		content = bytes.NewReader(rendered)
+		size = int64(len(rendered))
+		ctype = docContentTypes[DOC_HTML]
+		// Rendered links depend on the other files of the INDEX commit
+		tag += "-html-" + d.clone.Hash
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	if compressible(ctype) && size >= GZIP_MIN_SIZE {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			if gzipped, err := d.gzip(tag, name, content); err == nil {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Header().Set("ETag", strconv.Quote(tag+"-gzip"))
+				http.ServeContent(w, r, name, stat.ModTime(), bytes.NewReader(gzipped))
+				return true
+			}
+
+			// Serve uncompressed from the start of content
+			if _, err := content.Seek(0, io.SeekStart); err != nil {
+				return false
+			}
+		}
+	}
+
+	w.Header().Set("ETag", strconv.Quote(tag))
+	http.ServeContent(w, r, name, stat.ModTime(), content)
+
+	return true
+}
+
+// Get the TELA-MD-1 DOC at name rendered to HTML, rendering file if it has not been rendered
+func (d *docServer) markdown(name string, file *os.File) (rendered []byte, err error) {
+	d.Lock()
+	defer d.Unlock()
+
+	if rendered, ok := d.rendered[name]; ok {
+		return rendered, nil
+	}
+
+	source, err := io.ReadAll(file)
+	if err != nil {
+		return
+	}
+
+	rendered = []byte(renderMarkdown(string(source), path.Base(name), d.resolver(name)))
+	d.rendered[name] = rendered
+
+	return
+}
+
+// Get the link resolver for markdown at name. Links to paths within the INDEX are made relative to name so they work under any prefix,
+// links to a DOC without its .md extension are resolved to the DOC
+func (d *docServer) resolver(name string) func(link string) string {
+	dir := path.Dir(name)
+
+	return func(link string) string {
+		p, suffix := link, ""
+		if i := strings.IndexAny(link, "?#"); i >= 0 {
+			p, suffix = link[:i], link[i:]
+		}
+
+		if p == "" {
+			return link
+		}
+
+		target, err := url.PathUnescape(p)
+		if err != nil {
+			return link
+		}
+
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(path.Clean(target), "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+
+		// Links outside of the INDEX are not resolved
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return link
+		}
+
+		if _, ok := d.clone.files[target]; !ok {
+			if _, ok := d.clone.files[target+".md"]; ok {
+				target += ".md"
+			}
+		}
+
+		rel, err := filepath.Rel(dir, target)
+		if err != nil {
+			return link
+		}
+
+		rel = filepath.ToSlash(rel)
+		switch {
+		case rel == ".":
+			rel = "./"
+		case strings.HasSuffix(p, "/"):
+			rel += "/"
+		}
+
+		// Relative paths are not mistaken for a scheme
+		if !strings.HasPrefix(rel, ".") {
+			rel = "./" + rel
+		}
+
+		return (&url.URL{Path: rel}).EscapedPath() + suffix
+	}
+}
+
+// Get the gzip compressed content of the DOC at name with ETag tag, compressing content if it has not been compressed
+func (d *docServer) gzip(tag, name string, content io.Reader) (gzipped []byte, err error) {
+	d.Lock()
+	defer d.Unlock()
+
+	key := tag + "/" + name
+	if gzipped, ok := d.gzipped[key]; ok {
+		return gzipped, nil
+	}
+
+	source, err := io.ReadAll(content)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err = gz.Write(source); err != nil {
+		return
+	}
+
+	if err = gz.Close(); err != nil {
+		return
+	}
+
+	gzipped = buf.Bytes()
+	d.gzipped[key] = gzipped
+
+	return
+}
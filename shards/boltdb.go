@@ -52,9 +52,13 @@ func boltStoreEncryptedValue(disk *walletapi.Wallet_Disk, bucket string, key []b
 
 // Store a key-value in a bbolt DB
 func boltStoreValue(bucket string, key []byte, value []byte) (err error) {
-	var shard string
-	shard, err = boltGetShard(nil)
-	if err != nil {
+	return boltStorePathValue(GetPath(), bucket, key, value)
+}
+
+// Store a key-value in the bbolt DB of the datashards at path
+func boltStorePathValue(path, bucket string, key []byte, value []byte) (err error) {
+	shard := settingsShard(path)
+	if err = os.MkdirAll(shard, os.ModePerm); err != nil {
 		return
 	}
 
@@ -85,9 +89,13 @@ func boltStoreValue(bucket string, key []byte, value []byte) (err error) {
 
 // Get a key-value from a bbolt DB
 func boltGetValue(bucket string, key []byte) (result []byte, err error) {
-	var shard string
-	shard, err = boltGetShard(nil)
-	if err != nil {
+	return boltGetPathValue(GetPath(), bucket, key)
+}
+
+// Get a key-value from the bbolt DB of the datashards at path
+func boltGetPathValue(path, bucket string, key []byte) (result []byte, err error) {
+	shard := settingsShard(path)
+	if err = os.MkdirAll(shard, os.ModePerm); err != nil {
 		return
 	}
 
@@ -64,6 +64,11 @@ func gravitonStoreEncryptedValue(disk *walletapi.Wallet_Disk, t string, key, val
 
 // Store a key-value in a Graviton tree
 func gravitonStoreValue(t string, key, value []byte) (err error) {
+	return gravitonStorePathValue(GetPath(), t, key, value)
+}
+
+// Store a key-value in a Graviton tree of the datashards at path
+func gravitonStorePathValue(path, t string, key, value []byte) (err error) {
 	if t == "" {
 		err = fmt.Errorf("missing graviton tree input")
 		return
@@ -79,7 +84,7 @@ func gravitonStoreValue(t string, key, value []byte) (err error) {
 		return
 	}
 
-	store, err := graviton.NewDiskStore(GetShard(nil))
+	store, err := graviton.NewDiskStore(settingsShard(path))
 	if err != nil {
 		return
 	}
@@ -109,6 +114,11 @@ func gravitonStoreValue(t string, key, value []byte) (err error) {
 
 // Get a key-value from a Graviton tree
 func gravitonGetValue(t string, key []byte) (result []byte, err error) {
+	return gravitonGetPathValue(GetPath(), t, key)
+}
+
+// Get a key-value from a Graviton tree of the datashards at path
+func gravitonGetPathValue(path, t string, key []byte) (result []byte, err error) {
 	result = []byte("")
 	if t == "" {
 		err = fmt.Errorf("missing graviton tree input")
@@ -120,7 +130,7 @@ func gravitonGetValue(t string, key []byte) (result []byte, err error) {
 		return
 	}
 
-	store, err := graviton.NewDiskStore(GetShard(nil))
+	store, err := graviton.NewDiskStore(settingsShard(path))
 	if err != nil {
 		return
 	}
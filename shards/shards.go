@@ -99,7 +99,7 @@ func GetDBType() string {
 func GetShard(disk *walletapi.Wallet_Disk) (result string) {
 	dir := GetPath()
 	if disk == nil {
-		result = filepath.Join(dir, "settings")
+		result = settingsShard(dir)
 	} else {
 		address := disk.GetAddress().String()
 		result = filepath.Join(dir, fmt.Sprintf("%x", sha1.Sum([]byte(address))))
@@ -153,6 +153,41 @@ func GetValue(t string, key []byte) (result []byte, err error) {
 	return
 }
 
+// Store a key-value in the datashards at path in place of the package datashards path
+func StorePathValue(path, t string, key, value []byte) (err error) {
+	db := GetDBType()
+	switch db {
+	case "gravdb":
+		err = gravitonStorePathValue(path, t, key, value)
+	case "boltdb":
+		err = boltStorePathValue(path, t, key, value)
+	default:
+		err = fmt.Errorf("unknown db type %q", db)
+	}
+
+	return
+}
+
+// Get a key-value from the datashards at path in place of the package datashards path
+func GetPathValue(path, t string, key []byte) (result []byte, err error) {
+	db := GetDBType()
+	switch db {
+	case "gravdb":
+		result, err = gravitonGetPathValue(path, t, key)
+	case "boltdb":
+		result, err = boltGetPathValue(path, t, key)
+	default:
+		err = fmt.Errorf("unknown db type %q", db)
+	}
+
+	return
+}
+
+// Get the settings datashard path within the datashards at path
+func settingsShard(path string) string {
+	return filepath.Join(path, "settings")
+}
+
 // Store a key-value setting in datashards
 func StoreSettingsValue(key, value []byte) (err error) {
 	db := GetDBType()
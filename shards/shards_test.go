@@ -78,6 +78,19 @@ func TestShards(t *testing.T) {
 				assert.Equal(t, value, result, "Stored endpoints should be equal")
 			})
 
+			t.Run("PathValue", func(t *testing.T) {
+				path := filepath.Join(datashards, "path_"+dbType)
+				err := StorePathValue(path, tree, key, value)
+				assert.NoError(t, err, "Storing path value should not error: %s", err)
+				result, err := GetPathValue(path, tree, key)
+				assert.NoError(t, err, "Getting stored path value should not error: %s", err)
+				assert.Equal(t, value, result, "Stored path values should be equal")
+				_, err = os.Stat(settingsShard(path))
+				assert.NoError(t, err, "Path value should be stored within path: %s", err)
+				_, err = GetPathValue(filepath.Join(datashards, "other_"+dbType), tree, key)
+				assert.Error(t, err, "Path value should not exist at other path")
+			})
+
 			t.Run("SettingsValue", func(t *testing.T) {
 				err := StoreSettingsValue(key, value)
 				assert.NoError(t, err, "Storing value should not error: %s", err)
@@ -143,6 +156,10 @@ func TestShards(t *testing.T) {
 		assert.Error(t, err, "Storing value with invalid DB type should error")
 		_, err = GetValue(tree, key)
 		assert.Error(t, err, "Getting value with invalid DB type should error")
+		err = StorePathValue(datashards, tree, key, value)
+		assert.Error(t, err, "Storing path value with invalid DB type should error")
+		_, err = GetPathValue(datashards, tree, key)
+		assert.Error(t, err, "Getting path value with invalid DB type should error")
 		err = StoreSettingsValue(key, value)
 		assert.Error(t, err, "Storing settings value with invalid DB type should error")
 		_, err = GetSettingsValue(key)
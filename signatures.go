@@ -0,0 +1,138 @@
+package tela
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/deroproject/derohe/cryptography/bn256"
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/rpc"
+)
+
+// Signature policy used when cloning and serving TELA-DOCs
+type SignaturePolicy uint8
+
+const (
+	SIGNATURE_WARN    SignaturePolicy = iota // DOC signatures are verified and failures are logged, default policy
+	SIGNATURE_OFF                            // DOC signatures are not verified
+	SIGNATURE_ENFORCE                        // DOC signatures are verified and failures will stop the clone
+)
+
+// Signature verification result of a cloned TELA-DOC
+type DOCVerification struct {
+	SCID     string `json:"scid"`            // SCID of the DOC
+	Name     string `json:"name"`            // nameHdr of the DOC
+	Author   string `json:"author"`          // Owner address the signature was verified against
+	Verified bool   `json:"verified"`        // True if the DOC signature is valid for the docCode and author
+	Error    string `json:"error,omitempty"` // Reason verification failed
+}
+
+// Returns the string representation of SignaturePolicy
+func (p SignaturePolicy) String() string {
+	switch p {
+	case SIGNATURE_OFF:
+		return "off"
+	case SIGNATURE_WARN:
+		return "warn"
+	case SIGNATURE_ENFORCE:
+		return "enforce"
+	default:
+		return "unknown"
+	}
+}
+
+// Parse a string for its SignaturePolicy
+func ParseSignaturePolicy(policy string) (p SignaturePolicy, err error) {
+	switch strings.ToLower(policy) {
+	case "off":
+		p = SIGNATURE_OFF
+	case "warn":
+		p = SIGNATURE_WARN
+	case "enforce":
+		p = SIGNATURE_ENFORCE
+	default:
+		err = fmt.Errorf("unknown signature policy %q", policy)
+	}
+
+	return
+}
+
+// Check if C and S signature values are valid for data signed by address
+func CheckSignature(address string, signature Signature, data []byte) (err error) {
+	addr, err := rpc.NewAddress(address)
+	if err != nil {
+		err = fmt.Errorf("invalid signature address: %s", err)
+		return
+	}
+
+	c, ok := new(big.Int).SetString(signature.CheckC, 16)
+	if !ok {
+		err = fmt.Errorf("unknown C format")
+		return
+	}
+
+	s, ok := new(big.Int).SetString(signature.CheckS, 16)
+	if !ok {
+		err = fmt.Errorf("unknown S format")
+		return
+	}
+
+	point := new(bn256.G1).Add(new(bn256.G1).ScalarMult(crypto.G, s), new(bn256.G1).ScalarMult(addr.PublicKey.G1(), new(big.Int).Neg(c)))
+	serialize := []byte(fmt.Sprintf("%s%s%x", addr.PublicKey.G1().String(), point.String(), data))
+
+	if c.Cmp(crypto.ReducedHash(serialize)) != 0 {
+		err = fmt.Errorf("signature mismatch")
+	}
+
+	return
+}
+
+// Parse a TELA-DOC's code for its docCode exactly as it was appended to the contract
+func parseDocCode(code string) (docCode string, err error) {
+	start := strings.Index(code, "/*")
+	end := strings.LastIndex(code, "*/")
+	if start == -1 || end == -1 || end < start+2 {
+		err = fmt.Errorf("could not parse multiline comment")
+		return
+	}
+
+	docCode = code[start+2 : end]
+	docCode = strings.TrimPrefix(docCode, "\n")
+	docCode = strings.TrimSuffix(docCode, "\n")
+
+	return
+}
+
+// Verify a TELA-DOC's signature headers against its owner, the signed payload is rebuilt from the docCode in code's comment block
+// decoded with the encoding it was installed with
+func verifyDOC(scid, name, owner, code, encoding string, signature Signature) (result DOCVerification) {
+	result = DOCVerification{SCID: scid, Name: name, Author: owner}
+
+	if owner == "" || owner == "anon" {
+		result.Error = "DOC has no owner to verify signature against"
+		return
+	}
+
+	docCode, err := parseDocCode(code)
+	if err == nil {
+		docCode, err = decodeDocCode(docCode, encoding)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	// Trailing whitespace may have been trimmed from the signed file when it was installed
+	for _, payload := range []string{docCode, strings.TrimSpace(docCode)} {
+		if err = CheckSignature(owner, signature, []byte(payload)); err == nil {
+			result.Verified = true
+			return
+		}
+	}
+
+	result.Error = err.Error()
+
+	return
+}
@@ -3,7 +3,9 @@ package tela
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
@@ -12,30 +14,30 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/civilware/Gnomon/rwc"
 	"github.com/civilware/tela/logger"
 	"github.com/civilware/tela/shards"
-	"github.com/creachadair/jrpc2"
-	"github.com/creachadair/jrpc2/channel"
 	"github.com/deroproject/derohe/cryptography/crypto"
 	"github.com/deroproject/derohe/globals"
 	"github.com/deroproject/derohe/rpc"
 	"github.com/deroproject/derohe/walletapi"
-	"github.com/gorilla/websocket"
 
 	_ "embed"
 )
 
 // TELA-DOC-1 structure
 type DOC struct {
-	DocType string `json:"docType"` // Language this document is using (ex: "TELA-HTML-1", "TELA-JS-1" or "TELA-CSS-1")
-	Code    string `json:"code"`    // The application code HTML, JS...
-	SubDir  string `json:"subDir"`  // Sub directory to place file in (always use / for further children, ex: "sub1" or "sub1/sub2/sub3")
-	SCID    string `json:"scid"`    // SCID of this DOC, only used after DOC has been installed on-chain
-	Author  string `json:"author"`  // Author of this DOC, only used after DOC has been installed on-chain
-	DURL    string `json:"dURL"`    // TELA dURL
+	DocType  string `json:"docType"`            // Language this document is using (ex: "TELA-HTML-1", "TELA-JS-1" or "TELA-CSS-1")
+	Code     string `json:"code"`               // The application code HTML, JS...
+	SubDir   string `json:"subDir"`             // Sub directory to place file in (always use / for further children, ex: "sub1" or "sub1/sub2/sub3")
+	SCID     string `json:"scid"`               // SCID of this DOC, only used after DOC has been installed on-chain
+	Author   string `json:"author"`             // Author of this DOC, only used after DOC has been installed on-chain
+	DURL     string `json:"dURL"`               // TELA dURL
+	Part     uint64 `json:"part,omitempty"`     // Part number of a file chunked across multiple DOCs, starting at 1
+	Parts    uint64 `json:"parts,omitempty"`    // Total parts of a chunked file, 0 if the file is not chunked
+	Encoding string `json:"encoding,omitempty"` // Encoding of Code when installed, empty uses DOC_ENCODING_GZIP if it saves space and always encodes binary Code
 	// Signature values of Code
 	Signature `json:"signature"`
 	// Standard headers
@@ -58,7 +60,13 @@ type Cloning struct {
 	ServePath  string `json:"servePath"`  // URL serve path
 	Entrypoint string `json:"entrypoint"` // INDEX entrypoint
 	DURL       string `json:"dURL"`       // TELA dURL
-	Hash       string `json:"hash"`       // Commit hash of INDEX
+	Hash       string `json:"hash"`       // Commit hash of INDEX that was cloned
+	Latest     string `json:"latest"`     // Latest commit hash of INDEX, differs from Hash when its update rule uses a previous commit
+	// Signature verification results of cloned DOCs
+	Verifications []DOCVerification `json:"verifications,omitempty"`
+
+	files  map[string]servedDOC // DOCs cloned by their path within BasePath
+	pinned bool                 // Content was cloned at a commit and will not change
 }
 
 // Library structure for search queries
@@ -75,6 +83,9 @@ type ServerInfo struct {
 	Address    string
 	SCID       string
 	Entrypoint string
+	Commit     string
+	// Signature verification results of served DOCs
+	Verifications []DOCVerification
 }
 
 // Datashards structure
@@ -82,21 +93,37 @@ type ds struct {
 	main string
 }
 
-// TELA core components for serving content from TELA-INDEX-1 smart contracts
+// TELA host with the core components for serving content from TELA-INDEX-1 smart contracts, create with New
 type TELA struct {
 	sync.RWMutex
-	servers map[ServerInfo]*http.Server
-	path    ds   // Access datashard paths
-	updates bool // Allow updated content
-	port    int  // Start port to range servers from
-	max     int  // Max amount of TELA servers
-	client  struct {
-		WS  *websocket.Conn
-		RPC *jrpc2.Client
-	}
+	servers    map[*ServerInfo]*http.Server
+	path       ds                    // Access datashard paths
+	updates    atomic.Bool           // Allow updated content, read while cloning with or without the lock held
+	signatures atomic.Uint32         // SignaturePolicy used to verify DOC signatures when cloning
+	port       int                   // Start port to range servers from
+	max        int                   // Max amount of TELA servers
+	workers    int                   // Max amount of concurrent contract fetches when cloning
+	client     daemonClient          // Daemon connection used for TELA RPC calls
+	daemon     daemonSource          // Source of chain data, defaults to client
+	rules      updateRules           // Update rules of TELA-INDEXs
+	gateway    *Gateway              // Gateway serving all TELA content from a single port
+	handlers   map[*Handler]struct{} // Handlers serving TELA content from host application servers
+	security   securityPolicy        // Security headers of served content
+	rawMD      atomic.Bool           // Serve TELA-MD-1 DOCs as raw markdown
 }
 
-var tela TELA
+// Config for creating a TELA host with New, zero values will use the TELA defaults
+type Config struct {
+	Path       string          // Directory to store TELA files in, a datashards directory is created within it. Defaults to the shards package path
+	PortStart  int             // Port to start serving TELA servers from
+	MaxServers int             // Max amount of TELA servers
+	MaxWorkers int             // Max amount of concurrent contract fetches when cloning
+	Updates    bool            // Allow updated content
+	Signatures SignaturePolicy // Verify DOC signatures when cloning
+	Daemon     Daemon          // Source of chain data, defaults to a RPC connection to the endpoint given
+	Security   SecurityPolicy  // Security headers of served content
+	RawMD      bool            // Serve TELA-MD-1 DOCs as raw markdown in place of rendering them to HTML
+}
 
 const DOC_STATIC = "TELA-STATIC-1" // Generic docType for any file type
 const DOC_HTML = "TELA-HTML-1"     // HTML docType
@@ -105,7 +132,13 @@ const DOC_CSS = "TELA-CSS-1"       // CSS docType
 const DOC_JS = "TELA-JS-1"         // JavaScript docType
 const DOC_MD = "TELA-MD-1"         // Markdown docType
 
+const DOC_ENCODING_GZIP = "gzip+base64" // docEncoding of DOC code that is gzip compressed and base64 encoded
+const DOC_ENCODING_BASE64 = "base64"    // docEncoding of binary DOC code that is base64 encoded
+const DOC_ENCODING_ESCAPED = "escaped"  // docEncoding of DOC code with its comment terminators escaped
+const DOC_ENCODING_NONE = "none"        // Install DOC code as it is
+
 const DEFAULT_MAX_SERVER = 20   // Default max amount of servers
+const DEFAULT_MAX_WORKERS = 4   // Default max amount of concurrent contract fetches
 const DEFAULT_PORT_START = 8082 // Default start port for servers
 const DEFAULT_MIN_PORT = 1200   // Minimum port of possible serving range
 const DEFAULT_MAX_PORT = 65535  // Maximum port of possible serving range
@@ -127,16 +160,74 @@ var TELA_INDEX_1 string
 //go:embed */TELA-DOC-1.bas
 var TELA_DOC_1 string
 
-// Initialize the default storage path TELA will use, can be changed with SetShardPath if required
+// Initialize the package ratings
 func init() {
-	tela.path.main = shards.GetPath()
-
 	initRatings()
-	tela.port = DEFAULT_PORT_START
-	tela.max = DEFAULT_MAX_SERVER
+}
+
+// Create a TELA host with the default settings and storage path
+func newTELA() (t *TELA) {
+	t = &TELA{
+		port:    DEFAULT_PORT_START,
+		max:     DEFAULT_MAX_SERVER,
+		workers: DEFAULT_MAX_WORKERS,
+	}
+
+	t.path.main = shards.GetPath()
+	t.rules.path = t.path.main
+	t.signatures.Store(uint32(SIGNATURE_WARN))
+	t.daemon.daemon = &t.client
+
+	return
+}
+
+// New creates a TELA host from config, each host has its own servers, settings, storage path and daemon connection.
+// Hosts should not share a storage path as their TELA files are removed when shutdown
+func New(config Config) (t *TELA, err error) {
+	host := newTELA()
+
+	if config.Path != "" {
+		if err = host.SetShardPath(config.Path); err != nil {
+			return
+		}
+	}
+
+	if config.PortStart != 0 {
+		if err = host.SetPortStart(config.PortStart); err != nil {
+			return
+		}
+	}
+
+	if config.MaxServers != 0 {
+		host.SetMaxServers(config.MaxServers)
+	}
+
+	if config.MaxWorkers != 0 {
+		host.SetMaxWorkers(config.MaxWorkers)
+	}
+
+	if err = host.SetSignaturePolicy(config.Signatures); err != nil {
+		return
+	}
+
+	if err = host.SetSecurityPolicy(config.Security); err != nil {
+		return
+	}
+
+	host.AllowUpdates(config.Updates)
+	host.RenderMarkdown(!config.RawMD)
+	host.SetDaemon(config.Daemon)
+
+	t = host
 
-	// Cleanup any residual files before package is used
-	os.RemoveAll(tela.path.tela())
+	return
+}
+
+// Remove any residual TELA files before the first server is started, caller must hold the lock
+func (t *TELA) cleanup() {
+	if t.servers == nil {
+		os.RemoveAll(t.path.tela())
+	}
 }
 
 // Returns TELA datashard path
@@ -150,17 +241,17 @@ func (s ds) clone() string {
 }
 
 // Find if port is within valid range
-func isValidPort(port int) bool {
-	if port < DEFAULT_MIN_PORT || port > DEFAULT_MAX_PORT-tela.max {
+func (t *TELA) isValidPort(port int) bool {
+	if port < DEFAULT_MIN_PORT || port > DEFAULT_MAX_PORT-t.max {
 		return false
 	}
 	return true
 }
 
 // Listen for open ports and returns http server for TELA content on open port if found
-func FindOpenPort() (server *http.Server, found bool) {
-	max := tela.port + tela.max
-	port := tela.port // Start on tela.port and try +20
+func (t *TELA) FindOpenPort() (server *http.Server, found bool) {
+	max := t.port + t.max
+	port := t.port // Start on t.port and try +20
 	server = &http.Server{Addr: fmt.Sprintf(":%d", port)}
 	for !found && port < max {
 		li, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
@@ -195,18 +286,22 @@ func IsAcceptedLanguage(language string) bool {
 	return false
 }
 
-// Parse a TELA DOC for useable code and write file if IsAcceptedLanguage
-func parseAndSaveTELADoc(filePath, code, doctype string) (err error) {
-	start := strings.Index(code, "/*")
-	end := strings.Index(code, "*/")
-
-	if start == -1 || end == -1 {
-		err = fmt.Errorf("could not parse multiline comment")
+// Parse a TELA DOC for its useable code if IsAcceptedLanguage, decoding it if it was installed with encoding
+func parseTELADoc(code, doctype, encoding string) (content string, err error) {
+	comment, err := parseDocCode(code)
+	if err != nil {
 		return
 	}
 
-	comment := code[start+2:]
-	comment = strings.TrimSpace(strings.TrimSuffix(comment, "*/"))
+	// Raw text is trimmed of the whitespace it may have been installed with, encoded content is decoded exactly as it was installed
+	if rawEncoding(encoding) {
+		comment = strings.TrimSpace(comment)
+	}
+
+	comment, err = decodeDocCode(comment, encoding)
+	if err != nil {
+		return
+	}
 
 	// TODO any further DOC parsing for docTypes
 	switch doctype {
@@ -227,6 +322,23 @@ func parseAndSaveTELADoc(filePath, code, doctype string) (err error) {
 		return
 	}
 
+	content = comment
+
+	return
+}
+
+// Parse a TELA DOC for useable code and write file if IsAcceptedLanguage, decoding it if it was installed with encoding
+func parseAndSaveTELADoc(filePath, code, doctype, encoding string) (err error) {
+	content, err := parseTELADoc(code, doctype, encoding)
+	if err != nil {
+		return
+	}
+
+	return saveTELADoc(filePath, content)
+}
+
+// Write the content of a TELA DOC to a new file at filePath
+func saveTELADoc(filePath, content string) (err error) {
 	err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
 	if err != nil {
 		return
@@ -234,7 +346,23 @@ func parseAndSaveTELADoc(filePath, code, doctype string) (err error) {
 
 	logger.Printf("[TELA] Creating %s\n", filepath.Base(filePath))
 
-	return os.WriteFile(filePath, []byte(comment), 0644)
+	// Existing files are never overwritten
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write([]byte(content))
+	if errr := file.Close(); err == nil {
+		err = errr
+	}
+
+	// Remove any partially written file
+	if err != nil {
+		os.Remove(filePath)
+	}
+
+	return
 }
 
 // Decode a hex string if possible otherwise return it
@@ -247,26 +375,19 @@ func decodeHexString(hexStr string) string {
 }
 
 // Get a string key from smart contract at endpoint
-func getContractVar(scid, key, endpoint string) (variable string, err error) {
+func (t *TELA) getContractVar(ctx context.Context, scid, key, endpoint string) (variable string, err error) {
 	var params = rpc.GetSC_Params{SCID: scid, Variables: false, Code: false, KeysString: []string{key}}
 	var result rpc.GetSC_Result
 
-	tela.client.WS, _, err = websocket.DefaultDialer.Dial("ws://"+endpoint+"/ws", nil)
-	if err != nil {
-		return
-	}
-
-	input_output := rwc.New(tela.client.WS)
-	tela.client.RPC = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
-
-	err = tela.client.RPC.CallResult(context.Background(), "DERO.GetSC", params, &result)
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
 	if err != nil {
+		err = daemonError(ctx, scid, "", err)
 		return
 	}
 
 	res := result.ValuesString
 	if len(res) < 1 || res[0] == "" || strings.Contains(res[0], "NOT AVAILABLE err:") {
-		err = fmt.Errorf("invalid string value for %q", key)
+		err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("invalid string value for %q", key))
 		return
 	}
 
@@ -282,26 +403,19 @@ func getContractVar(scid, key, endpoint string) (variable string, err error) {
 }
 
 // Get a TXID as hex from daemon endpoint
-func getTXID(txid, endpoint string) (txidAsHex string, err error) {
+func (t *TELA) getTXID(ctx context.Context, txid, endpoint string) (txidAsHex string, err error) {
 	var params = rpc.GetTransaction_Params{Tx_Hashes: []string{txid}}
 	var result rpc.GetTransaction_Result
 
-	tela.client.WS, _, err = websocket.DefaultDialer.Dial("ws://"+endpoint+"/ws", nil)
-	if err != nil {
-		return
-	}
-
-	input_output := rwc.New(tela.client.WS)
-	tela.client.RPC = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
-
-	err = tela.client.RPC.CallResult(context.Background(), "DERO.GetTransaction", params, &result)
+	result, err = t.getDaemon().GetTransaction(ctx, endpoint, params)
 	if err != nil {
+		err = daemonError(ctx, "", txid, err)
 		return
 	}
 
 	res := result.Txs_as_hex
 	if len(res) < 1 || res[0] == "" {
-		err = fmt.Errorf("no data found for TXID %s", txid)
+		err = newError(ErrInvalidCommit, "", "", txid, fmt.Errorf("no data found for TXID"))
 		return
 	}
 
@@ -311,63 +425,267 @@ func getTXID(txid, endpoint string) (txidAsHex string, err error) {
 }
 
 // Get the current state of all string keys in a smart contract
-func getContractVars(scid, endpoint string) (vars map[string]interface{}, err error) {
+func (t *TELA) getContractVars(ctx context.Context, scid, endpoint string) (vars map[string]interface{}, err error) {
 	var params = rpc.GetSC_Params{SCID: scid, Variables: true, Code: false}
 	var result rpc.GetSC_Result
 
-	tela.client.WS, _, err = websocket.DefaultDialer.Dial("ws://"+endpoint+"/ws", nil)
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
 	if err != nil {
+		err = daemonError(ctx, scid, "", err)
 		return
 	}
 
-	input_output := rwc.New(tela.client.WS)
-	tela.client.RPC = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
+	vars = result.VariableStringKeys
+
+	return
+}
+
+// Get the current code of a smart contract at endpoint
+func (t *TELA) getContractCode(ctx context.Context, scid, endpoint string) (code string, err error) {
+	var params = rpc.GetSC_Params{SCID: scid, Variables: false, Code: true}
+	var result rpc.GetSC_Result
 
-	err = tela.client.RPC.CallResult(context.Background(), "DERO.GetSC", params, &result)
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
 	if err != nil {
+		err = daemonError(ctx, scid, "", err)
 		return
 	}
 
-	vars = result.VariableStringKeys
+	if result.Code == "" {
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("code is empty string"))
+		return
+	}
+
+	code = result.Code
 
 	return
 }
 
-// Get the current code of a smart contract at endpoint
-func getContractCode(scid, endpoint string) (code string, err error) {
-	var params = rpc.GetSC_Params{SCID: scid, Variables: false, Code: true}
+// Code and string keys of a smart contract from a single GetSC call, giving a consistent view of the contract
+type contractState struct {
+	scid string
+	vars map[string]interface{}
+}
+
+// Get the current code and all string keys of a smart contract at endpoint
+func (t *TELA) getContractState(ctx context.Context, scid, endpoint string) (state contractState, err error) {
+	vars, err := t.getContractVars(ctx, scid, endpoint)
+	if err != nil {
+		return
+	}
+
+	state = contractState{scid: scid, vars: vars}
+
+	return
+}
+
+// Keys of a TELA-DOC or TELA-INDEX that place it within a file tree, docPart and docParts are the only uint keys
+var treeKeys = []string{"telaVersion", HEADER_DURL.Trim(), HEADER_DOCTYPE.Trim(), HEADER_NAME.Trim(), HEADER_SUBDIR.Trim(), HEADER_ENCODING.Trim(), HEADER_PART.Trim(), HEADER_PARTS.Trim()}
+
+// Get the treeKeys of a smart contract at endpoint without its code, the state of a DOC is fetched with getContractState when it is read
+func (t *TELA) getContractTreeState(ctx context.Context, scid, endpoint string) (state contractState, err error) {
+	var params = rpc.GetSC_Params{SCID: scid, Variables: false, Code: false, KeysString: treeKeys}
 	var result rpc.GetSC_Result
 
-	tela.client.WS, _, err = websocket.DefaultDialer.Dial("ws://"+endpoint+"/ws", nil)
+	result, err = t.getDaemon().GetSC(ctx, endpoint, params)
 	if err != nil {
+		err = daemonError(ctx, scid, "", err)
 		return
 	}
 
-	input_output := rwc.New(tela.client.WS)
-	tela.client.RPC = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
+	state = contractState{scid: scid, vars: map[string]interface{}{}}
+	for i, value := range result.ValuesString {
+		if i >= len(treeKeys) || value == "" || strings.Contains(value, "NOT AVAILABLE err:") {
+			continue
+		}
+
+		// uint values are not hex encoded, they are kept as float64 the same as when all variables are requested
+		key := treeKeys[i]
+		if key == HEADER_PART.Trim() || key == HEADER_PARTS.Trim() {
+			if u, errr := strconv.ParseUint(value, 10, 64); errr == nil {
+				state.vars[key] = float64(u)
+			}
+
+			continue
+		}
+
+		state.vars[key] = value
+	}
+
+	return
+}
+
+// Get the contract states of scids concurrently using up to MaxWorkers, states are returned in the order of scids.
+// The error of the lowest index scid that failed is returned, an error stops any fetches after its index and ctx being done stops all fetches
+func (t *TELA) getContractStates(parent context.Context, scids []string, endpoint string) (states []contractState, err error) {
+	return t.fetchContractStates(parent, scids, endpoint, t.getContractState)
+}
+
+// Get the contract states of scids concurrently as getContractStates does using fetch for each scid
+func (t *TELA) fetchContractStates(parent context.Context, scids []string, endpoint string, fetch func(ctx context.Context, scid, endpoint string) (contractState, error)) (states []contractState, err error) {
+	states = make([]contractState, len(scids))
+
+	workers := t.workers
+	if workers > len(scids) {
+		workers = len(scids)
+	}
+
+	// Fetches of indexes lower than a failed index continue so the same error is returned whichever fails first
+	var mu sync.Mutex
+	failed := len(scids)
+	errs := make([]error, len(scids))
+	cancels := make([]context.CancelFunc, len(scids))
+
+	// Start fetching index i unless a lower index has failed
+	start := func(i int) (ctx context.Context, cancel context.CancelFunc, ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if i > failed || parent.Err() != nil {
+			return
+		}
+
+		ctx, cancel = context.WithCancel(parent)
+		cancels[i] = cancel
+
+		return ctx, cancel, true
+	}
+
+	// Record the error of index i and cancel any fetches after it
+	fail := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs[i] = err
+		if i < failed {
+			failed = i
+			for _, cancel := range cancels[i+1:] {
+				if cancel != nil {
+					cancel()
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ctx, cancel, ok := start(i)
+				if !ok {
+					continue
+				}
+
+				state, errr := fetch(ctx, scids[i], endpoint)
+				cancel()
+				if errr != nil {
+					fail(i, errr)
+					continue
+				}
+
+				states[i] = state
+			}
+		}()
+	}
+
+	for i := range scids {
+		mu.Lock()
+		stop := i > failed || parent.Err() != nil
+		mu.Unlock()
+		if stop {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for _, errr := range errs {
+		if errr != nil {
+			err = fmt.Errorf("could not get SC code: %w", errr)
+			break
+		}
+	}
+
+	// Fetches may have stopped without error when parent was done between them
+	if err == nil && parent.Err() != nil {
+		err = fmt.Errorf("could not get SC code: %w", daemonError(parent, "", "", parent.Err()))
+	}
 
-	err = tela.client.RPC.CallResult(context.Background(), "DERO.GetSC", params, &result)
 	if err != nil {
+		states = nil
+	}
+
+	return
+}
+
+// Get a string key from the contract state decoded as getContractVar would
+func (s contractState) value(key string) (variable string, err error) {
+	switch v := s.vars[key].(type) {
+	case string:
+		if v != "" {
+			variable = decodeHexString(v)
+			return
+		}
+	case float64:
+		variable = strconv.FormatUint(uint64(v), 10)
 		return
 	}
 
-	if result.Code == "" {
-		err = fmt.Errorf("code is empty string")
+	err = newError(ErrMissingHeader, s.scid, "", "", fmt.Errorf("invalid string value for %q", key))
+
+	return
+}
+
+// Get the smart contract code from the contract state
+func (s contractState) code() (code string, err error) {
+	c, ok := s.vars["C"].(string)
+	if !ok || c == "" {
+		err = newError(ErrNotTELA, s.scid, "", "", fmt.Errorf("code is empty string"))
 		return
 	}
 
-	code = result.Code
+	code = decodeHexString(c)
 
 	return
 }
 
-// Transfer for executing TELA smart contract actions with DERO walletapi
-func transfer(wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments) (txid string, err error) {
-	if wallet == nil {
-		err = fmt.Errorf("no wallet for transfer")
+// Get the docCode of a TELA-DOC from the contract state, decoded if it was installed with an encoding
+func (s contractState) docCode() (docCode string, err error) {
+	code, err := s.code()
+	if err != nil {
 		return
 	}
 
+	docCode, err = parseDocCode(code)
+	if err != nil {
+		return
+	}
+
+	encoding, _ := s.value(HEADER_ENCODING.Trim())
+
+	return decodeDocCode(docCode, encoding)
+}
+
+// Keep ringsize within the range accepted for TELA transfers
+func clampRingsize(ringsize uint64) uint64 {
+	if ringsize < 2 {
+		return 2
+	} else if ringsize > 128 {
+		return 128
+	}
+
+	return ringsize
+}
+
+// Get the DERO transfer used for executing TELA smart contract actions on the current network
+func scTransfers() []rpc.Transfer {
 	network := "mainnet"
 	if b, ok := globals.Arguments["--testnet"].(bool); ok && b {
 		network = "testnet"
@@ -376,12 +694,6 @@ func transfer(wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments
 		}
 	}
 
-	if ringsize < 2 {
-		ringsize = 2
-	} else if ringsize > 128 {
-		ringsize = 128
-	}
-
 	// Initialize a DERO transfer
 	var dest string
 	switch network {
@@ -393,14 +705,17 @@ func transfer(wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments
 		dest = "dero1qykyta6ntpd27nl0yq4xtzaf4ls6p5e9pqu0k2x4x3pqq5xavjsdxqgny8270"
 	}
 
-	transfers := []rpc.Transfer{{Destination: dest, Amount: 0}}
+	return []rpc.Transfer{{Destination: dest, Amount: 0}}
+}
 
+// Estimate the gas for executing args with transfers from the daemon at endpoint, signer is only used with a ringsize of 2.
+// The estimate is never below MINIMUM_GAS_FEE
+func (t *TELA) estimateGas(ctx context.Context, endpoint, signer string, ringsize uint64, transfers []rpc.Transfer, args rpc.Arguments) (gas uint64, err error) {
 	var code string
 	if c, ok := args.Value(rpc.SCCODE, rpc.DataString).(string); ok {
 		code = c
 	}
 
-	// Get gas estimate for transfer
 	gasParams := rpc.GasEstimate_Params{
 		Transfers: transfers,
 		SC_Code:   code,
@@ -410,35 +725,54 @@ func transfer(wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments
 	}
 
 	if ringsize == 2 {
-		gasParams.Signer = wallet.GetAddress().String()
+		gasParams.Signer = signer
 	}
 
-	endpoint := walletapi.Daemon_Endpoint_Active
-	tela.client.WS, _, err = websocket.DefaultDialer.Dial("ws://"+endpoint+"/ws", nil)
+	gasResult, err := t.getDaemon().GetGasEstimate(ctx, endpoint, gasParams)
 	if err != nil {
-		err = fmt.Errorf("could not dial daemon endpoint %s: %s", endpoint, err)
+		err = fmt.Errorf("could not estimate install fees from %s: %s", endpoint, err)
 		return
 	}
 
-	input_output := rwc.New(tela.client.WS)
-	tela.client.RPC = jrpc2.NewClient(channel.RawJSON(input_output, input_output), nil)
+	gas = gasResult.GasStorage
+	if gas < MINIMUM_GAS_FEE {
+		gas = MINIMUM_GAS_FEE
+	}
 
-	var gasResult rpc.GasEstimate_Result
-	if err = tela.client.RPC.CallResult(context.Background(), "DERO.GetGasEstimate", gasParams, &gasResult); err != nil {
-		err = fmt.Errorf("could not estimate install fees: %s", err)
+	return
+}
+
+// Transfer for executing TELA smart contract actions with DERO walletapi
+func (t *TELA) transfer(ctx context.Context, wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments) (txid string, err error) {
+	if wallet == nil {
+		err = fmt.Errorf("no wallet for transfer")
+		return
+	}
+
+	ringsize = clampRingsize(ringsize)
+	transfers := scTransfers()
+
+	// Get gas estimate for transfer
+	gas, err := t.estimateGas(ctx, walletapi.Daemon_Endpoint_Active, wallet.GetAddress().String(), ringsize, transfers, args)
+	if err != nil {
 		return
 	}
 
-	if gasResult.GasStorage < MINIMUM_GAS_FEE {
-		gasResult.GasStorage = MINIMUM_GAS_FEE
+	if err = ctx.Err(); err != nil {
+		return
 	}
 
-	tx, err := wallet.TransferPayload0(transfers, ringsize, false, args, gasResult.GasStorage, false)
+	tx, err := wallet.TransferPayload0(transfers, ringsize, false, args, gas, false)
 	if err != nil {
 		err = fmt.Errorf("contract install build error: %s", err)
 		return
 	}
 
+	// Transaction is not sent if ctx is done while it was being built
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	if err = wallet.SendTransaction(tx); err != nil {
 		err = fmt.Errorf("contract install dispatch error: %s", err)
 		return
@@ -450,301 +784,535 @@ func transfer(wallet *walletapi.Wallet_Disk, ringsize uint64, args rpc.Arguments
 }
 
 // Clone a TELA-DOC scid to path from endpoint
-func cloneDOC(scid, docNum, path, endpoint string) (clone Cloning, err error) {
+func (t *TELA) cloneDOC(ctx context.Context, scid, docNum, path, endpoint string) (clone Cloning, err error) {
 	if len(scid) != 64 {
-		err = fmt.Errorf("invalid DOC SCID: %s", scid)
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid DOC SCID"))
 		return
 	}
 
-	var scCode string
-	scCode, err = getContractCode(scid, endpoint)
+	state, err := t.getContractState(ctx, scid, endpoint)
 	if err != nil {
-		err = fmt.Errorf("could not get SC code from %s: %s", scid, err)
+		err = fmt.Errorf("could not get SC code: %w", err)
 		return
 	}
 
-	_, err = EqualSmartContracts(TELA_DOC_1, scCode)
+	return t.cloneDOCFromState(state, docNum, path)
+}
+
+// TELA-DOC file parsed from its contract state
+type docFile struct {
+	scid     string
+	dURL     string
+	docType  string
+	name     string
+	subDir   string
+	code     string
+	part     uint64 // Part number if the file is chunked across multiple DOCs
+	parts    uint64 // Total parts of a chunked file
+	encoding string // docEncoding the code was installed with
+}
+
+// Parse and validate the file of a TELA-DOC from its contract state
+func docFromState(state contractState) (file docFile, err error) {
+	code, err := state.code()
 	if err != nil {
-		err = fmt.Errorf("scid does not parse as TELA-DOC-1: %s", err)
+		err = fmt.Errorf("could not get SC code: %w", err)
 		return
 	}
 
-	var docType string
-	docType, err = getContractVar(scid, HEADER_DOCTYPE.Trim(), endpoint)
+	_, err = EqualSmartContracts(TELA_DOC_1, code)
 	if err != nil {
-		err = fmt.Errorf("could not get docType from %s: %s", scid, err)
+		err = newError(ErrNotTELA, state.scid, "", "", fmt.Errorf("does not parse as TELA-DOC-1: %s", err))
 		return
 	}
 
-	var fileName string
-	fileName, err = getContractVar(scid, HEADER_NAME.Trim(), endpoint)
+	file, err = docHeadersFromState(state)
+	file.code = code
+
+	return
+}
+
+// Parse and validate the headers of a TELA-DOC file from its contract state, the file has no code
+func docHeadersFromState(state contractState) (file docFile, err error) {
+	file.scid = state.scid
+
+	// dURL is only used to describe any errors for this DOC
+	file.dURL, _ = state.value(HEADER_DURL.Trim())
+
+	file.docType, err = state.value(HEADER_DOCTYPE.Trim())
 	if err != nil {
-		err = fmt.Errorf("could not get nameHdr from %s", scid)
+		err = fmt.Errorf("could not get docType: %w", withContent(err, file.scid, file.dURL, ""))
 		return
 	}
 
-	// Set entrypoint DOC
-	isDOC1 := Header(docNum) == HEADER_DOCUMENT.Number(1)
-	if isDOC1 {
-		clone.Entrypoint = fileName
+	file.name, err = state.value(HEADER_NAME.Trim())
+	if err != nil {
+		err = fmt.Errorf("could not get nameHdr: %w", withContent(err, file.scid, file.dURL, ""))
+		return
+	}
+
+	if err = validatePathName(file.name); err != nil {
+		err = newError(ErrUnsafePath, file.scid, file.dURL, "", fmt.Errorf("nameHdr %s", err))
+		return
 	}
 
 	// Check if DOC is to be placed in subDir
-	var subDir string
-	subDir, err = getContractVar(scid, HEADER_SUBDIR.Trim(), endpoint)
-	if err != nil && !strings.Contains(err.Error(), "invalid string value for") { // only return on RPC error
-		err = fmt.Errorf("could not get subDir for %s: %s", fileName, err)
+	subDir, _ := state.value(HEADER_SUBDIR.Trim())
+	if file.subDir, err = cleanSubDir(subDir); err != nil {
+		err = newError(ErrUnsafePath, file.scid, file.dURL, "", fmt.Errorf("subDir %s", err))
+		return
+	}
+
+	if !IsAcceptedLanguage(file.docType) {
+		err = newError(ErrLanguage, file.scid, file.dURL, "", fmt.Errorf("%s for DOC %s", file.docType, file.name))
 		return
 	}
 
+	file.encoding, _ = state.value(HEADER_ENCODING.Trim())
+	switch file.encoding {
+	case "", DOC_ENCODING_GZIP, DOC_ENCODING_BASE64, DOC_ENCODING_ESCAPED:
+	default:
+		err = newError(ErrLanguage, file.scid, file.dURL, "", fmt.Errorf("docEncoding %s for DOC %s", file.encoding, file.name))
+		return
+	}
+
+	// Check if DOC is a part of a chunked file
+	if part, errr := state.value(HEADER_PART.Trim()); errr == nil {
+		var parts string
+		parts, err = state.value(HEADER_PARTS.Trim())
+		if err != nil {
+			err = fmt.Errorf("could not get docParts: %w", withContent(err, file.scid, file.dURL, ""))
+			return
+		}
+
+		file.part, _ = strconv.ParseUint(part, 10, 64)
+		file.parts, _ = strconv.ParseUint(parts, 10, 64)
+		if file.part < 1 || file.part > file.parts {
+			err = newError(ErrParts, file.scid, file.dURL, "", fmt.Errorf("invalid part %s of %s for %s", part, parts, file.name))
+			return
+		}
+	}
+
+	return
+}
+
+// Verify a DOC file's signature against its owner as required by the signature policy, result is nil if signatures are not verified
+func (t *TELA) verifyDOCFile(state contractState, file docFile) (result *DOCVerification, err error) {
+	policy := t.GetSignaturePolicy()
+	if policy == SIGNATURE_OFF {
+		return
+	}
+
+	var owner string
+	var signature Signature
+	owner, _ = state.value(HEADER_OWNER.Trim())
+	signature.CheckC, _ = state.value(HEADER_CHECK_C.Trim())
+	signature.CheckS, _ = state.value(HEADER_CHECK_S.Trim())
+
+	verification := verifyDOC(file.scid, file.name, owner, file.code, file.encoding, signature)
+	if !verification.Verified {
+		if policy == SIGNATURE_ENFORCE {
+			err = newError(ErrSignature, file.scid, file.dURL, "", fmt.Errorf("%s: %s", file.name, verification.Error))
+			return
+		}
+
+		logger.Warnf("[TELA] Could not verify signature for %s %s: %s\n", file.name, file.scid, verification.Error)
+	}
+
+	result = &verification
+
+	return
+}
+
+// Clone a TELA-DOC to path using its contract state
+func (t *TELA) cloneDOCFromState(state contractState, docNum, path string) (clone Cloning, err error) {
+	file, err := docFromState(state)
+	if err != nil {
+		return
+	}
+
+	// Parts are only cloned as the file they are joined into
+	if file.parts > 0 {
+		err = newError(ErrParts, file.scid, file.dURL, "", fmt.Errorf("%s is part %d of %d, clone the INDEX embedding all parts", file.name, file.part, file.parts))
+		return
+	}
+
+	// Verify DOC signature against its owner if required
+	result, err := t.verifyDOCFile(state, file)
+	if err != nil {
+		return
+	}
+
+	content, err := parseTELADoc(file.code, file.docType, file.encoding)
+	if err != nil {
+		err = fmt.Errorf("error saving %s: %s", file.name, err)
+		return
+	}
+
+	clone, err = saveDOCFile(file, content, Header(docNum) == HEADER_DOCUMENT.Number(1), path)
+	if err != nil {
+		return
+	}
+
+	if result != nil {
+		clone.Verifications = append(clone.Verifications, *result)
+	}
+
+	return
+}
+
+// Save the content of a DOC file to path, isDOC1 sets the file as the entrypoint
+func saveDOCFile(file docFile, content string, isDOC1 bool, path string) (clone Cloning, err error) {
+	// Set entrypoint DOC
+	if isDOC1 {
+		clone.Entrypoint = file.name
+	}
+
 	// If a valid subDir was decoded add it to path for this DOC
-	if subDir != "" {
+	if file.subDir != "" {
 		// Split all subDir to create path
-		split := strings.Split(subDir, "/")
+		split := strings.Split(file.subDir, "/")
 		for _, s := range split {
 			path = filepath.Join(path, s)
 		}
 
 		// If serving from subDir point to it
 		if isDOC1 {
-			clone.ServePath = fmt.Sprintf("/%s", subDir)
+			clone.ServePath = fmt.Sprintf("/%s", file.subDir)
 		}
 	}
 
-	filePath := filepath.Join(path, fileName)
+	filePath := filepath.Join(path, file.name)
 	if _, err = os.Stat(filePath); !os.IsNotExist(err) {
-		err = fmt.Errorf("file %s already exists", filePath)
+		err = newError(ErrFileExists, file.scid, file.dURL, "", fmt.Errorf("%s", filePath))
 		return
 	}
 
-	if !IsAcceptedLanguage(docType) {
-		err = fmt.Errorf("%s is not an accepted language for DOC %s", docType, fileName)
+	clone.files = map[string]servedDOC{filepath.ToSlash(filepath.Join(file.subDir, file.name)): {scid: file.scid, docType: file.docType}}
+
+	err = saveTELADoc(filePath, content)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			err = newError(ErrFileExists, file.scid, file.dURL, "", err)
+			return
+		}
+
+		err = fmt.Errorf("error saving %s: %s", file.name, err)
+		return
+	}
+
+	return
+}
+
+// Clone into a staging directory beside basePath and rename it to basePath when clone is successful.
+// Any existing content at basePath is not modified and the staging directory is removed if clone fails
+func stageClone(basePath string, clone func(stagePath string) error) (err error) {
+	if _, err = os.Stat(basePath); !os.IsNotExist(err) {
+		err = &fs.PathError{Op: "clone", Path: basePath, Err: fs.ErrExist}
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(basePath), os.ModePerm)
+	if err != nil {
 		return
 	}
 
-	err = parseAndSaveTELADoc(filePath, scCode, docType)
+	// Each clone has its own staging directory so clones of the same content do not collide
+	stagePath, err := os.MkdirTemp(filepath.Dir(basePath), fmt.Sprintf(".%s-*", filepath.Base(basePath)))
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			os.RemoveAll(stagePath)
+		}
+	}()
+
+	err = clone(stagePath)
 	if err != nil {
-		err = fmt.Errorf("error saving %s: %s", fileName, err)
 		return
 	}
 
+	err = os.Rename(stagePath, basePath)
+	if err != nil {
+		// Content was created at basePath by another clone
+		if _, errr := os.Stat(basePath); errr == nil {
+			err = &fs.PathError{Op: "clone", Path: basePath, Err: fs.ErrExist}
+		}
+	}
+
 	return
 }
 
 // Clone a TELA-INDEX SCID to path from endpoint creating all DOCs embedded within the INDEX
-func cloneINDEX(scid, path, endpoint string) (clone Cloning, err error) {
+func (t *TELA) cloneINDEX(ctx context.Context, scid, path, endpoint string) (clone Cloning, err error) {
 	if len(scid) != 64 {
-		err = fmt.Errorf("invalid INDEX SCID: %s", scid)
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
 		return
 	}
 
-	dURL, err := getContractVar(scid, HEADER_DURL.Trim(), endpoint)
+	state, err := t.getContractState(ctx, scid, endpoint)
 	if err != nil {
-		err = fmt.Errorf("could not get dURL from %s: %s", scid, err)
+		err = fmt.Errorf("could not get SC code: %w", err)
 		return
 	}
 
-	tagErr := fmt.Sprintf("cloning %s@%s was not successful:", dURL, scid)
+	return t.cloneINDEXFromState(ctx, state, path, endpoint)
+}
 
-	hash, err := getContractVar(scid, "hash", endpoint)
+// Clone a TELA-INDEX to path using its contract state, creating all DOCs embedded within the INDEX from endpoint.
+// The library dependency graph of the INDEX is resolved before any content is cloned
+func (t *TELA) cloneINDEXFromState(ctx context.Context, state contractState, path, endpoint string) (clone Cloning, err error) {
+	graph, err := t.resolveINDEX(ctx, state, endpoint, false)
 	if err != nil {
-		err = fmt.Errorf("%s could not get commit hash: %s", tagErr, err)
 		return
 	}
 
-	tagCommit := fmt.Sprintf("%s@%s", dURL, hash)
+	latest := graph.Commit
+	owner, _ := state.value(HEADER_OWNER.Trim())
+	rule := t.GetUpdateRule(graph.SCID, owner)
+	if !rule.allows(graph.SCID, latest, t.UpdatesAllowed()) {
+		// If the user does not want updated content
+		if rule.Policy == UPDATE_DEFAULT {
+			err = newError(ErrUpdated, graph.SCID, graph.DURL, latest, nil)
+			return
+		}
 
-	// If the user does not want updated content
-	if !tela.updates && scid != hash {
-		err = fmt.Errorf("%s user defined no updates and content has been updated to %s", tagErr, tagCommit)
-		return
+		// Use the pinned or approved commit in place of the latest
+		logger.Printf("[TELA] Using %s commit %s for %s\n", rule.Policy, rule.target(graph.SCID), graph.DURL)
+		graph, err = t.resolveINDEXAtCommit(ctx, graph.SCID, rule.target(graph.SCID), endpoint, false)
+		if err != nil {
+			return
+		}
 	}
 
-	code, err := getContractCode(scid, endpoint)
-	if err != nil {
-		err = fmt.Errorf("%s could not get SC code: %s", tagErr, err)
+	if err = t.checkLibraryUpdates(graph); err != nil {
 		return
 	}
 
-	// Only clone contracts matching TELA standard
-	sc, err := EqualSmartContracts(TELA_INDEX_1, code)
+	clone, err = t.cloneGraph(graph, path)
 	if err != nil {
-		err = fmt.Errorf("%s does not parse as TELA-INDEX-1: %s", tagErr, err)
 		return
 	}
 
-	// TELA-INDEX entrypoint, this will be nameHdr of DOC1
-	entrypoint := ""
-	// Path where file will be stored
-	basePath := filepath.Join(path, dURL)
-	// Path to entrypoint
-	servePath := ""
+	clone.Latest = latest
 
-	// Parse INDEX SC for valid DOCs
-	entrypoint, servePath, err = parseAndCloneINDEXForDOCs(sc, basePath, endpoint)
+	return
+}
+
+// Clone a resolved TELA-INDEX graph to path
+func (t *TELA) cloneGraph(graph *Dependency, path string) (clone Cloning, err error) {
+	// Path where files will be stored
+	basePath := filepath.Join(path, graph.DURL)
+
+	clone, err = t.cloneDependency(graph, basePath)
 	if err != nil {
-		// If all of the files were not cloned successfully, any residual files are removed if they did not exist already
-		err = fmt.Errorf("%s %s", tagErr, err)
-		if !strings.Contains(err.Error(), "already exists") {
-			os.RemoveAll(basePath)
-		}
 		return
 	}
 
-	clone.DURL = dURL
+	clone.DURL = graph.DURL
 	clone.BasePath = basePath
-	clone.ServePath = servePath
-	clone.Entrypoint = entrypoint
+	clone.Hash = graph.Commit
 
 	return
 }
 
 // Clone a TELA-INDEX SCID at commit TXID to path from endpoint creating all DOCs embedded within the INDEX at that commit
-func cloneINDEXAtCommit(scid, txid, path, endpoint string) (clone Cloning, err error) {
+func (t *TELA) cloneINDEXAtCommit(ctx context.Context, scid, txid, path, endpoint string) (clone Cloning, err error) {
+	graph, err := t.resolveINDEXAtCommit(ctx, scid, txid, endpoint, false)
+	if err != nil {
+		return
+	}
+
+	return t.cloneGraph(graph, path)
+}
+
+// Resolve the library dependency graph of a TELA-INDEX SCID at commit TXID from endpoint, libraries are resolved from their current state.
+// If headers is set only the treeKeys of DOCs are fetched
+func (t *TELA) resolveINDEXAtCommit(ctx context.Context, scid, txid, endpoint string, headers bool) (graph *Dependency, err error) {
 	if len(scid) != 64 {
-		err = fmt.Errorf("invalid INDEX SCID: %s", scid)
+		err = newError(ErrInvalidSCID, scid, "", txid, fmt.Errorf("invalid INDEX SCID"))
 		return
 	}
 
 	if len(txid) != 64 {
-		err = fmt.Errorf("invalid INDEX commit TXID: %s", txid)
+		err = newError(ErrInvalidCommit, scid, "", txid, nil)
 		return
 	}
 
-	dURL, err := getContractVar(scid, HEADER_DURL.Trim(), endpoint)
+	dURL, err := t.getContractVar(ctx, scid, HEADER_DURL.Trim(), endpoint)
 	if err != nil {
-		err = fmt.Errorf("could not get dURL from %s: %s", scid, err)
+		err = fmt.Errorf("could not get dURL: %w", withContent(err, scid, "", txid))
 		return
 	}
 
-	tagErr := fmt.Sprintf("cloning %s@%s was not successful:", dURL, txid)
+	if err = validatePathName(dURL); err != nil {
+		err = newError(ErrUnsafePath, scid, "", txid, fmt.Errorf("dURL %s", err))
+		return
+	}
 
-	txidAsHex, err := getTXID(txid, endpoint)
+	txidAsHex, err := t.getTXID(ctx, txid, endpoint)
 	if err != nil {
-		err = fmt.Errorf("%s could not get TXID: %s", tagErr, err)
+		err = fmt.Errorf("could not get TXID: %w", withContent(err, scid, dURL, txid))
 		return
 	}
 
 	code, err := extractCodeFromTXID(txidAsHex)
 	if err != nil {
-		err = fmt.Errorf("%s could not get SC code: %s", tagErr, err)
+		err = newError(ErrNotTELA, scid, dURL, txid, fmt.Errorf("could not get SC code: %s", err))
 		return
 	}
 
 	// Only clone contracts matching TELA standard
 	sc, err := EqualSmartContracts(TELA_INDEX_1, code)
 	if err != nil {
-		err = fmt.Errorf("%s does not parse as TELA-INDEX-1: %s", tagErr, err)
+		err = newError(ErrNotTELA, scid, dURL, txid, fmt.Errorf("does not parse as TELA-INDEX-1: %s", err))
 		return
 	}
 
-	// TELA-INDEX entrypoint, this will be nameHdr of DOC1
-	entrypoint := ""
-	// Path when file will be stored
-	basePath := filepath.Join(path, dURL)
-	// Path to entrypoint
-	servePath := ""
-
-	// Parse INDEX SC for valid DOCs
-	entrypoint, servePath, err = parseAndCloneINDEXForDOCs(sc, basePath, endpoint)
+	// Resolve libraries from their current state
+	graph = &Dependency{SCID: scid, DURL: dURL, Commit: txid}
+	err = t.resolveDependencies(ctx, graph, sc, endpoint, []string{scid}, headers)
 	if err != nil {
-		// If all of the files were not cloned successfully, any residual files are removed if they did not exist already
-		err = fmt.Errorf("%s %s", tagErr, err)
-		if !strings.Contains(err.Error(), "already exists") {
-			os.RemoveAll(basePath)
+		graph = nil
+	}
+
+	return
+}
+
+// Clone a TELA-INDEX SCID at commit TXID to path from endpoint if the commit is allowed by the SCID's update rule, the clone is pinned as its content will not change
+func (t *TELA) cloneAllowedCommit(ctx context.Context, scid, txid, path, endpoint string) (clone Cloning, err error) {
+	owner, _ := t.getContractVar(ctx, scid, HEADER_OWNER.Trim(), endpoint)
+	rule := t.GetUpdateRule(scid, owner)
+	if !rule.allows(scid, txid, t.UpdatesAllowed()) {
+		if rule.Policy == UPDATE_DEFAULT {
+			err = newError(ErrUpdated, scid, "", txid, fmt.Errorf("cannot serve at commit as AllowUpdates is set false"))
+		} else {
+			err = newError(ErrUpdated, scid, "", txid, fmt.Errorf("cannot serve at commit not allowed by %s update rule", rule.Policy))
 		}
 		return
 	}
 
-	clone.DURL = dURL
-	clone.BasePath = basePath
-	clone.ServePath = servePath
-	clone.Entrypoint = entrypoint
+	graph, err := t.resolveINDEXAtCommit(ctx, scid, txid, endpoint, false)
+	if err != nil {
+		return
+	}
+
+	if err = t.checkLibraryUpdates(graph); err != nil {
+		return
+	}
+
+	clone, err = t.cloneGraph(graph, path)
+	if err != nil {
+		return
+	}
+
+	clone.pinned = true
 
 	return
 }
 
 // Clone TELA content at SCID from endpoint
-func Clone(scid, endpoint string) (err error) {
+func (t *TELA) Clone(scid, endpoint string) (err error) {
+	return t.CloneContext(context.Background(), scid, endpoint)
+}
+
+// CloneContext is Clone using ctx for all daemon requests
+func (t *TELA) CloneContext(ctx context.Context, scid, endpoint string) (err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", nil)
+		return
+	}
+
 	var valid string
-	_, err = getContractVar(scid, HEADER_DOCTYPE.Trim(), endpoint)
-	if err == nil {
-		valid = "DOC"
+	state, err := t.getContractState(ctx, scid, endpoint)
+	if err != nil {
+		return
 	}
 
-	if valid == "" {
-		_, err = getContractVar(scid, HEADER_DOCUMENT.Number(1).Trim(), endpoint)
-		if err == nil {
-			valid = "INDEX"
-		}
+	if _, err = state.value(HEADER_DOCTYPE.Trim()); err == nil {
+		valid = "DOC"
+	} else if _, err = state.value(HEADER_DOCUMENT.Number(1).Trim()); err == nil {
+		valid = "INDEX"
 	}
 
-	path := tela.path.clone()
+	path := t.path.clone()
 
 	switch valid {
 	case "INDEX":
-		_, err = cloneINDEX(scid, path, endpoint)
+		_, err = t.cloneINDEXFromState(ctx, state, path, endpoint)
 	case "DOC":
 		// Store DOCs in respective dURL directories
-		dURL, errr := getContractVar(scid, HEADER_DURL.Trim(), endpoint)
+		dURL, errr := state.value(HEADER_DURL.Trim())
 		if errr != nil {
-			err = fmt.Errorf("could not get DOC dURL from %s: %s", scid, errr)
+			err = fmt.Errorf("could not get DOC dURL: %w", errr)
 			return
 		}
-		_, err = cloneDOC(scid, "", filepath.Join(path, dURL), endpoint)
+
+		if errr = validatePathName(dURL); errr != nil {
+			err = newError(ErrUnsafePath, scid, "", "", fmt.Errorf("dURL %s", errr))
+			return
+		}
+		_, err = t.cloneDOCFromState(state, "", filepath.Join(path, dURL))
 	default:
-		err = fmt.Errorf("could not validate %s as TELA INDEX or DOC", scid)
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("could not validate as TELA INDEX or DOC"))
 	}
 
 	return
 }
 
 // Clone a TELA-INDEX SC at a commit TXID from endpoint
-func CloneAtCommit(scid, txid, endpoint string) (err error) {
-	_, err = getContractVar(scid, HEADER_DOCUMENT.Number(1).Trim(), endpoint)
+func (t *TELA) CloneAtCommit(scid, txid, endpoint string) (err error) {
+	return t.CloneAtCommitContext(context.Background(), scid, txid, endpoint)
+}
+
+// CloneAtCommitContext is CloneAtCommit using ctx for all daemon requests
+func (t *TELA) CloneAtCommitContext(ctx context.Context, scid, txid, endpoint string) (err error) {
+	_, err = t.getContractVar(ctx, scid, HEADER_DOCUMENT.Number(1).Trim(), endpoint)
 	if err != nil {
+		if errors.Is(err, ErrMissingHeader) {
+			err = newError(ErrNotTELA, scid, "", txid, fmt.Errorf("could not validate as TELA INDEX"))
+		}
 		return
 	}
 
-	path := tela.path.clone()
+	path := t.path.clone()
 
-	_, err = cloneINDEXAtCommit(scid, txid, path, endpoint)
+	_, err = t.cloneINDEXAtCommit(ctx, scid, txid, path, endpoint)
 
 	return
 }
 
 // serveTELA serves cloned TELA content returning a link to the running TELA server if successful
-func serveTELA(scid string, clone Cloning) (link string, err error) {
+func (t *TELA) serveTELA(scid, endpoint string, clone Cloning) (link string, err error) {
 	if strings.HasSuffix(clone.DURL, TAG_LIBRARY) {
 		os.RemoveAll(clone.BasePath)
-		err = fmt.Errorf("%s is a library", clone.DURL)
+		err = newError(ErrLibrary, scid, clone.DURL, "", nil)
 		return
 	}
 
 	// INDEX and DOCs are valid, get ready to serve
-	server, found := FindOpenPort()
+	server, found := t.FindOpenPort()
 	if !found {
 		os.RemoveAll(clone.BasePath)
-		err = fmt.Errorf("could not find open port to serve %s", clone.DURL)
+		err = newError(ErrNoOpenPort, scid, clone.DURL, "", nil)
 		return
 	}
 
-	// Set the directory to serve files from
-	fs := http.FileServer(http.Dir(clone.BasePath))
-
 	// Handle all requests to server
-	server.Handler = fs
+	server.Handler = t.secureHandler(scid, endpoint, t.contentHandler(clone))
 
 	// Serve on this address:port
 	link = fmt.Sprintf("http://localhost%s/%s", server.Addr+clone.ServePath, clone.Entrypoint)
 
-	if tela.servers == nil {
-		tela.servers = make(map[ServerInfo]*http.Server)
+	if t.servers == nil {
+		t.servers = make(map[*ServerInfo]*http.Server)
 	}
 
 	// Add server to TELA
-	info := ServerInfo{Name: clone.DURL, Address: server.Addr, SCID: scid, Entrypoint: clone.Entrypoint}
-	tela.servers[info] = server
+	info := &ServerInfo{Name: clone.DURL, Address: server.Addr, SCID: scid, Entrypoint: clone.Entrypoint, Commit: clone.Hash, Verifications: clone.Verifications}
+	t.servers[info] = server
 
 	// Serve content
 	go func() {
@@ -764,42 +1332,54 @@ func serveTELA(scid string, clone Cloning) (link string, err error) {
 }
 
 // ServeTELA clones and serves a TELA-INDEX-1 SC from endpoint and returns a link to the running TELA server if successful
-func ServeTELA(scid, endpoint string) (link string, err error) {
-	tela.Lock()
-	defer tela.Unlock()
+func (t *TELA) ServeTELA(scid, endpoint string) (link string, err error) {
+	return t.ServeTELAContext(context.Background(), scid, endpoint)
+}
+
+// ServeTELAContext is ServeTELA using ctx for all daemon requests, TELA is locked until it returns
+func (t *TELA) ServeTELAContext(ctx context.Context, scid, endpoint string) (link string, err error) {
+	t.Lock()
+	defer t.Unlock()
 
-	clone, err := cloneINDEX(scid, tela.path.tela(), endpoint)
+	t.cleanup()
+
+	clone, err := t.cloneINDEX(ctx, scid, t.path.tela(), endpoint)
 	if err != nil {
-		os.RemoveAll(clone.BasePath)
 		return
 	}
 
-	return serveTELA(scid, clone)
+	return t.serveTELA(scid, endpoint, clone)
 }
 
 // ServeAtCommit clones and serves a TELA-INDEX-1 SC from endpoint at commit TXID if the SC code from that commit can be decoded,
-// ensure AllowUpdates is set true prior to calling ServeAtCommit otherwise it will return error
-func ServeAtCommit(scid, txid, endpoint string) (link string, err error) {
-	tela.Lock()
-	defer tela.Unlock()
+// the commit must be allowed by the SCID's update rule, with no rule set AllowUpdates must be true to serve any commit other than the original install
+func (t *TELA) ServeAtCommit(scid, txid, endpoint string) (link string, err error) {
+	return t.ServeAtCommitContext(context.Background(), scid, txid, endpoint)
+}
 
-	if !tela.updates {
-		err = fmt.Errorf("cannot serve %s at commit as AllowUpdates is set false", scid)
-		return
-	}
+// ServeAtCommitContext is ServeAtCommit using ctx for all daemon requests, TELA is locked until it returns
+func (t *TELA) ServeAtCommitContext(ctx context.Context, scid, txid, endpoint string) (link string, err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.cleanup()
 
-	clone, err := cloneINDEXAtCommit(scid, txid, tela.path.tela(), endpoint)
+	clone, err := t.cloneAllowedCommit(ctx, scid, txid, t.path.tela(), endpoint)
 	if err != nil {
-		os.RemoveAll(clone.BasePath)
 		return
 	}
 
-	return serveTELA(scid, clone)
+	return t.serveTELA(scid, endpoint, clone)
 }
 
 // OpenTELALink will open content from a telaLink formatted as tela://open/<scid>/subDir/../..
 // if no server exists for that content it will try starting one using ServeTELA()
-func OpenTELALink(telaLink, endpoint string) (link string, err error) {
+func (t *TELA) OpenTELALink(telaLink, endpoint string) (link string, err error) {
+	return t.OpenTELALinkContext(context.Background(), telaLink, endpoint)
+}
+
+// OpenTELALinkContext is OpenTELALink using ctx for all daemon requests
+func (t *TELA) OpenTELALinkContext(ctx context.Context, telaLink, endpoint string) (link string, err error) {
 	target, args, err := ParseTELALink(telaLink)
 	if err != nil {
 		err = fmt.Errorf("could not parse tela link: %s", err)
@@ -817,15 +1397,15 @@ func OpenTELALink(telaLink, endpoint string) (link string, err error) {
 	}
 
 	var exists bool
-	link, err = ServeTELA(args[1], endpoint)
+	link, err = t.ServeTELAContext(ctx, args[1], endpoint)
 	if err != nil {
-		if !strings.Contains(err.Error(), "already exists") {
-			err = fmt.Errorf("could not serve tela link: %s", err)
+		if !errors.Is(err, ErrFileExists) {
+			err = fmt.Errorf("could not serve tela link: %w", err)
 			return
 		}
 
 		// Find the server that already exists
-		for _, s := range GetServerInfo() {
+		for _, s := range t.GetServerInfo() {
 			if s.SCID == args[1] {
 				link = fmt.Sprintf("http://localhost%s", s.Address)
 				break
@@ -844,7 +1424,7 @@ func OpenTELALink(telaLink, endpoint string) (link string, err error) {
 	// TELA will serve with entrypoint if server did not exist
 	if !exists && len(args) > 2 {
 		var entrypoint string
-		for _, s := range GetServerInfo() {
+		for _, s := range t.GetServerInfo() {
 			if s.SCID == args[1] {
 				entrypoint = fmt.Sprintf("/%s", s.Entrypoint)
 				break
@@ -866,100 +1446,105 @@ func OpenTELALink(telaLink, endpoint string) (link string, err error) {
 	return
 }
 
-// ShutdownTELA shuts down all TELA servers and cleans up directory
-func ShutdownTELA() {
-	tela.Lock()
-	defer tela.Unlock()
+// ShutdownTELA shuts down all TELA servers and Handlers and cleans up directory
+func (t *TELA) ShutdownTELA() {
+	t.ShutdownGateway()
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.client.Close()
 
-	if tela.servers == nil {
+	// Handlers are closed with the servers
+	for h := range t.handlers {
+		h.close()
+	}
+
+	t.handlers = nil
+
+	if t.servers == nil {
 		return
 	}
 
 	logger.Printf("[TELA] Shutdown\n")
-	for i, s := range tela.servers {
+	for i, s := range t.servers {
 		err := s.Shutdown(context.Background())
 		if err != nil {
 			logger.Errorf("[TELA] Shutdown: %s\n", err)
 		}
-		tela.servers[i] = nil
+		t.servers[i] = nil
 	}
 
-	tela.servers = nil
-
-	if tela.client.WS != nil {
-		tela.client.WS.Close()
-		tela.client.WS = nil
-	}
-
-	if tela.client.RPC != nil {
-		tela.client.RPC.Close()
-		tela.client.RPC = nil
-	}
+	t.servers = nil
 
 	// All files removed when servers are shutdown
-	os.RemoveAll(tela.path.tela())
+	os.RemoveAll(t.path.tela())
 }
 
 // ShutdownTELA shuts down running TELA servers by name, if two servers with same name exist both will shutdown
-func ShutdownServer(name string) {
-	tela.Lock()
-	defer tela.Unlock()
+func (t *TELA) ShutdownServer(name string) {
+	t.Lock()
+	defer t.Unlock()
 
-	if tela.servers == nil {
+	if t.servers == nil {
 		return
 	}
 
 	logger.Printf("[TELA] Shutdown %s\n", name)
-	for i, s := range tela.servers {
+	for i, s := range t.servers {
 		if i.Name == name {
 			err := s.Shutdown(context.Background())
 			if err != nil {
 				logger.Errorf("[TELA] Shutdown: %s\n", err)
 			}
-			delete(tela.servers, i)
+			delete(t.servers, i)
 		}
 	}
 }
 
 // Get the current TELA datashard storage path
-func GetPath() string {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) GetPath() string {
+	t.RLock()
+	defer t.RUnlock()
 
-	return tela.path.tela()
+	return t.path.tela()
 }
 
 // SetShardPath can be used to set a custom path for TELA DOC storage,
 // TELA will remove all its files from the /tela directory when servers are Shutdown
-func SetShardPath(path string) (err error) {
-	tela.Lock()
-	if path, err = shards.SetPath(path); err == nil {
-		tela.path.main = path
+func (t *TELA) SetShardPath(path string) (err error) {
+	if _, err = os.Stat(filepath.Dir(path)); err != nil {
+		err = fmt.Errorf("%s does not exists", path)
+		return
 	}
-	tela.Unlock()
+
+	t.Lock()
+	t.path.main = filepath.Join(path, "datashards")
+	t.rules.setPath(t.path.main)
+	t.Unlock()
 
 	return
 }
 
 // Get running TELA server info
-func GetServerInfo() []ServerInfo {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) GetServerInfo() []ServerInfo {
+	t.RLock()
+	defer t.RUnlock()
 
-	servers := make([]ServerInfo, 0, len(tela.servers))
-	for info := range tela.servers {
-		servers = append(servers, info)
+	servers := make([]ServerInfo, 0, len(t.servers))
+	for info := range t.servers {
+		servers = append(servers, *info)
 	}
 
 	return servers
 }
 
 // Check if TELA has existing server by name
-func HasServer(name string) bool {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) HasServer(name string) bool {
+	t.RLock()
+	defer t.RUnlock()
 
-	for info := range tela.servers {
+	for info := range t.servers {
 		if strings.EqualFold(info.Name, name) {
 			return true
 		}
@@ -968,26 +1553,68 @@ func HasServer(name string) bool {
 }
 
 // AllowUpdates default is false and will not allow TELA content to be served that has been updated since its original install
-func AllowUpdates(b bool) {
-	tela.Lock()
-	tela.updates = b
-	tela.Unlock()
+func (t *TELA) AllowUpdates(b bool) {
+	t.updates.Store(b)
 }
 
 // Check if TELA server is allowed to serve TELA content that has been updated since its original install
-func UpdatesAllowed() bool {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) UpdatesAllowed() bool {
+	return t.updates.Load()
+}
 
-	return tela.updates
+// RenderMarkdown sets whether served TELA-MD-1 DOCs are rendered to HTML, raw markdown is served if false.
+// Raw markdown of a rendered DOC can be requested by adding ?raw to its URL
+func (t *TELA) RenderMarkdown(b bool) {
+	t.rawMD.Store(!b)
+}
+
+// MarkdownRendered checks if served TELA-MD-1 DOCs are rendered to HTML
+func (t *TELA) MarkdownRendered() bool {
+	return !t.rawMD.Load()
+}
+
+// Set the SignaturePolicy used to verify DOC signatures against their owner when cloning and serving TELA content
+func (t *TELA) SetSignaturePolicy(policy SignaturePolicy) (err error) {
+	switch policy {
+	case SIGNATURE_OFF, SIGNATURE_WARN, SIGNATURE_ENFORCE:
+		t.signatures.Store(uint32(policy))
+	default:
+		err = fmt.Errorf("invalid signature policy %d", policy)
+	}
+
+	return
+}
+
+// Get the current SignaturePolicy used when cloning and serving TELA content
+func (t *TELA) GetSignaturePolicy() SignaturePolicy {
+	return SignaturePolicy(t.signatures.Load())
+}
+
+// Set the Daemon TELA will use for chain data, if nil TELA will use its default RPC connection to endpoint
+func (t *TELA) SetDaemon(daemon Daemon) {
+	t.daemon.Lock()
+	if daemon == nil {
+		t.daemon.daemon = &t.client
+	} else {
+		t.daemon.daemon = daemon
+	}
+	t.daemon.Unlock()
+}
+
+// Get the Daemon TELA uses for chain data
+func (t *TELA) getDaemon() Daemon {
+	t.daemon.RLock()
+	defer t.daemon.RUnlock()
+
+	return t.daemon.daemon
 }
 
 // Set the initial port to start serving TELA content from if isValidPort
-func SetPortStart(port int) (err error) {
-	if isValidPort(port) {
-		tela.Lock()
-		tela.port = port
-		tela.Unlock()
+func (t *TELA) SetPortStart(port int) (err error) {
+	if t.isValidPort(port) {
+		t.Lock()
+		t.port = port
+		t.Unlock()
 	} else {
 		err = fmt.Errorf("invalid port %d", port)
 	}
@@ -996,33 +1623,52 @@ func SetPortStart(port int) (err error) {
 }
 
 // Check the initial port that TELA content will be served from
-func PortStart() int {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) PortStart() int {
+	t.RLock()
+	defer t.RUnlock()
 
-	return tela.port
+	return t.port
 }
 
 // Set the maximum amount of TELA servers which can be active
-func SetMaxServers(i int) {
-	tela.Lock()
-	max := DEFAULT_MAX_PORT - tela.port
+func (t *TELA) SetMaxServers(i int) {
+	t.Lock()
+	max := DEFAULT_MAX_PORT - t.port
 	if i < 1 {
-		tela.max = 1
+		t.max = 1
 	} else if i > max { // This would exceed all possible ports within serving range
-		tela.max = max
+		t.max = max
 	} else {
-		tela.max = i
+		t.max = i
 	}
-	tela.Unlock()
+	t.Unlock()
 }
 
 // Check the maximum amount of TELA servers
-func MaxServers() int {
-	tela.RLock()
-	defer tela.RUnlock()
+func (t *TELA) MaxServers() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.max
+}
 
-	return tela.max
+// Set the maximum amount of concurrent contract fetches used when cloning TELA content
+func (t *TELA) SetMaxWorkers(i int) {
+	t.Lock()
+	if i < 1 {
+		t.workers = 1
+	} else {
+		t.workers = i
+	}
+	t.Unlock()
+}
+
+// Check the maximum amount of concurrent contract fetches used when cloning TELA content
+func (t *TELA) MaxWorkers() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.workers
 }
 
 // Create arguments for INDEX or DOC SC install
@@ -1030,6 +1676,11 @@ func NewInstallArgs(params interface{}) (args rpc.Arguments, err error) {
 	var code string
 	switch h := params.(type) {
 	case *INDEX:
+		if err = validatePathName(h.DURL); err != nil {
+			err = fmt.Errorf("invalid dURL: %s", err)
+			return
+		}
+
 		code, err = ParseHeaders(TELA_INDEX_1, h)
 		if err != nil {
 			return
@@ -1041,12 +1692,47 @@ func NewInstallArgs(params interface{}) (args rpc.Arguments, err error) {
 			return
 		}
 	case *DOC:
-		code, err = ParseHeaders(TELA_DOC_1, h)
+		if h.DURL != "" {
+			if err = validatePathName(h.DURL); err != nil {
+				err = fmt.Errorf("invalid dURL: %s", err)
+				return
+			}
+		}
+
+		if err = validatePathName(h.NameHdr); err != nil {
+			err = fmt.Errorf("invalid nameHdr: %s", err)
+			return
+		}
+
+		if (h.Part > 0 || h.Parts > 0) && (h.Part < 1 || h.Part > h.Parts) {
+			err = fmt.Errorf("invalid part %d of %d parts", h.Part, h.Parts)
+			return
+		}
+
+		// Install the normalised subDir and encoded code
+		doc := *h
+		doc.SubDir, err = cleanSubDir(h.SubDir)
+		if err != nil {
+			err = fmt.Errorf("invalid subDir: %s", err)
+			return
+		}
+
+		if h.DocType != DOC_STATIC && IsBinaryCode(h.Code) {
+			err = fmt.Errorf("binary docCode must be installed as %s", DOC_STATIC)
+			return
+		}
+
+		doc.Code, doc.Encoding, err = encodeDocCode(h.Code, h.Encoding)
 		if err != nil {
 			return
 		}
 
-		code, err = appendDocCode(code, h.Code)
+		code, err = ParseHeaders(TELA_DOC_1, &doc)
+		if err != nil {
+			return
+		}
+
+		code, err = appendDocCode(code, doc.Code, h.Code)
 		if err != nil {
 			return
 		}
@@ -1065,7 +1751,12 @@ func NewInstallArgs(params interface{}) (args rpc.Arguments, err error) {
 }
 
 // Install TELA smart contracts with DERO walletapi
-func Installer(wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{}) (txid string, err error) {
+func (t *TELA) Installer(wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{}) (txid string, err error) {
+	return t.InstallerContext(context.Background(), wallet, ringsize, params)
+}
+
+// InstallerContext is Installer using ctx for the gas estimate, ctx is checked before the transfer is built and sent
+func (t *TELA) InstallerContext(ctx context.Context, wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{}) (txid string, err error) {
 	if wallet == nil {
 		err = fmt.Errorf("no wallet for TELA Installer")
 		return
@@ -1077,7 +1768,7 @@ func Installer(wallet *walletapi.Wallet_Disk, ringsize uint64, params interface{
 		return
 	}
 
-	return transfer(wallet, ringsize, args)
+	return t.transfer(ctx, wallet, ringsize, args)
 }
 
 // Create arguments for INDEX SC UpdateCode call
@@ -1085,6 +1776,11 @@ func NewUpdateArgs(params interface{}) (args rpc.Arguments, err error) {
 	var code, scid string
 	switch h := params.(type) {
 	case *INDEX:
+		if err = validatePathName(h.DURL); err != nil {
+			err = fmt.Errorf("invalid dURL: %s", err)
+			return
+		}
+
 		scid = h.SCID
 		code, err = ParseHeaders(TELA_INDEX_1, h)
 		if err != nil {
@@ -1107,7 +1803,12 @@ func NewUpdateArgs(params interface{}) (args rpc.Arguments, err error) {
 }
 
 // Update a TELA INDEX SC with DERO walletapi
-func Updater(wallet *walletapi.Wallet_Disk, params interface{}) (txid string, err error) {
+func (t *TELA) Updater(wallet *walletapi.Wallet_Disk, params interface{}) (txid string, err error) {
+	return t.UpdaterContext(context.Background(), wallet, params)
+}
+
+// UpdaterContext is Updater using ctx for the gas estimate, ctx is checked before the transfer is built and sent
+func (t *TELA) UpdaterContext(ctx context.Context, wallet *walletapi.Wallet_Disk, params interface{}) (txid string, err error) {
 	if wallet == nil {
 		err = fmt.Errorf("no wallet for TELA Updater")
 		return
@@ -1119,7 +1820,7 @@ func Updater(wallet *walletapi.Wallet_Disk, params interface{}) (txid string, er
 		return
 	}
 
-	return transfer(wallet, 2, args)
+	return t.transfer(ctx, wallet, 2, args)
 }
 
 // Create arguments for TELA Rate SC call
@@ -1142,7 +1843,12 @@ func NewRateArgs(scid string, rating uint64) (args rpc.Arguments, err error) {
 }
 
 // Rate a TELA SC positively (rating > 49) or negatively (rating < 50) with DERO walletapi
-func Rate(wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid string, err error) {
+func (t *TELA) Rate(wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid string, err error) {
+	return t.RateContext(context.Background(), wallet, scid, rating)
+}
+
+// RateContext is Rate using ctx for the gas estimate, ctx is checked before the transfer is built and sent
+func (t *TELA) RateContext(ctx context.Context, wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid string, err error) {
 	if wallet == nil {
 		err = fmt.Errorf("no wallet for TELA Rate")
 		return
@@ -1154,21 +1860,26 @@ func Rate(wallet *walletapi.Wallet_Disk, scid string, rating uint64) (txid strin
 		return
 	}
 
-	return transfer(wallet, 2, args)
+	return t.transfer(ctx, wallet, 2, args)
 }
 
 // Get the rating of a TELA scid from endpoint. Result is all individual ratings, likes and dislikes and the average rating category.
 // Using height will filter the individual ratings (including only >= height) this will not effect like and dislike results
-func GetRating(scid, endpoint string, height uint64) (ratings Rating_Result, err error) {
+func (t *TELA) GetRating(scid, endpoint string, height uint64) (ratings Rating_Result, err error) {
+	return t.GetRatingContext(context.Background(), scid, endpoint, height)
+}
+
+// GetRatingContext is GetRating using ctx for the daemon request
+func (t *TELA) GetRatingContext(ctx context.Context, scid, endpoint string, height uint64) (ratings Rating_Result, err error) {
 	var vars map[string]interface{}
-	vars, err = getContractVars(scid, endpoint)
+	vars, err = t.getContractVars(ctx, scid, endpoint)
 	if err != nil {
 		return
 	}
 
 	c, ok := vars["C"].(string)
 	if !ok {
-		err = fmt.Errorf("could not get TELA SC code for rating")
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("could not get TELA SC code for rating"))
 		return
 	}
 
@@ -1177,7 +1888,7 @@ func GetRating(scid, endpoint string, height uint64) (ratings Rating_Result, err
 	if err != nil {
 		_, err = EqualSmartContracts(TELA_DOC_1, code)
 		if err != nil {
-			err = fmt.Errorf("scid does not parse as a TELA SC: %s", err)
+			err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("does not parse as a TELA SC: %s", err))
 			return
 		}
 	}
@@ -1237,8 +1948,13 @@ func GetRating(scid, endpoint string, height uint64) (ratings Rating_Result, err
 }
 
 // Get TELA-DOC info from scid at endpoint
-func GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
-	vars, err := getContractVars(scid, endpoint)
+func (t *TELA) GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
+	return t.GetDOCInfoContext(context.Background(), scid, endpoint)
+}
+
+// GetDOCInfoContext is GetDOCInfo using ctx for the daemon request
+func (t *TELA) GetDOCInfoContext(ctx context.Context, scid, endpoint string) (doc DOC, err error) {
+	vars, err := t.getContractVars(ctx, scid, endpoint)
 	if err != nil {
 		return
 	}
@@ -1246,32 +1962,32 @@ func GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
 	// SC code, dURL and docType are required, otherwise values can be empty
 	c, ok := vars["C"].(string)
 	if !ok {
-		err = fmt.Errorf("could not get SC code from %s", scid)
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("could not get SC code"))
 		return
 	}
 
 	code := decodeHexString(c)
 	_, err = EqualSmartContracts(TELA_DOC_1, code)
 	if err != nil {
-		err = fmt.Errorf("scid does not parse as TELA-DOC-1: %s", err)
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("does not parse as TELA-DOC-1: %s", err))
 		return
 	}
 
 	dT, ok := vars[HEADER_DOCTYPE.Trim()].(string)
 	if !ok {
-		err = fmt.Errorf("could not get docType from %s", scid)
+		err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("could not get docType"))
 		return
 	}
 
 	docType := decodeHexString(dT)
 	if !IsAcceptedLanguage(docType) {
-		err = fmt.Errorf("could not validate docType %q", docType)
+		err = newError(ErrLanguage, scid, "", "", fmt.Errorf("could not validate docType %q", docType))
 		return
 	}
 
 	d, ok := vars[HEADER_DURL.Trim()].(string)
 	if !ok {
-		err = fmt.Errorf("could not get dURL from %s", scid)
+		err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("could not get dURL"))
 		return
 	}
 
@@ -1314,13 +2030,33 @@ func GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
 		checkS = decodeHexString(fS)
 	}
 
+	var encoding string
+	enc, ok := vars[HEADER_ENCODING.Trim()].(string)
+	if ok {
+		encoding = decodeHexString(enc)
+	}
+
+	var part, parts uint64
+	p, ok := vars[HEADER_PART.Trim()].(float64)
+	if ok {
+		part = uint64(p)
+	}
+
+	ps, ok := vars[HEADER_PARTS.Trim()].(float64)
+	if ok {
+		parts = uint64(ps)
+	}
+
 	doc = DOC{
-		DocType: docType,
-		Code:    code,
-		SubDir:  subDir,
-		SCID:    scid,
-		Author:  author,
-		DURL:    dURL,
+		DocType:  docType,
+		Code:     code,
+		SubDir:   subDir,
+		SCID:     scid,
+		Author:   author,
+		DURL:     dURL,
+		Part:     part,
+		Parts:    parts,
+		Encoding: encoding,
 		Signature: Signature{
 			CheckC: checkC,
 			CheckS: checkS,
@@ -1336,8 +2072,13 @@ func GetDOCInfo(scid, endpoint string) (doc DOC, err error) {
 }
 
 // Get TELA-INDEX info from scid at endpoint
-func GetINDEXInfo(scid, endpoint string) (index INDEX, err error) {
-	vars, err := getContractVars(scid, endpoint)
+func (t *TELA) GetINDEXInfo(scid, endpoint string) (index INDEX, err error) {
+	return t.GetINDEXInfoContext(context.Background(), scid, endpoint)
+}
+
+// GetINDEXInfoContext is GetINDEXInfo using ctx for the daemon request
+func (t *TELA) GetINDEXInfoContext(ctx context.Context, scid, endpoint string) (index INDEX, err error) {
+	vars, err := t.getContractVars(ctx, scid, endpoint)
 	if err != nil {
 		return
 	}
@@ -1345,20 +2086,20 @@ func GetINDEXInfo(scid, endpoint string) (index INDEX, err error) {
 	// SC code and dURL are required, otherwise values can be empty
 	c, ok := vars["C"].(string)
 	if !ok {
-		err = fmt.Errorf("could not get SC code from %s", scid)
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("could not get SC code"))
 		return
 	}
 
 	code := decodeHexString(c)
 	_, err = EqualSmartContracts(TELA_INDEX_1, code)
 	if err != nil {
-		err = fmt.Errorf("scid does not parse as TELA-INDEX-1: %s", err)
+		err = newError(ErrNotTELA, scid, "", "", fmt.Errorf("does not parse as TELA-INDEX-1: %s", err))
 		return
 	}
 
 	d, ok := vars[HEADER_DURL.Trim()].(string)
 	if !ok {
-		err = fmt.Errorf("could not get dURL from %s", scid)
+		err = newError(ErrMissingHeader, scid, "", "", fmt.Errorf("could not get dURL"))
 		return
 	}
 
@@ -1,20 +1,34 @@
 package tela
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
+	"unicode/utf8"
 
 	"github.com/civilware/tela/logger"
 	"github.com/civilware/tela/shards"
 	"github.com/deroproject/derohe/cryptography/crypto"
 	"github.com/deroproject/derohe/dvm"
 	"github.com/deroproject/derohe/globals"
+	"github.com/deroproject/derohe/rpc"
 	"github.com/deroproject/derohe/walletapi"
 	"github.com/stretchr/testify/assert"
 )
@@ -406,7 +420,7 @@ func TestTELA(t *testing.T) {
 				assert.NoError(t, err, "Install %d %s should not error: %s", i, doc.NameHdr, err)
 
 				_, err = retry(t, fmt.Sprintf("confirming install TX %s", tx), func() (string, error) {
-					return getContractCode(tx, endpoint)
+					return tela.getContractCode(context.Background(), tx, endpoint)
 				})
 				if err != nil {
 					t.Fatalf("Could not confirm DOC %d TX %s: %s", i, tx, err)
@@ -464,7 +478,7 @@ func TestTELA(t *testing.T) {
 				assert.NoError(t, err, "Install %d %s should not have error: %s", i, app.NameHdr, err)
 
 				_, err = retry(t, fmt.Sprintf("confirming install TX %s", tx), func() (string, error) {
-					return getContractCode(tx, endpoint)
+					return tela.getContractCode(context.Background(), tx, endpoint)
 				})
 				if err != nil {
 					t.Fatalf("Could not confirm INDEX %d TX %s: %s", i, tx, err)
@@ -531,7 +545,7 @@ func TestTELA(t *testing.T) {
 			t.Logf("Simulator INDEX 8 SC installed %s: %s", indexLimits.DURL, tx)
 
 			v, err := retry(t, "confirming contract size limit install", func() (string, error) {
-				return getContractVar(tx, "likes", endpoint)
+				return tela.getContractVar(context.Background(), tx, "likes", endpoint)
 			})
 			assert.NoError(t, err, "Confirming INDEX install at max size limit install should not error: %s", err)
 			assert.NotEmpty(t, v, "Likes value should not be empty")
@@ -571,6 +585,9 @@ func TestTELA(t *testing.T) {
 		// If another port in range is in use these will fail
 		assert.Equal(t, max, len(tela.servers), "All valid SCID should have been served")
 		assert.Equal(t, max, len(GetServerInfo()), "All valid SCID should have info")
+		for _, info := range GetServerInfo() {
+			assert.NotEmpty(t, info.Verifications, "Served DOCs should have signature verification results")
+		}
 
 		assert.True(t, HasServer("test1.tela"), "This server name should be present and is not")
 		assert.False(t, HasServer("none"), "This server name should not be present and is")
@@ -693,7 +710,7 @@ func TestTELA(t *testing.T) {
 			noCodeTXIDs = append(noCodeTXIDs, txid)
 
 			v, err := retry(t, fmt.Sprintf("confirming down %d rating", i), func() (string, error) {
-				return getContractVar(validSCIDs[i], wallets[i].GetAddress().String(), endpoint)
+				return tela.getContractVar(context.Background(), validSCIDs[i], wallets[i].GetAddress().String(), endpoint)
 			})
 			assert.NoError(t, err, "Getting rating variable should not error: %s", err)
 			assert.NotEmpty(t, v, "Value should not be empty")
@@ -715,7 +732,7 @@ func TestTELA(t *testing.T) {
 			time.Sleep(sleepFor)
 
 			v, err := retry(t, fmt.Sprintf("confirming up %d rating", i), func() (string, error) {
-				return getContractVar(validSCIDs[i], wallets[i+offset].GetAddress().String(), endpoint)
+				return tela.getContractVar(context.Background(), validSCIDs[i], wallets[i+offset].GetAddress().String(), endpoint)
 			})
 			assert.NoError(t, err, "Getting rating variable should not error: %s", err)
 			assert.NotEmpty(t, v, "Value should not be empty")
@@ -772,7 +789,7 @@ func TestTELA(t *testing.T) {
 			assert.NoError(t, err, "Installing INDEX %d library should not have error: %s", i, err)
 
 			_, err = retry(t, fmt.Sprintf("confirming install TX %s", tx), func() (string, error) {
-				return getContractCode(tx, endpoint)
+				return tela.getContractCode(context.Background(), tx, endpoint)
 			})
 			if err != nil {
 				t.Fatalf("Could not confirm INDEX %d library TX %s: %s", i, tx, err)
@@ -839,7 +856,7 @@ func TestTELA(t *testing.T) {
 			assert.NoError(t, err, "Installing INDEX %d embed should not have error: %s", i, err)
 
 			_, err = retry(t, fmt.Sprintf("confirming install TX %s", tx), func() (string, error) {
-				return getContractCode(tx, endpoint)
+				return tela.getContractCode(context.Background(), tx, endpoint)
 			})
 			if err != nil {
 				t.Fatalf("Could not confirm INDEX %d embed TX %s: %s", i, tx, err)
@@ -880,36 +897,51 @@ func TestTELA(t *testing.T) {
 		assert.False(t, IsAcceptedLanguage("TELA-html-1"), "Language should be case sensitive")
 
 		// Parse empty document with no multiline comment
-		assert.Error(t, parseAndSaveTELADoc("", "", ""), "Should not be able to parse this document")
+		assert.Error(t, parseAndSaveTELADoc("", "", "", ""), "Should not be able to parse this document")
 		// Parse invalid docType
-		assert.Error(t, parseAndSaveTELADoc("", "/*\n*/", "invalid"), "DocType should be invalid")
+		assert.Error(t, parseAndSaveTELADoc("", "/*\n*/", "invalid", ""), "DocType should be invalid")
 		// Save to invalid path
-		assert.Error(t, parseAndSaveTELADoc(filepath.Join(testPath, "app2", "index.html", "filename.html"), TELA_DOC_1, DOC_HTML), "Path should be invalid")
+		assert.Error(t, parseAndSaveTELADoc(filepath.Join(testPath, "app2", "index.html", "filename.html"), TELA_DOC_1, DOC_HTML, ""), "Path should be invalid")
 		// Parse types not installed in tests
-		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "json.json"), TELA_DOC_1, DOC_JSON), "DOC_JSON should be valid")
-		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "markdown.md"), TELA_DOC_1, DOC_MD), "DOC_MD should be valid")
-		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "other.jsx"), TELA_DOC_1, DOC_STATIC), "DOC_STATIC should be valid")
+		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "json.json"), TELA_DOC_1, DOC_JSON, ""), "DOC_JSON should be valid")
+		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "markdown.md"), TELA_DOC_1, DOC_MD, ""), "DOC_MD should be valid")
+		assert.NoError(t, parseAndSaveTELADoc(filepath.Join(datashards, "other.jsx"), TELA_DOC_1, DOC_STATIC, ""), "DOC_STATIC should be valid")
 
 		// decodeHexString return non hex
 		expectedAddress := "deto1qy87ghfeeh6n6tdxtgh7yuvtp6wh2uwfzvz7vjq0krjy4smmx62j5qgqht7t3"
 		assert.Equal(t, expectedAddress, decodeHexString(expectedAddress), "decodeHexString should return a DERO address when passed")
 
 		// Test cloneDOC
-		_, err := cloneDOC(scDoesNotExist, "", "", endpoint)
+		_, err := tela.cloneDOC(context.Background(), scDoesNotExist, "", "", endpoint)
 		assert.Error(t, err, "cloneDOC should error with invalid scid")
-		_, err = cloneDOC(nameservice, "", "", endpoint)
+		_, err = tela.cloneDOC(context.Background(), nameservice, "", "", endpoint)
 		assert.Error(t, err, "cloneDOC with NON TELA should error")
 
+		// Test daemon client is reused, redialed after failure and closed on shutdown
+		_, err = tela.getContractVar(context.Background(), validSCIDs[0], HEADER_DURL.Trim(), endpoint)
+		assert.NoError(t, err, "Getting dURL should not error: %s", err)
+		client := tela.client.rpc
+		assert.NotNil(t, client, "Daemon client should be connected")
+		_, err = tela.getContractCode(context.Background(), validSCIDs[0], endpoint)
+		assert.NoError(t, err, "Getting code should not error: %s", err)
+		assert.Equal(t, client, tela.client.rpc, "Daemon client should have been reused")
+		tela.client.ws.Close() // Drop the connection
+		_, err = tela.getContractVars(context.Background(), validSCIDs[0], endpoint)
+		assert.NoError(t, err, "Getting vars should not error after reconnect: %s", err)
+		assert.NotEqual(t, client, tela.client.rpc, "Daemon client should have reconnected")
+		ShutdownTELA()
+		assert.Nil(t, tela.client.rpc, "Daemon client should be closed after shutdown")
+
 		// Test getTXID
-		_, err = getTXID(scDoesNotExist, endpoint)
+		_, err = tela.getTXID(context.Background(), scDoesNotExist, endpoint)
 		assert.Error(t, err, "Getting invalid TX should error")
-		_, err = getTXID(scDoesNotExist, "")
+		_, err = tela.getTXID(context.Background(), scDoesNotExist, "")
 		assert.Error(t, err, "Getting invalid TX with invalid endpoint should error")
 
 		// Test cloneINDEXAtCommit
-		_, err = cloneINDEXAtCommit("", "", tela.path.clone(), endpoint) // invalid scid
+		_, err = tela.cloneINDEXAtCommit(context.Background(), "", "", tela.path.clone(), endpoint) // invalid scid
 		assert.Error(t, err, "cloneINDEXAtCommit with invalid SCID should error")
-		_, err = cloneINDEXAtCommit(nameservice, commitTXIDs[0], tela.path.clone(), endpoint) // NON TELA
+		_, err = tela.cloneINDEXAtCommit(context.Background(), nameservice, commitTXIDs[0], tela.path.clone(), endpoint) // NON TELA
 		assert.Error(t, err, "cloneINDEXAtCommit with NON TELA should error")
 
 		// Test extractCodeFromTXID
@@ -989,6 +1021,23 @@ func TestTELA(t *testing.T) {
 		assert.Error(t, err, "Set port should error setting to %d", setTo)
 		assert.Equal(t, DEFAULT_PORT_START, PortStart(), "Port start should be equal")
 
+		// Test SetSignaturePolicy
+		assert.Equal(t, SIGNATURE_WARN, GetSignaturePolicy(), "Signature policy should be warn by default")
+		err = SetSignaturePolicy(SIGNATURE_ENFORCE)
+		assert.NoError(t, err, "Setting valid signature policy should not error: %s", err)
+		assert.Equal(t, SIGNATURE_ENFORCE, GetSignaturePolicy(), "Signature policy should be enforce")
+		err = Clone(validSCIDs[2], endpoint)
+		assert.Error(t, err, "Cloning INDEX with unsigned DOCs should error when signatures are enforced")
+		err = SetSignaturePolicy(SignaturePolicy(99))
+		assert.Error(t, err, "Setting invalid signature policy should error")
+		assert.Equal(t, SIGNATURE_ENFORCE, GetSignaturePolicy(), "Signature policy should have remained as enforce")
+		policy, err := ParseSignaturePolicy("off")
+		assert.NoError(t, err, "Parsing valid signature policy should not error: %s", err)
+		assert.Equal(t, "off", policy.String(), "Signature policy string should be equal")
+		_, err = ParseSignaturePolicy("invalid")
+		assert.Error(t, err, "Parsing invalid signature policy should error")
+		SetSignaturePolicy(SIGNATURE_WARN)
+
 		// Test SetMaxServers
 		setTo = 30
 		SetMaxServers(setTo)
@@ -1092,6 +1141,33 @@ func TestTELA(t *testing.T) {
 		_, _, _, err = ParseSignature([]byte(fmt.Sprintf("%s\n%s\n%s\n%s\n\n%s", signatureHeader, signatureAddress, signatureCheckC, "S: string", signatureFooter)))
 		assert.EqualError(t, fmt.Errorf("unknown S format"), err.Error(), "Parse signature should error with invalid S and did not")
 
+		// Test CheckSignature and verifyDOC
+		otherAddress := "deto1qy5afru8r3rryk357gh002l77yssljsh3x6drrq2c3acf2u4w63zjqg7sqz9t"
+		_, c, s, _ := ParseSignature(signature)
+		err = CheckSignature(thisAddress, Signature{CheckC: c, CheckS: s}, []byte("some data"))
+		assert.NoError(t, err, "Check signature should not error: %s", err)
+		err = CheckSignature(thisAddress, Signature{CheckC: c, CheckS: s}, []byte("other data"))
+		assert.Error(t, err, "Check signature should error with different data")
+		err = CheckSignature(otherAddress, Signature{CheckC: c, CheckS: s}, []byte("some data"))
+		assert.Error(t, err, "Check signature should error with different address")
+		err = CheckSignature("deto1qy", Signature{CheckC: c, CheckS: s}, []byte("some data"))
+		assert.Error(t, err, "Check signature should error with invalid address")
+		err = CheckSignature(thisAddress, Signature{CheckC: "string", CheckS: s}, []byte("some data"))
+		assert.Error(t, err, "Check signature should error with invalid C")
+		err = CheckSignature(thisAddress, Signature{CheckC: c, CheckS: "string"}, []byte("some data"))
+		assert.Error(t, err, "Check signature should error with invalid S")
+
+		docData := "<html>\n</html>\n"
+		_, c, s, _ = ParseSignature(wallets[0].SignData([]byte(docData)))
+		signedDOC := &DOC{DocType: DOC_HTML, Code: docData, DURL: "signed.tela", Signature: Signature{CheckC: c, CheckS: s}, Headers: Headers{NameHdr: "index.html"}}
+		installArgs, err := NewInstallArgs(signedDOC)
+		assert.NoError(t, err, "Creating signed DOC install args should not error: %s", err)
+		signedCode := installArgs.Value(rpc.SCCODE, rpc.DataString).(string)
+		assert.True(t, verifyDOC("", "index.html", thisAddress, signedCode, "", signedDOC.Signature).Verified, "Signed DOC should verify")
+		assert.False(t, verifyDOC("", "index.html", otherAddress, signedCode, "", signedDOC.Signature).Verified, "Signed DOC should not verify with different owner")
+		assert.False(t, verifyDOC("", "index.html", "anon", signedCode, "", signedDOC.Signature).Verified, "Signed DOC should not verify with anon owner")
+		assert.False(t, verifyDOC("", "index.html", thisAddress, TELA_INDEX_1, "", signedDOC.Signature).Verified, "Signed DOC should not verify with no docCode")
+
 		// Parse structures outside of standard contracts
 		_, err = ParseHeaders(TELA_INDEX_1, map[Header]interface{}{HEADER_COVER_URL: "cover", HEADER_FILE_URL: "file", HEADER_ROYALTY: 1})
 		assert.NoError(t, err, "map[Header]interface{} should be valid: %s", err)
@@ -1194,9 +1270,10 @@ func TestTELA(t *testing.T) {
 		docCode := strings.Repeat(dvmCode, codeSizeToBig/len(dvmCode))
 
 		doc := &DOC{
-			DocType: "TELA-HTML-1",
-			Code:    docCode,
-			DURL:    "error.tela",
+			DocType:  "TELA-HTML-1",
+			Code:     docCode,
+			DURL:     "error.tela",
+			Encoding: DOC_ENCODING_NONE,
 			Signature: Signature{
 				CheckC: "1c37f9e61f15a9526ba680dce0baa567e642ca2cd0ddea71649dab415dad8cb2",
 				CheckS: "1c37f9e61f15a9526ba680dce0baa567e642ca2cd0ddea71649dab415dad8cb2",
@@ -1275,9 +1352,9 @@ func TestTELA(t *testing.T) {
 		// Invalid daemon address
 		_, err := ServeTELA(validSCIDs[0], "")
 		assert.Error(t, err, "Daemon address on getContractVar should not have connected")
-		_, err = getContractCode(validSCIDs[0], "")
+		_, err = tela.getContractCode(context.Background(), validSCIDs[0], "")
 		assert.Error(t, err, "Daemon address on getContractCode should not have connected")
-		_, err = getContractVars(validSCIDs[0], "")
+		_, err = tela.getContractVars(context.Background(), validSCIDs[0], "")
 		assert.Error(t, err, "Daemon address on getContractVars should not have connected")
 
 		// No servers should be started on errors
@@ -1291,10 +1368,2439 @@ func TestTELA(t *testing.T) {
 		// Reset testnet flag for nil/network/ringsize cases and daemon transfer error
 		globals.Arguments["--testnet"] = false
 		walletapi.Daemon_Endpoint_Active = ""
-		transfer(nil, 0, nil)
-		transfer(wallets[0], 0, nil)
-		transfer(wallets[0], 256, nil)
+		tela.transfer(context.Background(), nil, 0, nil)
+		tela.transfer(context.Background(), wallets[0], 0, nil)
+		tela.transfer(context.Background(), wallets[0], 256, nil)
+	})
+}
+
+// Test TELA offline using a MemoryDaemon
+func TestMemoryDaemon(t *testing.T) {
+	testPath := filepath.Join(mainPath, testDir)
+	walletPath := filepath.Join(testPath, "tela_memory_wallets")
+	datashards := filepath.Join(testPath, "datashards")
+
+	err := SetShardPath(testPath)
+	if err != nil {
+		t.Fatalf("Could not set test directory: %s", err)
+	}
+
+	memory := NewMemoryDaemon()
+	SetDaemon(memory)
+
+	t.Cleanup(func() {
+		ShutdownTELA()
+		SetDaemon(nil)
+		os.RemoveAll(datashards)
+		os.RemoveAll(walletPath)
+	})
+
+	os.RemoveAll(datashards)
+	os.RemoveAll(walletPath)
+
+	globals.Arguments["--testnet"] = true
+	globals.InitNetwork()
+
+	wallet, err := createTestWallet("tela_memory", walletPath, walletSeeds[0])
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %s", err)
+	}
+
+	owner := wallet.GetAddress().String()
+	endpoint := "memory"
+
+	// Add DOCs signed by owner to the MemoryDaemon
+	var docSCIDs []string
+	for i, doc := range telaDocs[:3] {
+		code, err := readFile(doc.filePath)
+		if err != nil {
+			t.Fatalf("Could not read %s file: %s", doc.NameHdr, err)
+		}
+
+		_, doc.CheckC, doc.CheckS, err = ParseSignature(wallet.SignData([]byte(code)))
+		if err != nil {
+			t.Fatalf("Could not sign %s: %s", doc.NameHdr, err)
+		}
+
+		doc.Code = code
+		scid := fmt.Sprintf("%064x", i+1)
+		err = addMemoryDOC(memory, scid, owner, doc.DOC)
+		if err != nil {
+			t.Fatalf("Could not add DOC %s: %s", doc.NameHdr, err)
+		}
+
+		docSCIDs = append(docSCIDs, scid)
+	}
+
+	index := INDEX{
+		DURL: "memory.tela",
+		DOCs: docSCIDs,
+		Headers: Headers{
+			NameHdr:  "TELA Memory App",
+			DescrHdr: "TELA test app served from a MemoryDaemon",
+			IconHdr:  "icon.url",
+		},
+	}
+
+	indexSCID := fmt.Sprintf("%064x", 100)
+	err = addMemoryINDEX(memory, indexSCID, owner, index)
+	if err != nil {
+		t.Fatalf("Could not add INDEX: %s", err)
+	}
+
+	t.Run("GetSC", func(t *testing.T) {
+		code, err := tela.getContractCode(context.Background(), indexSCID, endpoint)
+		assert.NoError(t, err, "Getting INDEX code should not error: %s", err)
+		_, err = EqualSmartContracts(TELA_INDEX_1, code)
+		assert.NoError(t, err, "INDEX code should parse as TELA-INDEX-1: %s", err)
+
+		dURL, err := tela.getContractVar(context.Background(), indexSCID, HEADER_DURL.Trim(), endpoint)
+		assert.NoError(t, err, "Getting dURL should not error: %s", err)
+		assert.Equal(t, index.DURL, dURL, "dURL should be decoded")
+
+		likes, err := tela.getContractVar(context.Background(), indexSCID, "likes", endpoint)
+		assert.NoError(t, err, "Getting likes should not error: %s", err)
+		assert.Equal(t, "0", likes, "Likes should not be decoded")
+
+		_, err = tela.getContractVar(context.Background(), indexSCID, "none", endpoint)
+		assert.Error(t, err, "Getting key that does not exist should error")
+		_, err = tela.getContractCode(context.Background(), scDoesNotExist, endpoint)
+		assert.Error(t, err, "Getting code of SCID that does not exist should error")
+		_, err = tela.getTXID(context.Background(), scDoesNotExist, endpoint)
+		assert.Error(t, err, "Getting TXID that does not exist should error")
+
+		err = memory.AddSC("scid", "", nil)
+		assert.Error(t, err, "Adding invalid SCID should error")
+		err = memory.AddSC(scDoesNotExist, "", map[string]interface{}{"key": 1.5})
+		assert.Error(t, err, "Adding invalid value type should error")
+		err = memory.StoreString(scDoesNotExist, "key", "value")
+		assert.Error(t, err, "Storing on SCID that does not exist should error")
+	})
+
+	t.Run("Info", func(t *testing.T) {
+		doc, err := GetDOCInfo(docSCIDs[0], endpoint)
+		assert.NoError(t, err, "GetDOCInfo should not error: %s", err)
+		assert.Equal(t, owner, doc.Author, "DOC author should be owner")
+		assert.Equal(t, telaDocs[0].NameHdr, doc.NameHdr, "DOC nameHdr should be equal")
+		assert.Equal(t, telaDocs[0].DocType, doc.DocType, "DOC docType should be equal")
+		assert.NotEmpty(t, doc.CheckC, "DOC should have signature")
+
+		_, err = GetDOCInfo(indexSCID, endpoint)
+		assert.Error(t, err, "GetDOCInfo on INDEX should error")
+
+		info, err := GetINDEXInfo(indexSCID, endpoint)
+		assert.NoError(t, err, "GetINDEXInfo should not error: %s", err)
+		assert.Equal(t, owner, info.Author, "INDEX author should be owner")
+		assert.Equal(t, index.DURL, info.DURL, "INDEX dURL should be equal")
+		assert.Equal(t, docSCIDs, info.DOCs, "INDEX DOCs should be equal")
+
+		_, err = GetINDEXInfo(docSCIDs[0], endpoint)
+		assert.Error(t, err, "GetINDEXInfo on DOC should error")
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		// Each contract state should only be requested once when cloning
+		counter := &countingDaemon{MemoryDaemon: memory, calls: map[string]int{}, variables: map[string]int{}}
+		SetDaemon(counter)
+		err := Clone(indexSCID, endpoint)
+		SetDaemon(memory)
+		assert.NoError(t, err, "Cloning INDEX should not error: %s", err)
+		for _, doc := range telaDocs[:3] {
+			_, err = os.Stat(filepath.Join(datashards, "clone", index.DURL, doc.NameHdr))
+			assert.NoError(t, err, "Cloned file %s should exist: %s", doc.NameHdr, err)
+		}
+
+		for _, scid := range append([]string{indexSCID}, docSCIDs...) {
+			assert.Equal(t, 1, counter.calls[scid], "SCID %s should have one GetSC call", scid)
+		}
+
+		err = Clone(docSCIDs[0], endpoint)
+		assert.NoError(t, err, "Cloning DOC should not error: %s", err)
+		_, err = os.Stat(filepath.Join(datashards, "clone", telaDocs[0].DURL, telaDocs[0].NameHdr))
+		assert.NoError(t, err, "Cloned file %s should exist: %s", telaDocs[0].NameHdr, err)
+
+		err = Clone(docSCIDs[0], endpoint)
+		assert.Error(t, err, "Cloning DOC that already exists should error")
+
+		err = Clone(scDoesNotExist, endpoint)
+		assert.Error(t, err, "Cloning SCID that does not exist should error")
+
+		// Results should be the same for any amount of workers
+		defer SetMaxWorkers(DEFAULT_MAX_WORKERS)
+		SetMaxWorkers(0)
+		assert.Equal(t, 1, MaxWorkers(), "Max workers should be at least 1")
+		for _, workers := range []int{1, 2, 8} {
+			SetMaxWorkers(workers)
+			assert.Equal(t, workers, MaxWorkers(), "Max workers should be set")
+
+			clone, err := tela.cloneINDEX(context.Background(), indexSCID, filepath.Join(datashards, fmt.Sprintf("workers%d", workers)), endpoint)
+			assert.NoError(t, err, "Cloning INDEX with %d workers should not error: %s", workers, err)
+			assert.Equal(t, telaDocs[0].NameHdr, clone.Entrypoint, "Entrypoint should be DOC1 with %d workers", workers)
+			if assert.Len(t, clone.Verifications, len(docSCIDs), "All DOCs should have verification results with %d workers", workers) {
+				for i, v := range clone.Verifications {
+					assert.Equal(t, docSCIDs[i], v.SCID, "Verifications should be in DOC order with %d workers", workers)
+				}
+			}
+		}
+
+		// Any DOC that can not be fetched should stop the clone
+		missing := append([]string{}, docSCIDs...)
+		missing = append(missing, scDoesNotExist, scDoesNotExist)
+		missingSCID := fmt.Sprintf("%064x", 101)
+		err = addMemoryINDEX(memory, missingSCID, owner, INDEX{DURL: "missing.tela", DOCs: missing, Headers: Headers{NameHdr: "Missing"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		err = Clone(missingSCID, endpoint)
+		assert.Error(t, err, "Cloning INDEX with missing DOC should error")
+		_, err = os.Stat(filepath.Join(datashards, "clone", "missing.tela"))
+		assert.True(t, os.IsNotExist(err), "Files should not remain after failed clone")
+
+		// Canceling between fetches should error without returning partial states
+		SetMaxWorkers(1)
+		canceled, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		SetDaemon(&cancelingDaemon{MemoryDaemon: memory, cancel: cancel})
+		states, err := tela.getContractStates(canceled, docSCIDs, endpoint)
+		SetDaemon(memory)
+		assert.ErrorIs(t, err, context.Canceled, "Getting contract states should error when canceled: %s", err)
+		assert.ErrorIs(t, err, ErrDaemon, "Canceled contract states should be a daemon error: %s", err)
+		assert.Nil(t, states, "Contract states should not be returned when canceled")
+
+		// Error of the lowest index is returned when a later index fails first
+		SetMaxWorkers(DEFAULT_MAX_WORKERS)
+		slow, fast := fmt.Sprintf("%064x", 102), fmt.Sprintf("%064x", 103)
+		SetDaemon(&delayedFailDaemon{MemoryDaemon: memory, delays: map[string]time.Duration{slow: sleepFor / 10, fast: 0}})
+		for i := 0; i < 5; i++ {
+			states, err = tela.getContractStates(context.Background(), []string{docSCIDs[0], slow, docSCIDs[1], fast, docSCIDs[2]}, endpoint)
+			var telaErr *Error
+			if assert.ErrorAs(t, err, &telaErr, "Getting contract states with failed SCIDs should be a TELA Error") {
+				assert.Equal(t, slow, telaErr.SCID, "Error should be of the lowest index that failed")
+			}
+			assert.Nil(t, states, "Contract states should not be returned with an error")
+		}
+		SetDaemon(memory)
+	})
+
+	t.Run("ServeTELA", func(t *testing.T) {
+		SetSignaturePolicy(SIGNATURE_ENFORCE)
+		defer SetSignaturePolicy(SIGNATURE_WARN)
+
+		link, err := ServeTELA(indexSCID, endpoint)
+		assert.NoError(t, err, "Serving INDEX should not error: %s", err)
+		assert.True(t, strings.HasSuffix(link, telaDocs[0].NameHdr), "Link should point to entrypoint: %s", link)
+
+		servers := GetServerInfo()
+		if assert.Len(t, servers, 1, "One server should be running") {
+			assert.Len(t, servers[0].Verifications, len(docSCIDs), "All DOCs should have verification results")
+			for _, v := range servers[0].Verifications {
+				assert.True(t, v.Verified, "DOC %s signature should be verified: %s", v.Name, v.Error)
+			}
+		}
+
+		// DOC signature does not match its code
+		other := fmt.Sprintf("%064x", 200)
+		doc := telaDocs[0].DOC
+		doc.Code = "<html></html>"
+		doc.DURL = "other.tela"
+		err = addMemoryDOC(memory, other, owner, doc)
+		assert.NoError(t, err, "Adding DOC should not error: %s", err)
+		err = addMemoryINDEX(memory, fmt.Sprintf("%064x", 201), owner, INDEX{DURL: doc.DURL, DOCs: []string{other}, Headers: Headers{NameHdr: "Other"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		_, err = ServeTELA(fmt.Sprintf("%064x", 201), endpoint)
+		assert.ErrorIs(t, err, ErrSignature, "Serving INDEX with invalid DOC signature should error when enforced: %s", err)
+
+		ShutdownTELA()
+		assert.Empty(t, GetServerInfo(), "Servers should be shutdown")
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := CloneContext(canceled, indexSCID, endpoint)
+		assert.ErrorIs(t, err, context.Canceled, "CloneContext should error when canceled: %s", err)
+		_, err = ServeTELAContext(canceled, indexSCID, endpoint)
+		assert.ErrorIs(t, err, context.Canceled, "ServeTELAContext should error when canceled: %s", err)
+		_, err = OpenTELALinkContext(canceled, "tela://open/"+indexSCID, endpoint)
+		assert.Error(t, err, "OpenTELALinkContext should error when canceled")
+		_, err = GetRatingContext(canceled, indexSCID, endpoint, 0)
+		assert.ErrorIs(t, err, context.Canceled, "GetRatingContext should error when canceled: %s", err)
+		_, err = GetDOCInfoContext(canceled, docSCIDs[0], endpoint)
+		assert.ErrorIs(t, err, context.Canceled, "GetDOCInfoContext should error when canceled: %s", err)
+		_, err = GetINDEXInfoContext(canceled, indexSCID, endpoint)
+		assert.ErrorIs(t, err, context.Canceled, "GetINDEXInfoContext should error when canceled: %s", err)
+		_, err = RateContext(canceled, wallet, indexSCID, 90)
+		assert.Error(t, err, "RateContext should error when canceled")
+
+		// Daemon can be set while it is in use
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				SetDaemon(memory)
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := GetINDEXInfo(indexSCID, endpoint)
+				assert.NoError(t, err, "GetINDEXInfo should not error while setting daemon: %s", err)
+			}()
+		}
+		wg.Wait()
+
+		// A stalled daemon should not hold TELA past the deadline
+		SetDaemon(&stalledDaemon{MemoryDaemon: memory})
+		defer SetDaemon(memory)
+
+		timeout, cancel := context.WithTimeout(context.Background(), sleepFor/10)
+		defer cancel()
+
+		start := time.Now()
+		_, err = ServeTELAContext(timeout, indexSCID, endpoint)
+		assert.Error(t, err, "ServeTELAContext should error on deadline")
+		assert.Less(t, time.Since(start), sleepFor, "ServeTELAContext should return at deadline")
+		assert.Empty(t, GetServerInfo(), "No servers should be running")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		var telaErr *Error
+		_, err := tela.getContractVar(context.Background(), indexSCID, "none", endpoint)
+		assert.ErrorIs(t, err, ErrMissingHeader, "Getting key that does not exist should be missing header: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "Missing header should be a TELA Error") {
+			assert.Equal(t, indexSCID, telaErr.SCID, "Error should carry SCID")
+		}
+
+		err = Clone("scid", endpoint)
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Cloning invalid SCID should error: %s", err)
+		err = Clone(scDoesNotExist, endpoint)
+		assert.ErrorIs(t, err, ErrNotTELA, "Cloning SCID that does not exist should not be TELA: %s", err)
+		_, err = GetDOCInfo(indexSCID, endpoint)
+		assert.ErrorIs(t, err, ErrNotTELA, "GetDOCInfo on INDEX should not be TELA-DOC: %s", err)
+		_, err = GetRating(scDoesNotExist, endpoint, 0)
+		assert.ErrorIs(t, err, ErrNotTELA, "GetRating on SCID that does not exist should not be TELA: %s", err)
+
+		// DOC was cloned in Clone
+		err = Clone(docSCIDs[0], endpoint)
+		assert.ErrorIs(t, err, ErrFileExists, "Cloning DOC that already exists should error: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "File exists should be a TELA Error") {
+			assert.Equal(t, docSCIDs[0], telaErr.SCID, "Error should carry DOC SCID")
+			assert.Equal(t, telaDocs[0].DURL, telaErr.DURL, "Error should carry DOC dURL")
+		}
+
+		// Libraries are not served
+		libSCID := fmt.Sprintf("%064x", 300)
+		err = addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "errors" + TAG_LIBRARY, DOCs: docSCIDs[1:], Headers: Headers{NameHdr: "Library"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		_, err = ServeTELA(libSCID, endpoint)
+		assert.ErrorIs(t, err, ErrLibrary, "Serving library should error: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "Library should be a TELA Error") {
+			assert.Equal(t, libSCID, telaErr.SCID, "Error should carry library SCID")
+			assert.Equal(t, "errors"+TAG_LIBRARY, telaErr.DURL, "Error should carry library dURL")
+		}
+
+		// Embedded library with a different TELA version
+		embedSCID := fmt.Sprintf("%064x", 301)
+		err = addMemoryINDEX(memory, embedSCID, owner, INDEX{DURL: "embed.tela", DOCs: []string{docSCIDs[0], libSCID}, Headers: Headers{NameHdr: "Embed"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		err = memory.StoreString(libSCID, "telaVersion", "0.0.0")
+		assert.NoError(t, err, "Storing version should not error: %s", err)
+		_, err = ServeTELA(embedSCID, endpoint)
+		assert.ErrorIs(t, err, ErrVersion, "Serving INDEX with mismatched library version should error: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "Version mismatch should be a TELA Error") {
+			assert.Equal(t, libSCID, telaErr.SCID, "Error should carry library SCID")
+		}
+
+		// Library as entrypoint
+		err = addMemoryINDEX(memory, embedSCID, owner, INDEX{DURL: "embed.tela", DOCs: []string{libSCID, docSCIDs[0]}, Headers: Headers{NameHdr: "Embed"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		err = memory.StoreString(libSCID, "telaVersion", TELA_VERSION)
+		assert.NoError(t, err, "Storing version should not error: %s", err)
+		_, err = ServeTELA(embedSCID, endpoint)
+		assert.ErrorIs(t, err, ErrEmbed, "Serving INDEX with library entrypoint should error: %s", err)
+
+		// Updated content when updates are not allowed
+		commit := fmt.Sprintf("%064x", 400)
+		err = memory.StoreString(indexSCID, "hash", commit)
+		assert.NoError(t, err, "Storing hash should not error: %s", err)
+		_, err = ServeTELA(indexSCID, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving updated INDEX should error when updates are not allowed: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "Updated should be a TELA Error") {
+			assert.Equal(t, indexSCID, telaErr.SCID, "Error should carry INDEX SCID")
+			assert.Equal(t, index.DURL, telaErr.DURL, "Error should carry INDEX dURL")
+			assert.Equal(t, commit, telaErr.Commit, "Error should carry INDEX commit")
+		}
+		err = memory.StoreString(indexSCID, "hash", indexSCID)
+		assert.NoError(t, err, "Storing hash should not error: %s", err)
+
+		_, err = ServeAtCommit(indexSCID, commit, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving at commit should error when updates are not allowed: %s", err)
+		AllowUpdates(true)
+		err = CloneAtCommit(indexSCID, "txid", endpoint)
+		AllowUpdates(false)
+		assert.ErrorIs(t, err, ErrInvalidCommit, "Cloning at invalid commit should error: %s", err)
+
+		// Daemon that can not be reached
+		SetDaemon(&failingDaemon{MemoryDaemon: memory})
+		defer SetDaemon(memory)
+
+		_, err = ServeTELA(indexSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDaemon, "Serving with unreachable daemon should error: %s", err)
+		if assert.ErrorAs(t, err, &telaErr, "Daemon unreachable should be a TELA Error") {
+			assert.Equal(t, indexSCID, telaErr.SCID, "Error should carry INDEX SCID")
+		}
+		assert.Empty(t, GetServerInfo(), "No servers should be running")
+	})
+
+	t.Run("Traversal", func(t *testing.T) {
+		contractsPath := filepath.Join(testDir, "contracts")
+		for i, file := range []string{"traversal_name.bas", "traversal_subdir.bas", "absolute_subdir.bas"} {
+			scid := fmt.Sprintf("%064x", 500+i)
+			err := addMemoryContract(memory, scid, contractsPath, file)
+			if err != nil {
+				t.Fatalf("Could not add %s: %s", file, err)
+			}
+
+			err = Clone(scid, endpoint)
+			assert.ErrorIs(t, err, ErrUnsafePath, "Cloning %s should error: %s", file, err)
+
+			// DOC embedded in a valid INDEX
+			indexSCID := fmt.Sprintf("%064x", 510+i)
+			err = addMemoryINDEX(memory, indexSCID, owner, INDEX{DURL: fmt.Sprintf("traversal%d.tela", i), DOCs: []string{scid}, Headers: Headers{NameHdr: "Traversal"}})
+			assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+			_, err = ServeTELA(indexSCID, endpoint)
+			assert.ErrorIs(t, err, ErrUnsafePath, "Serving INDEX with %s should error: %s", file, err)
+		}
+
+		_, err := os.Stat(filepath.Join(datashards, "traversal.html"))
+		assert.True(t, os.IsNotExist(err), "File should not be written outside of clone path")
+
+		scid := fmt.Sprintf("%064x", 520)
+		err = addMemoryContract(memory, scid, contractsPath, "traversal_durl.bas")
+		if err != nil {
+			t.Fatalf("Could not add traversal_durl.bas: %s", err)
+		}
+
+		_, err = ServeTELA(scid, endpoint)
+		assert.ErrorIs(t, err, ErrUnsafePath, "Serving INDEX with traversal dURL should error: %s", err)
+		var telaErr *Error
+		if assert.ErrorAs(t, err, &telaErr, "Unsafe path should be a TELA Error") {
+			assert.Equal(t, scid, telaErr.SCID, "Error should carry INDEX SCID")
+		}
+		assert.Empty(t, GetServerInfo(), "No servers should be running")
+
+		// Unsafe path elements
+		for _, name := range []string{"", ".", "..", "../index.html", "sub/index.html", `..\index.html`, "C:index.html", "index\x00.html", "index\n.html"} {
+			assert.Error(t, validatePathName(name), "Path name %q should not be valid", name)
+		}
+
+		for _, subDir := range []string{"..", "sub/../..", "/sub", "sub/./dir", `sub\..\..`, "sub/C:"} {
+			_, err = cleanSubDir(subDir)
+			assert.Error(t, err, "SubDir %q should not be valid", subDir)
+		}
+
+		for subDir, want := range map[string]string{"": "", "sub": "sub", "sub/": "sub", "sub//dir/": "sub/dir", "sub.dir/v1.0": "sub.dir/v1.0"} {
+			clean, err := cleanSubDir(subDir)
+			assert.NoError(t, err, "SubDir %q should be valid: %s", subDir, err)
+			assert.Equal(t, want, clean, "SubDir %q should be normalised", subDir)
+		}
+
+		// Install should reject the same content
+		doc := telaDocs[0].DOC
+		doc.Code = "<html></html>"
+		for _, bad := range []DOC{
+			{DURL: "../app.tela", Headers: Headers{NameHdr: "index.html"}},
+			{DURL: "app.tela", Headers: Headers{NameHdr: "../index.html"}},
+			{DURL: "app.tela", SubDir: "../..", Headers: Headers{NameHdr: "index.html"}},
+		} {
+			d := doc
+			d.DURL, d.SubDir, d.NameHdr = bad.DURL, bad.SubDir, bad.NameHdr
+			_, err = NewInstallArgs(&d)
+			assert.Error(t, err, "Installing DOC with unsafe path should error")
+		}
+
+		_, err = NewInstallArgs(&INDEX{DURL: "../app.tela", DOCs: docSCIDs, Headers: Headers{NameHdr: "Traversal"}})
+		assert.Error(t, err, "Installing INDEX with unsafe dURL should error")
+		_, err = NewUpdateArgs(&INDEX{SCID: indexSCID, DURL: "/app.tela", DOCs: docSCIDs, Headers: Headers{NameHdr: "Traversal"}})
+		assert.Error(t, err, "Updating INDEX with unsafe dURL should error")
+
+		doc.SubDir = "sub//dir/"
+		args, err := NewInstallArgs(&doc)
+		assert.NoError(t, err, "Installing DOC with subDir should not error: %s", err)
+		code, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+		assert.Contains(t, code, `STORE("subDir", "sub/dir")`, "Installed subDir should be normalised")
+		assert.Equal(t, "sub//dir/", doc.SubDir, "Install should not modify DOC")
+	})
+
+	t.Run("Staging", func(t *testing.T) {
+		// Existing content should not be modified by a failed clone
+		clonePath := filepath.Join(datashards, "clone")
+		existing := filepath.Join(clonePath, index.DURL, telaDocs[0].NameHdr)
+		before, err := os.ReadFile(existing)
+		assert.NoError(t, err, "Reading existing file should not error: %s", err)
+		err = Clone(indexSCID, endpoint)
+		assert.ErrorIs(t, err, ErrFileExists, "Cloning INDEX that already exists should error: %s", err)
+		after, err := os.ReadFile(existing)
+		assert.NoError(t, err, "Existing file should remain after failed clone: %s", err)
+		assert.Equal(t, before, after, "Existing file should not be modified by failed clone")
+
+		// Clones of the same dURL should not collide, only one should be successful
+		path := filepath.Join(datashards, "staging")
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = tela.cloneINDEX(context.Background(), indexSCID, path, endpoint)
+			}(i)
+		}
+		wg.Wait()
+
+		var cloned int
+		for _, err := range errs {
+			if err == nil {
+				cloned++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrFileExists, "Concurrent clone should only error as existing: %s", err)
+		}
+		assert.Equal(t, 1, cloned, "One concurrent clone should be successful")
+		for _, doc := range telaDocs[:3] {
+			_, err = os.Stat(filepath.Join(path, index.DURL, doc.NameHdr))
+			assert.NoError(t, err, "Cloned file %s should exist: %s", doc.NameHdr, err)
+		}
+
+		// No staging directories should remain after successful or failed clones
+		for _, dir := range []string{path, clonePath} {
+			entries, err := os.ReadDir(dir)
+			assert.NoError(t, err, "Reading %s should not error: %s", dir, err)
+			for _, e := range entries {
+				assert.False(t, strings.HasPrefix(e.Name(), "."), "Staging directory %s should be removed", e.Name())
+			}
+		}
+	})
+
+	t.Run("Dependencies", func(t *testing.T) {
+		// Library chain with a DOC at each depth, app -> deps0.lib -> deps1.lib ...
+		libSCID := func(i int) string { return fmt.Sprintf("%064x", 600+i) }
+		addLibrary := func(i int, docs ...string) {
+			err := addMemoryINDEX(memory, libSCID(i), owner, INDEX{DURL: fmt.Sprintf("deps%d%s", i, TAG_LIBRARY), DOCs: docs, Headers: Headers{NameHdr: "Dependency"}})
+			if err != nil {
+				t.Fatalf("Could not add library %d: %s", i, err)
+			}
+		}
+
+		for i := 0; i < MAX_LIBRARY_DEPTH; i++ {
+			if i == MAX_LIBRARY_DEPTH-1 {
+				addLibrary(i, docSCIDs[1])
+			} else {
+				addLibrary(i, docSCIDs[1], libSCID(i+1))
+			}
+		}
+
+		appSCID := fmt.Sprintf("%064x", 650)
+		err := addMemoryINDEX(memory, appSCID, owner, INDEX{DURL: "deps.tela", DOCs: []string{docSCIDs[0], libSCID(0)}, Headers: Headers{NameHdr: "Dependencies"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		graph, err := GetDependencies(appSCID, endpoint)
+		assert.NoError(t, err, "Resolving dependencies should not error: %s", err)
+		if assert.NotNil(t, graph, "Graph should be resolved") {
+			assert.Equal(t, "deps.tela", graph.DURL, "Graph dURL should be INDEX dURL")
+			assert.Equal(t, []string{docSCIDs[0], libSCID(0)}, graph.DOCs, "Graph DOCs should be in INDEX order")
+			dep, depth := graph, 0
+			for len(dep.Libraries) > 0 {
+				dep = dep.Libraries[0]
+				depth++
+				assert.Equal(t, depth, dep.Depth, "Library depth should be set")
+				assert.Equal(t, libSCID(depth-1), dep.SCID, "Library at depth %d should be resolved", depth)
+			}
+			assert.Equal(t, MAX_LIBRARY_DEPTH, depth, "Graph should resolve libraries to max depth")
+		}
+
+		clone, err := tela.cloneINDEX(context.Background(), appSCID, filepath.Join(datashards, "deps"), endpoint)
+		assert.NoError(t, err, "Cloning INDEX at max library depth should not error: %s", err)
+		assert.Len(t, clone.Verifications, 1+MAX_LIBRARY_DEPTH, "All DOCs in graph should have verification results")
+
+		// Exceed max depth
+		addLibrary(MAX_LIBRARY_DEPTH-1, docSCIDs[1], libSCID(MAX_LIBRARY_DEPTH))
+		addLibrary(MAX_LIBRARY_DEPTH, docSCIDs[1])
+		_, err = GetDependencies(appSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDependency, "Resolving libraries past max depth should error: %s", err)
+
+		// Cycle, deps0.lib -> deps1.lib -> deps0.lib
+		addLibrary(1, docSCIDs[1], libSCID(0))
+		_, err = GetDependencies(appSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDependency, "Resolving library cycle should error: %s", err)
+		assert.ErrorContains(t, err, "cycle", "Error should be a cycle")
+		_, err = ServeTELA(appSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDependency, "Serving library cycle should error: %s", err)
+
+		// Library embedding itself
+		addLibrary(1, docSCIDs[1], libSCID(1))
+		_, err = GetDependencies(appSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDependency, "Resolving library embedding itself should error: %s", err)
+
+		// Shared library, deps1.lib and deps2.lib both embed deps3.lib
+		addLibrary(1, docSCIDs[1], libSCID(2), libSCID(3))
+		addLibrary(2, docSCIDs[1], libSCID(3))
+		addLibrary(3, docSCIDs[1])
+		graph, err = GetDependencies(appSCID, endpoint)
+		if assert.NoError(t, err, "Resolving shared library should not error: %s", err) {
+			deps1 := graph.Libraries[0].Libraries[0]
+			if assert.Len(t, deps1.Libraries, 2, "Shared library should be resolved in each INDEX") {
+				assert.Equal(t, libSCID(3), deps1.Libraries[1].SCID, "Shared library should be embedded in deps1.lib")
+				assert.Equal(t, libSCID(3), deps1.Libraries[0].Libraries[0].SCID, "Shared library should be embedded in deps2.lib")
+			}
+		}
+
+		shared, err := tela.cloneINDEX(context.Background(), appSCID, filepath.Join(datashards, "shared"), endpoint)
+		assert.NoError(t, err, "Cloning shared library should not error: %s", err)
+		assert.Len(t, shared.Verifications, 6, "Shared library DOCs should be verified in each INDEX")
+
+		// Same library twice in one INDEX
+		addLibrary(1, docSCIDs[1], libSCID(3), libSCID(3))
+		_, err = GetDependencies(appSCID, endpoint)
+		assert.ErrorIs(t, err, ErrDependency, "Resolving duplicate library should error: %s", err)
+		var telaErr *Error
+		if assert.ErrorAs(t, err, &telaErr, "Duplicate library should be a TELA Error") {
+			assert.Equal(t, libSCID(3), telaErr.SCID, "Error should carry duplicate library SCID")
+		}
+
+		_, err = os.Stat(filepath.Join(datashards, "tela", "deps.tela"))
+		assert.True(t, os.IsNotExist(err), "Files should not be cloned when dependencies do not resolve")
+
+		_, err = GetDependencies(docSCIDs[0], endpoint)
+		assert.ErrorIs(t, err, ErrNotTELA, "Resolving DOC should error: %s", err)
+		_, err = GetDependencies("scid", endpoint)
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Resolving invalid SCID should error: %s", err)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		historySCID := fmt.Sprintf("%064x", 700)
+		history := INDEX{DURL: "history.tela", DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "History"}}
+		err := addMemoryINDEX(memory, historySCID, owner, history)
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		// Each commit adds a DOC
+		txids := []string{historySCID}
+		for i := 1; i < len(docSCIDs); i++ {
+			txid := fmt.Sprintf("%064x", 700+i)
+			history.DOCs = docSCIDs[:i+1]
+			err = updateMemoryINDEX(memory, historySCID, txid, int64(10*i), uint64(i), history)
+			assert.NoError(t, err, "Updating INDEX should not error: %s", err)
+			txids = append(txids, txid)
+		}
+
+		commits, err := GetINDEXHistory(historySCID, endpoint)
+		assert.NoError(t, err, "GetINDEXHistory should not error: %s", err)
+		if assert.Len(t, commits, len(docSCIDs), "History should have every commit") {
+			for i, c := range commits {
+				assert.Equal(t, uint64(i), c.Number, "Commit number should be in order")
+				assert.Equal(t, txids[i], c.TXID, "Commit %d TXID should be equal", i)
+				assert.Equal(t, docSCIDs[:i+1], c.DOCs, "Commit %d DOCs should be equal", i)
+			}
+			assert.Equal(t, int64(1), commits[0].Height, "Install height should be equal")
+			assert.Equal(t, int64(20), commits[2].Height, "Commit height should be equal")
+		}
+
+		_, err = GetINDEXHistory(docSCIDs[0], endpoint)
+		assert.ErrorIs(t, err, ErrNotTELA, "GetINDEXHistory on DOC should error: %s", err)
+		_, err = GetINDEXHistory("scid", endpoint)
+		assert.ErrorIs(t, err, ErrInvalidSCID, "GetINDEXHistory on invalid SCID should error: %s", err)
+
+		// Serve and clone by commit number
+		_, err = ServeAtCommitNumber(historySCID, 1, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving at commit number should error when updates are not allowed: %s", err)
+
+		AllowUpdates(true)
+		defer AllowUpdates(false)
+
+		link, err := ServeAtCommitNumber(historySCID, 1, endpoint)
+		assert.NoError(t, err, "Serving at commit number should not error: %s", err)
+		assert.NotEmpty(t, link, "Serving at commit number should return link")
+		_, err = os.Stat(filepath.Join(datashards, "tela", history.DURL, telaDocs[1].NameHdr))
+		assert.NoError(t, err, "DOC added at commit should exist: %s", err)
+		_, err = os.Stat(filepath.Join(datashards, "tela", history.DURL, telaDocs[2].NameHdr))
+		assert.True(t, os.IsNotExist(err), "DOC added after commit should not exist")
+		ShutdownTELA()
+
+		err = CloneAtCommitNumber(historySCID, 0, endpoint)
+		assert.NoError(t, err, "Cloning at commit number should not error: %s", err)
+		entries, err := os.ReadDir(filepath.Join(datashards, "clone", history.DURL))
+		assert.NoError(t, err, "Reading clone should not error: %s", err)
+		assert.Len(t, entries, 1, "Clone at commit 0 should have one DOC")
+
+		err = CloneAtCommitNumber(historySCID, uint64(len(docSCIDs)), endpoint)
+		assert.ErrorIs(t, err, ErrInvalidCommit, "Cloning at commit number that does not exist should error: %s", err)
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		diffSCID := fmt.Sprintf("%064x", 800)
+		libSCID := fmt.Sprintf("%064x", 801)
+		err := addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "diff" + TAG_LIBRARY, DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "Diff Library"}})
+		assert.NoError(t, err, "Adding library should not error: %s", err)
+
+		diffIndex := INDEX{DURL: "diff.tela", DOCs: docSCIDs[:2], Headers: Headers{NameHdr: "Diff", DescrHdr: "Before"}}
+		err = addMemoryINDEX(memory, diffSCID, owner, diffIndex)
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		// Replace DOC2, embed a library and change headers
+		txid := fmt.Sprintf("%064x", 802)
+		diffIndex.DOCs = []string{docSCIDs[0], docSCIDs[2], libSCID}
+		diffIndex.NameHdr = "Diff Updated"
+		diffIndex.DescrHdr = "After"
+		err = updateMemoryINDEX(memory, diffSCID, txid, 10, 1, diffIndex)
+		assert.NoError(t, err, "Updating INDEX should not error: %s", err)
+
+		diff, err := DiffINDEX(diffSCID, diffSCID, txid, endpoint)
+		assert.NoError(t, err, "DiffINDEX should not error: %s", err)
+		assert.False(t, diff.Empty(), "Diff should have changes")
+		assert.Equal(t, diffSCID, diff.From, "Diff from should be equal")
+		assert.Equal(t, txid, diff.To, "Diff to should be equal")
+		assert.Equal(t, []HeaderDiff{
+			{Header: HEADER_NAME, From: "Diff", To: "Diff Updated"},
+			{Header: HEADER_DESCRIPTION, From: "Before", To: "After"},
+		}, diff.Headers, "Diff headers should be equal")
+		assert.Equal(t, []string{libSCID}, diff.Added, "Diff added should be library")
+		assert.Empty(t, diff.Removed, "Diff should have no removed DOCs")
+		assert.Equal(t, []string{libSCID}, diff.Libraries.Added, "Diff should have added library")
+		if assert.Len(t, diff.Replaced, 1, "Diff should have replaced DOC") {
+			assert.Equal(t, "DOC2", diff.Replaced[0].DOCNum, "Replaced DOC# should be equal")
+			assert.Equal(t, docSCIDs[1], diff.Replaced[0].From, "Replaced from should be equal")
+			assert.Equal(t, docSCIDs[2], diff.Replaced[0].To, "Replaced to should be equal")
+			assert.True(t, strings.HasPrefix(diff.Replaced[0].Diff, fmt.Sprintf("--- %s\n+++ %s\n@@ ", docSCIDs[1], docSCIDs[2])), "Replaced DOC should have unified diff")
+		}
+
+		// Reverse diff
+		diff, err = DiffINDEX(diffSCID, txid, diffSCID, endpoint)
+		assert.NoError(t, err, "DiffINDEX should not error: %s", err)
+		assert.Equal(t, []string{libSCID}, diff.Removed, "Reverse diff removed should be library")
+		assert.Equal(t, []string{libSCID}, diff.Libraries.Removed, "Reverse diff should have removed library")
+
+		// Live state is the latest commit
+		diff, err = DiffINDEX(diffSCID, txid, "", endpoint)
+		assert.NoError(t, err, "DiffINDEX with live state should not error: %s", err)
+		assert.Equal(t, txid, diff.To, "Live state should be latest commit")
+		assert.True(t, diff.Empty(), "Diff of latest commit and live state should be empty")
+
+		_, err = DiffINDEX(diffSCID, "", txid, endpoint)
+		assert.ErrorIs(t, err, ErrInvalidCommit, "DiffINDEX without from commit should error: %s", err)
+		_, err = DiffINDEX(docSCIDs[0], "", "", endpoint)
+		assert.ErrorIs(t, err, ErrInvalidCommit, "DiffINDEX without from commit should error: %s", err)
+		_, err = DiffINDEX(diffSCID, docSCIDs[0], "", endpoint)
+		assert.Error(t, err, "DiffINDEX from DOC should error")
+
+		// Unified diff of docCode
+		assert.Empty(t, unifiedDiff("a", "b", "same", "same"), "Unified diff of equal text should be empty")
+		expected := "--- a\n+++ b\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -9,3 +9,4 @@\n 9\n 10\n 11\n+12\n"
+		assert.Equal(t, expected, unifiedDiff("a", "b", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11", "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12"), "Unified diff should be equal")
+
+		// Large changes are not diffed by line
+		var large, changed strings.Builder
+		for i := 0; i < 5000; i++ {
+			fmt.Fprintf(&large, "line %d\n", i)
+			fmt.Fprintf(&changed, "changed %d\n", i)
+		}
+		assert.Equal(t, "Files a and b differ\n", unifiedDiff("a", "b", large.String(), changed.String()), "Large changes should not be diffed by line")
+		expected = "--- a\n+++ b\n@@ -2498,7 +2498,7 @@\n line 2497\n line 2498\n line 2499\n-line 2500\n+changed 2500\n line 2501\n line 2502\n line 2503\n"
+		assert.Equal(t, expected, unifiedDiff("a", "b", large.String(), strings.Replace(large.String(), "line 2500\n", "changed 2500\n", 1)), "Large text with a small change should be diffed by line")
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		updatesSCID := fmt.Sprintf("%064x", 900)
+		updates := INDEX{DURL: "updates.tela", DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "Updates"}}
+		err := addMemoryINDEX(memory, updatesSCID, owner, updates)
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		// Each commit adds a DOC
+		txids := []string{updatesSCID}
+		for i := 1; i < len(docSCIDs); i++ {
+			txid := fmt.Sprintf("%064x", 900+i)
+			updates.DOCs = docSCIDs[:i+1]
+			err = updateMemoryINDEX(memory, updatesSCID, txid, int64(10*i), uint64(i), updates)
+			assert.NoError(t, err, "Updating INDEX should not error: %s", err)
+			txids = append(txids, txid)
+		}
+
+		latest := txids[len(txids)-1]
+
+		t.Cleanup(func() {
+			SetUpdateRule(updatesSCID, UpdateRule{})
+			SetAuthorUpdateRule(owner, UpdateRule{})
+		})
+
+		// Serve updatesSCID and check the commit that was served
+		serveCommit := func(t *testing.T, commit string) {
+			defer ShutdownTELA()
+			_, err := ServeTELA(updatesSCID, endpoint)
+			if !assert.NoError(t, err, "Serving INDEX should not error: %s", err) {
+				return
+			}
+
+			info := GetServerInfo()
+			if assert.Len(t, info, 1, "Should be serving INDEX") {
+				assert.Equal(t, commit, info[0].Commit, "Served commit should be equal")
+			}
+		}
+
+		_, err = ServeTELA(updatesSCID, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving updated INDEX with no rule should error: %s", err)
+
+		// Pin to a commit
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_PIN, Commit: txids[1]})
+		assert.NoError(t, err, "Setting pin rule should not error: %s", err)
+		serveCommit(t, txids[1])
+
+		clone, err := tela.cloneINDEX(context.Background(), updatesSCID, t.TempDir(), endpoint)
+		assert.NoError(t, err, "Cloning pinned INDEX should not error: %s", err)
+		assert.Equal(t, txids[1], clone.Hash, "Cloned commit should be pinned commit")
+		assert.Equal(t, latest, clone.Latest, "Latest commit should be equal")
+		_, err = os.Stat(filepath.Join(clone.BasePath, telaDocs[1].NameHdr))
+		assert.NoError(t, err, "DOC of pinned commit should exist: %s", err)
+		_, err = os.Stat(filepath.Join(clone.BasePath, telaDocs[2].NameHdr))
+		assert.True(t, os.IsNotExist(err), "DOC added after pinned commit should not exist")
+
+		_, err = ServeAtCommit(updatesSCID, txids[1], endpoint)
+		assert.NoError(t, err, "Serving pinned commit should not error: %s", err)
+		ShutdownTELA()
+		_, err = ServeAtCommit(updatesSCID, latest, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving commit other than pinned commit should error: %s", err)
+
+		// Pin with no commit is the original install
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_PIN})
+		assert.NoError(t, err, "Setting pin rule should not error: %s", err)
+		serveCommit(t, updatesSCID)
+
+		// Manual approval
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_MANUAL})
+		assert.NoError(t, err, "Setting manual rule should not error: %s", err)
+		serveCommit(t, updatesSCID)
+		err = ApproveUpdate(updatesSCID, latest)
+		assert.NoError(t, err, "Approving update should not error: %s", err)
+		assert.Equal(t, UpdateRule{Policy: UPDATE_MANUAL, Commit: latest}, GetUpdateRule(updatesSCID, owner), "Approved rule should be equal")
+		serveCommit(t, latest)
+
+		// Latest
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_LATEST})
+		assert.NoError(t, err, "Setting latest rule should not error: %s", err)
+		serveCommit(t, latest)
+
+		// Author rule is used when the SCID has no rule
+		err = SetUpdateRule(updatesSCID, UpdateRule{})
+		assert.NoError(t, err, "Removing rule should not error: %s", err)
+		err = SetAuthorUpdateRule(owner, UpdateRule{Policy: UPDATE_LATEST})
+		assert.NoError(t, err, "Setting author rule should not error: %s", err)
+		assert.Equal(t, UPDATE_LATEST, GetUpdateRule(updatesSCID, owner).Policy, "Author rule should be used")
+		serveCommit(t, latest)
+
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_PIN})
+		assert.NoError(t, err, "Setting pin rule should not error: %s", err)
+		serveCommit(t, updatesSCID)
+
+		// Rules are stored in shards
+		tela.rules.reset()
+		scids, authors := GetUpdateRules()
+		assert.Equal(t, UpdateRule{Policy: UPDATE_PIN}, scids[updatesSCID], "Stored SCID rule should be equal")
+		assert.Equal(t, UpdateRule{Policy: UPDATE_LATEST}, authors[owner], "Stored author rule should be equal")
+
+		// Rules are stored within each host's path
+		host, err := New(Config{Path: filepath.Join(datashards, "rules"), Daemon: memory})
+		assert.NoError(t, err, "Creating host should not error: %s", err)
+		scids, authors = host.GetUpdateRules()
+		assert.Empty(t, scids, "Host should not have the default host's SCID rules")
+		assert.Empty(t, authors, "Host should not have the default host's author rules")
+		err = host.SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_LATEST})
+		assert.NoError(t, err, "Setting host rule should not error: %s", err)
+		tela.rules.reset()
+		assert.Equal(t, UPDATE_PIN, GetUpdateRule(updatesSCID, owner).Policy, "Host rule should not change the default host's rule")
+		host.rules.reset()
+		assert.Equal(t, UPDATE_LATEST, host.GetUpdateRule(updatesSCID, owner).Policy, "Host rule should be stored in its path")
+
+		// Libraries follow their own rules
+		libSCID := fmt.Sprintf("%064x", 950)
+		err = addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "updates" + TAG_LIBRARY, DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "Updates Library"}})
+		assert.NoError(t, err, "Adding library should not error: %s", err)
+		err = memory.StoreString(libSCID, "hash", fmt.Sprintf("%064x", 951))
+		assert.NoError(t, err, "Storing hash should not error: %s", err)
+		updates.DOCs = []string{docSCIDs[0], libSCID}
+		err = updateMemoryINDEX(memory, updatesSCID, fmt.Sprintf("%064x", 952), 100, uint64(len(txids)), updates)
+		assert.NoError(t, err, "Updating INDEX should not error: %s", err)
+		latest = fmt.Sprintf("%064x", 952)
+
+		err = SetAuthorUpdateRule(owner, UpdateRule{})
+		assert.NoError(t, err, "Removing author rule should not error: %s", err)
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_LATEST})
+		assert.NoError(t, err, "Setting latest rule should not error: %s", err)
+		_, err = ServeTELA(updatesSCID, endpoint)
+		assert.ErrorIs(t, err, ErrUpdated, "Serving INDEX with updated library should error: %s", err)
+		var telaErr *Error
+		if assert.ErrorAs(t, err, &telaErr, "Updated should be a TELA Error") {
+			assert.Equal(t, libSCID, telaErr.SCID, "Error should carry library SCID")
+		}
+
+		err = SetAuthorUpdateRule(owner, UpdateRule{Policy: UPDATE_LATEST})
+		assert.NoError(t, err, "Setting author rule should not error: %s", err)
+		serveCommit(t, latest)
+
+		// Invalid rules
+		err = SetUpdateRule("scid", UpdateRule{Policy: UPDATE_LATEST})
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Setting rule with invalid SCID should error: %s", err)
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_PIN, Commit: "txid"})
+		assert.ErrorIs(t, err, ErrInvalidCommit, "Setting rule with invalid commit should error: %s", err)
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: UPDATE_LATEST, Commit: latest})
+		assert.Error(t, err, "Setting latest rule with commit should error")
+		err = SetUpdateRule(updatesSCID, UpdateRule{Policy: 255})
+		assert.Error(t, err, "Setting unknown policy should error")
+		err = SetAuthorUpdateRule("author", UpdateRule{Policy: UPDATE_LATEST})
+		assert.Error(t, err, "Setting rule with invalid author should error")
+		err = SetAuthorUpdateRule(owner, UpdateRule{Policy: UPDATE_PIN, Commit: latest})
+		assert.Error(t, err, "Setting author rule with commit should error")
+		err = ApproveUpdate(updatesSCID, "txid")
+		assert.ErrorIs(t, err, ErrInvalidCommit, "Approving invalid commit should error: %s", err)
+
+		for _, policy := range []UpdatePolicy{UPDATE_DEFAULT, UPDATE_LATEST, UPDATE_PIN, UPDATE_MANUAL} {
+			p, err := ParseUpdatePolicy(policy.String())
+			assert.NoError(t, err, "Parsing update policy should not error: %s", err)
+			assert.Equal(t, policy, p, "Parsed update policy should be equal")
+		}
+		_, err = ParseUpdatePolicy("always")
+		assert.Error(t, err, "Parsing unknown update policy should error")
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		t.Cleanup(ShutdownGateway)
+
+		gatewaySCIDs := []string{fmt.Sprintf("%064x", 1000), fmt.Sprintf("%064x", 1001)}
+		for i, scid := range gatewaySCIDs {
+			err := addMemoryINDEX(memory, scid, owner, INDEX{DURL: fmt.Sprintf("gateway%d.tela", i), DOCs: docSCIDs, Headers: Headers{NameHdr: "Gateway"}})
+			assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		}
+
+		libSCID := fmt.Sprintf("%064x", 1002)
+		err := addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "gateway" + TAG_LIBRARY, DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "Gateway Library"}})
+		assert.NoError(t, err, "Adding library should not error: %s", err)
+
+		index, err := readFile(telaDocs[0].filePath)
+		assert.NoError(t, err, "Reading index should not error: %s", err)
+
+		get := func(t *testing.T, url, host string) (status int, body string) {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if !assert.NoError(t, err, "Creating request should not error: %s", err) {
+				return
+			}
+
+			if host != "" {
+				req.Host = host
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err, "Gateway request should not error: %s", err) {
+				return
+			}
+			defer resp.Body.Close()
+
+			b, _ := io.ReadAll(resp.Body)
+
+			return resp.StatusCode, string(b)
+		}
+
+		gateway, err := StartGateway(GatewayConfig{Route: GATEWAY_PATH, Endpoint: endpoint, MaxApps: 2})
+		if !assert.NoError(t, err, "Starting gateway should not error: %s", err) {
+			return
+		}
+
+		assert.Equal(t, gateway, GetGateway(), "Running gateway should be equal")
+		_, err = StartGateway(GatewayConfig{Endpoint: endpoint})
+		assert.Error(t, err, "Starting second gateway should error")
+
+		// Content is cloned on demand by concurrent requests
+		var wg sync.WaitGroup
+		statuses := make([]int, 8)
+		for i := range statuses {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				statuses[i], _ = get(t, gateway.Link(gatewaySCIDs[0]), "")
+			}(i)
+		}
+		wg.Wait()
+
+		for _, status := range statuses {
+			assert.Equal(t, http.StatusOK, status, "Concurrent gateway requests should be successful")
+		}
+
+		status, body := get(t, gateway.Link(gatewaySCIDs[0]), "")
+		assert.Equal(t, http.StatusOK, status, "Gateway request should be successful")
+		assert.Equal(t, index, body, "Gateway should serve entrypoint")
+		status, _ = get(t, gateway.Link(gatewaySCIDs[0])+telaDocs[1].NameHdr, "")
+		assert.Equal(t, http.StatusOK, status, "Gateway should serve DOC")
+		status, _ = get(t, strings.TrimSuffix(gateway.Link(gatewaySCIDs[0]), "/"), "")
+		assert.Equal(t, http.StatusOK, status, "Gateway should redirect to content root")
+		status, body = get(t, fmt.Sprintf("http://localhost:%d%sgateway0.tela/", gateway.Port(), GATEWAY_PATH_PREFIX), "")
+		assert.Equal(t, http.StatusOK, status, "Gateway should serve loaded content by dURL")
+		assert.Equal(t, index, body, "Gateway should serve entrypoint by dURL")
+
+		cacheControl := func(t *testing.T, url, host string) string {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if !assert.NoError(t, err, "Creating request should not error: %s", err) {
+				return ""
+			}
+
+			if host != "" {
+				req.Host = host
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err, "Gateway request should not error: %s", err) {
+				return ""
+			}
+			resp.Body.Close()
+
+			return resp.Header.Get("Cache-Control")
+		}
+
+		assert.Equal(t, CACHE_CONTROL_LIVE, cacheControl(t, gateway.Link(gatewaySCIDs[0]), ""), "Live gateway content should be revalidated")
+
+		// Content at a commit is its own app and will not change
+		commitLink := gateway.LinkAtCommit(gatewaySCIDs[0], gatewaySCIDs[0])
+		assert.Equal(t, fmt.Sprintf("http://localhost:%d%s%s@%s/", gateway.Port(), GATEWAY_PATH_PREFIX, gatewaySCIDs[0], gatewaySCIDs[0]), commitLink, "Commit link should be equal")
+		link, err := gateway.LoadAtCommit(context.Background(), gatewaySCIDs[0], gatewaySCIDs[0])
+		assert.NoError(t, err, "Loading gateway content at commit should not error: %s", err)
+		assert.Equal(t, commitLink+telaDocs[0].NameHdr, link, "Loaded commit link should be entrypoint")
+		status, body = get(t, commitLink, "")
+		assert.Equal(t, http.StatusOK, status, "Gateway request at commit should be successful")
+		assert.Equal(t, index, body, "Gateway should serve entrypoint at commit")
+		assert.Equal(t, CACHE_CONTROL_PINNED, cacheControl(t, commitLink, ""), "Gateway content at commit should be immutable")
+		_, err = gateway.LoadAtCommit(context.Background(), gatewaySCIDs[0], "commit")
+		assert.ErrorIs(t, err, ErrInvalidCommit, "Loading gateway content at invalid commit should error: %s", err)
+		gateway.Evict(gatewaySCIDs[0])
+		status, _ = get(t, fmt.Sprintf("http://localhost:%d%sgateway0.tela@%s/", gateway.Port(), GATEWAY_PATH_PREFIX, gatewaySCIDs[0]), "")
+		assert.Equal(t, http.StatusNotFound, status, "Gateway should not serve commits by dURL")
+		status, _ = get(t, gateway.Link(gatewaySCIDs[0]), "")
+		assert.Equal(t, http.StatusOK, status, "Gateway request should be successful")
+
+		if apps := gateway.Apps(); assert.Len(t, apps, 1, "Gateway should have one app loaded") {
+			assert.Equal(t, gatewaySCIDs[0], apps[0].SCID, "Loaded app SCID should be equal")
+			assert.Equal(t, gatewaySCIDs[0], apps[0].Commit, "Loaded app commit should be equal")
+		}
+
+		// Least recently used is evicted when MaxApps is reached
+		link, err = gateway.Load(context.Background(), gatewaySCIDs[1])
+		assert.NoError(t, err, "Loading gateway content should not error: %s", err)
+		assert.Equal(t, gateway.Link(gatewaySCIDs[1])+telaDocs[0].NameHdr, link, "Loaded link should be entrypoint")
+		status, _ = get(t, gateway.Link(gatewaySCIDs[0]), "")
+		assert.Equal(t, http.StatusOK, status, "Gateway request should be successful")
+		_, err = gateway.Load(context.Background(), indexSCID)
+		assert.NoError(t, err, "Loading gateway content should not error: %s", err)
+
+		apps := gateway.Apps()
+		assert.Len(t, apps, 2, "Gateway should have MaxApps loaded")
+		for _, app := range apps {
+			assert.NotEqual(t, gatewaySCIDs[1], app.SCID, "Least recently used app should be evicted")
+		}
+		_, err = os.Stat(filepath.Join(datashards, "gateway", gatewaySCIDs[1]))
+		assert.True(t, os.IsNotExist(err), "Evicted app should be removed")
+
+		gateway.Evict(indexSCID)
+		assert.Len(t, gateway.Apps(), 1, "Gateway should have evicted app")
+
+		// Clones are not canceled with the load that started them and apps still loading are not counted towards MaxApps
+		gated := &gatedDaemon{MemoryDaemon: memory, scid: gatewaySCIDs[1], gate: make(chan struct{})}
+		SetDaemon(gated)
+		timeout, cancel := context.WithTimeout(context.Background(), sleepFor/10)
+		_, err = gateway.Load(timeout, gatewaySCIDs[1])
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "Load should stop waiting when its ctx is done: %s", err)
+		_, err = gateway.Load(context.Background(), indexSCID)
+		assert.NoError(t, err, "Loading gateway content should not error: %s", err)
+		assert.Len(t, gateway.Apps(), 2, "Apps still loading should not be counted towards MaxApps")
+		close(gated.gate)
+		_, err = gateway.Load(context.Background(), gatewaySCIDs[1])
+		SetDaemon(memory)
+		assert.NoError(t, err, "Clone should continue after the load that started it is done: %s", err)
+		assert.Len(t, gateway.Apps(), 2, "Gateway should have MaxApps loaded")
+
+		// Files being served are kept until they are released
+		app, err := gateway.load(context.Background(), gatewaySCIDs[1], "")
+		if assert.NoError(t, err, "Loading gateway content should not error: %s", err) {
+			gateway.Evict(gatewaySCIDs[1])
+			_, err = os.Stat(filepath.Join(app.clone.BasePath, app.clone.ServePath, app.clone.Entrypoint))
+			assert.NoError(t, err, "Evicted app files should be kept while being served: %s", err)
+			gateway.release(app)
+			_, err = os.Stat(app.path)
+			assert.True(t, os.IsNotExist(err), "Evicted app files should be removed once released")
+		}
+
+		// Content that can not be served
+		status, _ = get(t, fmt.Sprintf("http://localhost:%d/", gateway.Port()), "")
+		assert.Equal(t, http.StatusNotFound, status, "Gateway request with no content should not be found")
+		status, _ = get(t, gateway.Link(docSCIDs[0]), "")
+		assert.Equal(t, http.StatusNotFound, status, "Gateway request for DOC should not be found")
+		status, body = get(t, gateway.Link(libSCID), "")
+		assert.Equal(t, http.StatusForbidden, status, "Gateway request for library should be forbidden")
+		assert.Equal(t, http.StatusText(http.StatusForbidden)+"\n", body, "Gateway error should not be sent to the browser")
+		_, err = os.Stat(filepath.Join(datashards, "gateway", libSCID))
+		assert.True(t, os.IsNotExist(err), "Library should be removed")
+
+		ShutdownGateway()
+		assert.Nil(t, GetGateway(), "Gateway should be shutdown")
+		_, err = os.Stat(filepath.Join(datashards, "gateway"))
+		assert.True(t, os.IsNotExist(err), "Gateway content should be removed")
+
+		// Subdomain routes
+		gateway, err = StartGateway(GatewayConfig{Route: GATEWAY_SUBDOMAIN, Endpoint: endpoint})
+		if !assert.NoError(t, err, "Starting gateway should not error: %s", err) {
+			return
+		}
+
+		host := gatewaySCIDs[0][:32] + "." + gatewaySCIDs[0][32:] + ".localhost"
+		assert.Equal(t, fmt.Sprintf("http://%s:%d/", host, gateway.Port()), gateway.Link(gatewaySCIDs[0]), "Subdomain link should be equal")
+		for _, label := range strings.Split(host, ".") {
+			assert.LessOrEqual(t, len(label), 63, "Subdomain labels should be valid DNS labels")
+		}
+
+		root := fmt.Sprintf("http://localhost:%d/", gateway.Port())
+		status, body = get(t, root, host)
+		assert.Equal(t, http.StatusOK, status, "Subdomain gateway request should be successful")
+		assert.Equal(t, index, body, "Subdomain gateway should serve entrypoint")
+		status, _ = get(t, root+telaDocs[1].NameHdr, fmt.Sprintf("%s:%d", host, gateway.Port()))
+		assert.Equal(t, http.StatusOK, status, "Subdomain gateway should serve DOC")
+		status, _ = get(t, root, "")
+		assert.Equal(t, http.StatusNotFound, status, "Gateway request with no subdomain should not be found")
+
+		commitHost := gatewaySCIDs[0][:32] + "." + gatewaySCIDs[0][32:] + "." + host
+		assert.Equal(t, fmt.Sprintf("http://%s:%d/", commitHost, gateway.Port()), gateway.LinkAtCommit(gatewaySCIDs[0], gatewaySCIDs[0]), "Subdomain commit link should be equal")
+		status, body = get(t, root, commitHost)
+		assert.Equal(t, http.StatusOK, status, "Subdomain gateway request at commit should be successful")
+		assert.Equal(t, index, body, "Subdomain gateway should serve entrypoint at commit")
+		assert.Equal(t, CACHE_CONTROL_PINNED, cacheControl(t, root, commitHost), "Subdomain gateway content at commit should be immutable")
+		status, _ = get(t, root, gatewaySCIDs[0]+".localhost")
+		assert.Equal(t, http.StatusNotFound, status, "Gateway request with SCID as a single label should not be found")
+
+		ShutdownTELA()
+		assert.Nil(t, GetGateway(), "Gateway should be shutdown with TELA")
+
+		// Entrypoint redirect
+		handler := tela.contentHandler(Cloning{BasePath: t.TempDir(), ServePath: "/sub", Entrypoint: "main.html"})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusFound, rec.Code, "Root should redirect to entrypoint")
+		assert.Equal(t, "./sub/main.html", rec.Header().Get("Location"), "Redirect should be relative to entrypoint")
 	})
+
+	t.Run("Handler", func(t *testing.T) {
+		index, err := readFile(telaDocs[0].filePath)
+		assert.NoError(t, err, "Reading index should not error: %s", err)
+
+		handler, err := NewHandler(indexSCID, endpoint)
+		if !assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			return
+		}
+
+		info := handler.Info()
+		assert.Equal(t, "memory.tela", info.Name, "Handler name should be equal")
+		assert.Equal(t, indexSCID, info.SCID, "Handler SCID should be equal")
+		assert.Equal(t, indexSCID, info.Commit, "Handler commit should be equal")
+		assert.Empty(t, info.Address, "Handler should not have an address")
+
+		// Mount handler under a host application route
+		mux := http.NewServeMux()
+		mux.Handle("/apps/memory/", http.StripPrefix("/apps/memory", handler))
+		mux.Handle("/apps/memory", http.StripPrefix("/apps/memory", handler))
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/apps/memory", nil))
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, "Handler prefix should redirect to content root")
+		assert.Equal(t, "./memory/", rec.Header().Get("Location"), "Redirect should be relative to prefix")
+
+		for _, u := range []string{"/apps/memory", "/apps/memory/"} {
+			resp, err := http.Get(server.URL + u)
+			if assert.NoError(t, err, "Handler request should not error: %s", err) {
+				b, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode, "Handler request should be successful")
+				assert.Equal(t, index, string(b), "Handler should serve entrypoint")
+			}
+		}
+
+		resp, err := http.Get(server.URL + "/apps/memory/" + telaDocs[1].NameHdr)
+		if assert.NoError(t, err, "Handler request should not error: %s", err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "Handler should serve DOC")
+		}
+
+		// Handlers are independent of each other
+		commitHandler, err := NewHandlerAtCommit(indexSCID, indexSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler at commit should not error: %s", err) {
+			assert.Equal(t, indexSCID, commitHandler.Info().Commit, "Handler commit should be equal")
+			assert.NotEqual(t, handler.path, commitHandler.path, "Handlers should have their own directory")
+		}
+
+		_, err = NewHandlerAtCommit(indexSCID, fmt.Sprintf("%064x", 1101), endpoint)
+		assert.Error(t, err, "Creating handler at invalid commit should error")
+
+		_, err = NewHandler(docSCIDs[0], endpoint)
+		assert.Error(t, err, "Creating handler for DOC should error")
+		_, err = NewHandler("scid", endpoint)
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Creating handler with invalid SCID should error")
+
+		libSCID := fmt.Sprintf("%064x", 1100)
+		err = addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "handler" + TAG_LIBRARY, DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "Handler Library"}})
+		assert.NoError(t, err, "Adding library should not error: %s", err)
+		_, err = NewHandler(libSCID, endpoint)
+		assert.ErrorIs(t, err, ErrLibrary, "Creating handler for library should error")
+
+		entries, err := os.ReadDir(filepath.Join(datashards, "handler"))
+		assert.NoError(t, err, "Reading handler directory should not error: %s", err)
+		assert.Len(t, entries, 2, "Failed handlers should be removed")
+
+		// Settings can be set while handlers are created
+		updates, policy := UpdatesAllowed(), GetSignaturePolicy()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				AllowUpdates(updates)
+				SetSignaturePolicy(policy)
+			}()
+			go func() {
+				defer wg.Done()
+				h, err := NewHandler(indexSCID, endpoint)
+				if assert.NoError(t, err, "Creating handler while setting settings should not error: %s", err) {
+					h.Close()
+				}
+			}()
+		}
+		wg.Wait()
+
+		handler.Close()
+		handler.Close()
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusGone, rec.Code, "Closed handler should not serve content")
+		_, err = os.Stat(handler.path)
+		assert.True(t, os.IsNotExist(err), "Closed handler files should be removed")
+
+		ShutdownTELA()
+		if commitHandler != nil {
+			_, err = os.Stat(commitHandler.path)
+			assert.True(t, os.IsNotExist(err), "Handler files should be removed with TELA shutdown")
+		}
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		index, err := readFile(telaDocs[0].filePath)
+		assert.NoError(t, err, "Reading index should not error: %s", err)
+
+		// Nothing is requested until the FS is used
+		counter := &countingDaemon{MemoryDaemon: memory, calls: map[string]int{}, variables: map[string]int{}}
+		SetDaemon(counter)
+		indexFS := NewINDEXFS(indexSCID, endpoint)
+		assert.Empty(t, counter.calls, "FS should not request content when created")
+
+		// DOC code is only requested when a file is read
+		_, err = fs.ReadDir(indexFS, ".")
+		assert.NoError(t, err, "Reading FS directory should not error: %s", err)
+		for _, scid := range docSCIDs {
+			assert.Zero(t, counter.variables[scid], "SCID %s code should not be requested until it is read", scid)
+		}
+
+		_, err = fs.ReadFile(indexFS, telaDocs[1].NameHdr)
+		assert.NoError(t, err, "Reading FS file should not error: %s", err)
+		assert.Equal(t, 1, counter.variables[docSCIDs[1]], "Read file code should be requested")
+		assert.Zero(t, counter.variables[docSCIDs[0]], "Unread file code should not be requested")
+
+		err = fstest.TestFS(indexFS, telaDocs[0].NameHdr, telaDocs[1].NameHdr, telaDocs[2].NameHdr)
+		assert.NoError(t, err, "FS should be valid: %s", err)
+		assert.Equal(t, 1, counter.calls[indexSCID], "INDEX should have one GetSC call")
+		for _, scid := range docSCIDs {
+			assert.Equal(t, 1, counter.variables[scid], "SCID %s code should be requested once", scid)
+		}
+		SetDaemon(memory)
+
+		b, err := fs.ReadFile(indexFS, telaDocs[0].NameHdr)
+		assert.NoError(t, err, "Reading FS file should not error: %s", err)
+		assert.Equal(t, index, string(b), "FS file should be equal to DOC code")
+
+		entrypoint, err := indexFS.Entrypoint()
+		assert.NoError(t, err, "FS entrypoint should not error: %s", err)
+		assert.Equal(t, telaDocs[0].NameHdr, entrypoint, "FS entrypoint should be DOC1")
+
+		info, err := fs.Stat(indexFS, telaDocs[0].NameHdr)
+		if assert.NoError(t, err, "Stat FS file should not error: %s", err) {
+			assert.True(t, info.ModTime().IsZero(), "FS ModTime should be zero")
+			assert.Equal(t, docSCIDs[0], info.Sys(), "FS file Sys should be DOC SCID")
+			assert.Equal(t, int64(len(index)), info.Size(), "FS file size should be equal")
+			assert.Equal(t, fs.FileMode(0444), info.Mode(), "FS file should be read only")
+		}
+
+		_, err = indexFS.Open("missing.html")
+		assert.ErrorIs(t, err, fs.ErrNotExist, "Opening missing FS file should error")
+		_, err = indexFS.Open("../" + telaDocs[0].NameHdr)
+		assert.ErrorIs(t, err, fs.ErrInvalid, "Opening invalid FS path should error")
+		_, err = NewINDEXFS("scid", endpoint).Open(".")
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Opening FS with invalid SCID should error")
+		_, err = NewINDEXFS(docSCIDs[0], endpoint).Open(".")
+		assert.Error(t, err, "Opening FS of DOC should error")
+
+		// INDEX with subDirs and a library
+		fsSCID := fmt.Sprintf("%064x", 1200)
+		libSCID := fmt.Sprintf("%064x", 1201)
+		pageSCID := fmt.Sprintf("%064x", 1202)
+		err = addMemoryINDEX(memory, libSCID, owner, INDEX{DURL: "fs" + TAG_LIBRARY, DOCs: docSCIDs[1:2], Headers: Headers{NameHdr: "FS Library"}})
+		assert.NoError(t, err, "Adding library should not error: %s", err)
+		page := DOC{DocType: DOC_HTML, SubDir: "sub/dir", DURL: "fs.tela", Code: "<p>page</p>", Headers: Headers{NameHdr: "page.html"}}
+		_, page.CheckC, page.CheckS, err = ParseSignature(wallet.SignData([]byte(page.Code)))
+		assert.NoError(t, err, "Signing DOC should not error: %s", err)
+		err = addMemoryDOC(memory, pageSCID, owner, page)
+		assert.NoError(t, err, "Adding DOC should not error: %s", err)
+		err = addMemoryINDEX(memory, fsSCID, owner, INDEX{DURL: "fs.tela", DOCs: []string{docSCIDs[0], pageSCID, libSCID}, Headers: Headers{NameHdr: "FS"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		err = updateMemoryINDEX(memory, fsSCID, fmt.Sprintf("%064x", 1203), 5, 1, INDEX{DURL: "fs.tela", DOCs: docSCIDs[:1], Headers: Headers{NameHdr: "FS"}})
+		assert.NoError(t, err, "Updating INDEX should not error: %s", err)
+
+		installFS := NewINDEXFSAtCommit(fsSCID, fsSCID, endpoint)
+		var paths []string
+		err = fs.WalkDir(installFS, ".", func(path string, d fs.DirEntry, err error) error {
+			paths = append(paths, path)
+			return err
+		})
+		assert.NoError(t, err, "Walking FS should not error: %s", err)
+		assert.Equal(t, []string{".", "fs.lib", "fs.lib/main.js", "index.html", "sub", "sub/dir", "sub/dir/page.html"}, paths, "FS should have DOC subDirs and libraries")
+
+		b, err = fs.ReadFile(installFS, "sub/dir/page.html")
+		assert.NoError(t, err, "Reading FS file should not error: %s", err)
+		assert.Equal(t, "<p>page</p>", string(b), "FS file should be equal to DOC code")
+
+		info, err = fs.Stat(installFS, "fs.lib")
+		if assert.NoError(t, err, "Stat FS library should not error: %s", err) {
+			assert.True(t, info.IsDir(), "FS library should be a directory")
+			assert.Equal(t, libSCID, info.Sys(), "FS library Sys should be library SCID")
+		}
+
+		err = fstest.TestFS(installFS, "index.html", "sub/dir/page.html", "fs.lib/main.js")
+		assert.NoError(t, err, "FS should be valid: %s", err)
+
+		liveFS := NewINDEXFS(fsSCID, endpoint)
+		entries, err := fs.ReadDir(liveFS, ".")
+		if assert.NoError(t, err, "Reading FS directory should not error: %s", err) && assert.Len(t, entries, 1, "FS should use current commit") {
+			info, err = entries[0].Info()
+			assert.NoError(t, err, "FS entry info should not error: %s", err)
+			assert.Equal(t, int64(len(index)), info.Size(), "FS entry should be current commit")
+		}
+
+		// FS can be served without cloning
+		server := httptest.NewServer(http.FileServer(http.FS(installFS)))
+		defer server.Close()
+		resp, err := http.Get(server.URL + "/sub/dir/page.html")
+		if assert.NoError(t, err, "FS request should not error: %s", err) {
+			b, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "FS request should be successful")
+			assert.Equal(t, "<p>page</p>", string(b), "FS should serve DOC code")
+		}
+
+		_, err = os.Stat(filepath.Join(datashards, "tela", "fs.tela"))
+		assert.True(t, os.IsNotExist(err), "FS should not write files")
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		t.Cleanup(ShutdownTELA)
+
+		script, err := readFile(telaDocs[1].filePath)
+		assert.NoError(t, err, "Reading script should not error: %s", err)
+
+		handler, err := NewHandler(indexSCID, endpoint)
+		if !assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			return
+		}
+
+		get := func(h http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			for k, v := range header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			return rec
+		}
+
+		// Content-Type is from docType
+		for i, ctype := range []string{"text/html; charset=utf-8", "text/javascript; charset=utf-8", "text/css; charset=utf-8"} {
+			rec := get(handler, "/"+telaDocs[i].NameHdr, nil)
+			assert.Equal(t, http.StatusOK, rec.Code, "DOC request should be successful")
+			assert.Equal(t, ctype, rec.Header().Get("Content-Type"), "Content-Type should be from docType")
+			assert.Equal(t, `"`+docSCIDs[i]+`"`, rec.Header().Get("ETag"), "ETag should be DOC SCID")
+			assert.Equal(t, CACHE_CONTROL_LIVE, rec.Header().Get("Cache-Control"), "Live content should be revalidated")
+		}
+
+		rec := get(handler, "/", nil)
+		assert.Equal(t, `"`+docSCIDs[0]+`"`, rec.Header().Get("ETag"), "Root should serve index.html DOC")
+
+		rec = get(handler, "/"+telaDocs[1].NameHdr, map[string]string{"If-None-Match": `"` + docSCIDs[1] + `"`})
+		assert.Equal(t, http.StatusNotModified, rec.Code, "Matching ETag should not be modified")
+
+		// Compression
+		rec = get(handler, "/"+telaDocs[1].NameHdr, map[string]string{"Accept-Encoding": "br, gzip"})
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), "Content should be compressed")
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"), "Compressed content should vary by encoding")
+		assert.Equal(t, `"`+docSCIDs[1]+`-gzip"`, rec.Header().Get("ETag"), "Compressed ETag should differ")
+		assert.Less(t, rec.Body.Len(), len(script), "Content should be compressed")
+		gz, err := gzip.NewReader(rec.Body)
+		if assert.NoError(t, err, "Reading compressed content should not error: %s", err) {
+			b, _ := io.ReadAll(gz)
+			assert.Equal(t, script, string(b), "Decompressed content should be equal")
+		}
+
+		rec = get(handler, "/"+telaDocs[1].NameHdr, map[string]string{"Accept-Encoding": "gzip", "If-None-Match": `"` + docSCIDs[1] + `-gzip"`})
+		assert.Equal(t, http.StatusNotModified, rec.Code, "Matching compressed ETag should not be modified")
+
+		rec = get(handler, "/"+telaDocs[1].NameHdr, map[string]string{"Accept-Encoding": "gzip;q=0"})
+		assert.Empty(t, rec.Header().Get("Content-Encoding"), "Content should not be compressed when refused")
+		assert.Equal(t, script, rec.Body.String(), "Uncompressed content should be equal")
+
+		// Content at a commit will not change
+		pinned, err := NewHandlerAtCommit(indexSCID, indexSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler at commit should not error: %s", err) {
+			rec = get(pinned, "/"+telaDocs[2].NameHdr, nil)
+			assert.Equal(t, CACHE_CONTROL_PINNED, rec.Header().Get("Cache-Control"), "Content at a commit should be immutable")
+			pinned.Close()
+		}
+
+		assert.Equal(t, "image/svg+xml", docContentType(DOC_STATIC, "icon.svg"), "Static Content-Type should be from extension")
+		assert.Empty(t, docContentType(DOC_STATIC, "file"), "Static Content-Type without extension should be detected")
+		assert.True(t, compressible("text/plain; charset=utf-8"), "Text should be compressible")
+		assert.False(t, compressible("image/png"), "Images should not be compressible")
+	})
+
+	t.Run("Security", func(t *testing.T) {
+		t.Cleanup(func() {
+			SetSecurityPolicy(SecurityPolicy{})
+			ShutdownTELA()
+		})
+
+		handler, err := NewHandler(indexSCID, endpoint)
+		if !assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			return
+		}
+
+		get := func() http.Header {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+telaDocs[0].NameHdr, nil))
+			assert.Equal(t, http.StatusOK, rec.Code, "Request should be successful")
+
+			return rec.Header()
+		}
+
+		// Defaults block all other origins except wallet connections
+		header := get()
+		csp := header.Get("Content-Security-Policy")
+		assert.Contains(t, csp, "default-src 'self';", "CSP should only allow self by default")
+		assert.Contains(t, csp, "connect-src 'self' ws://localhost:44326 ws://127.0.0.1:44326;", "CSP should only allow XSWD connections to other origins by default")
+		assert.Contains(t, csp, "object-src 'none'", "CSP should block objects")
+		assert.Equal(t, DEFAULT_REFERRER_POLICY, header.Get("Referrer-Policy"), "Referrer-Policy should be default")
+		assert.Equal(t, DEFAULT_PERMISSIONS_POLICY, header.Get("Permissions-Policy"), "Permissions-Policy should be default")
+		assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"), "X-Content-Type-Options should be nosniff")
+
+		// Policy applies to content already being served
+		err = SetSecurityPolicy(SecurityPolicy{Allowlist: []string{"https://example.com"}, ReferrerPolicy: "same-origin"})
+		assert.NoError(t, err, "Setting security policy should not error: %s", err)
+		header = get()
+		assert.Contains(t, header.Get("Content-Security-Policy"), "connect-src 'self' https://example.com ws://localhost:44326 ws://127.0.0.1:44326;", "CSP should allow allowlisted origins")
+		assert.Equal(t, "same-origin", header.Get("Referrer-Policy"), "Referrer-Policy should be set")
+
+		// Per app exceptions
+		err = SetAppOrigins(indexSCID, append(XSWD_ORIGINS, DaemonOrigins("127.0.0.1:10102")...)...)
+		assert.NoError(t, err, "Setting app origins should not error: %s", err)
+		csp = get().Get("Content-Security-Policy")
+		assert.Contains(t, csp, "connect-src 'self' https://example.com ws://localhost:44326 ws://127.0.0.1:44326 http://127.0.0.1:10102 ws://127.0.0.1:10102;", "CSP should allow app origins")
+		assert.Contains(t, csp, "default-src 'self' https://example.com ws://localhost:44326", "CSP should allow app origins for all sources")
+		assert.NotContains(t, GetSecurityPolicy().csp(docSCIDs[0], endpoint), "10102", "App origins should not apply to other apps")
+		assert.Len(t, GetSecurityPolicy().Apps, 1, "Policy should have app origins")
+
+		err = SetAppOrigins(indexSCID)
+		assert.NoError(t, err, "Removing app origins should not error: %s", err)
+		assert.NotContains(t, get().Get("Content-Security-Policy"), "10102", "App origins should be removed")
+
+		// Policy is copied
+		policy := GetSecurityPolicy()
+		policy.Allowlist[0] = "https://changed.com"
+		assert.Equal(t, "https://example.com", GetSecurityPolicy().Allowlist[0], "Policy should not be modified by caller")
+
+		// XSWD and the daemon an app was cloned from are allowed for connections by default
+		err = SetSecurityPolicy(SecurityPolicy{})
+		assert.NoError(t, err, "Setting security policy should not error: %s", err)
+		csp = GetSecurityPolicy().csp(indexSCID, "127.0.0.1:20000")
+		assert.Contains(t, csp, "connect-src 'self' ws://localhost:44326 ws://127.0.0.1:44326 http://127.0.0.1:20000 ws://127.0.0.1:20000;", "CSP should allow XSWD and daemon connections by default")
+		assert.Contains(t, csp, "default-src 'self';", "Wallet origins should only be allowed for connections")
+		err = SetSecurityPolicy(SecurityPolicy{NoWalletOrigins: true})
+		assert.NoError(t, err, "Setting security policy should not error: %s", err)
+		assert.Contains(t, get().Get("Content-Security-Policy"), "connect-src 'self';", "CSP should not allow wallet origins when disabled")
+
+		err = SetSecurityPolicy(SecurityPolicy{ContentSecurityPolicy: "default-src 'none'"})
+		assert.NoError(t, err, "Setting security policy should not error: %s", err)
+		assert.Equal(t, "default-src 'none'", get().Get("Content-Security-Policy"), "Custom CSP should be used")
+
+		err = SetSecurityPolicy(SecurityPolicy{Disabled: true})
+		assert.NoError(t, err, "Setting security policy should not error: %s", err)
+		header = get()
+		for _, key := range []string{"Content-Security-Policy", "Referrer-Policy", "Permissions-Policy", "X-Content-Type-Options"} {
+			assert.Empty(t, header.Get(key), "%s should not be sent when disabled", key)
+		}
+
+		for _, origin := range []string{"example.com", "ftp://example.com", "https://example.com/path", "https://example.com; script-src *", "https://user@example.com", "https://"} {
+			err = SetSecurityPolicy(SecurityPolicy{Allowlist: []string{origin}})
+			assert.Error(t, err, "Invalid origin %q should error", origin)
+			err = SetAppOrigins(indexSCID, origin)
+			assert.Error(t, err, "Invalid app origin %q should error", origin)
+		}
+
+		err = SetSecurityPolicy(SecurityPolicy{Apps: map[string][]string{"scid": {"https://example.com"}}})
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Invalid app SCID should error")
+		err = SetSecurityPolicy(SecurityPolicy{ReferrerPolicy: "no-referrer\r\nX-Injected: true"})
+		assert.Error(t, err, "Security header with line break should error")
+		err = SetAppOrigins("scid")
+		assert.ErrorIs(t, err, ErrInvalidSCID, "Invalid app SCID should error")
+		assert.True(t, GetSecurityPolicy().Disabled, "Invalid policy should not be set")
+
+		_, err = New(Config{Path: filepath.Join(testPath, "security"), Security: SecurityPolicy{Allowlist: []string{"example.com"}}})
+		assert.Error(t, err, "New with invalid security policy should error")
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		t.Cleanup(func() {
+			RenderMarkdown(true)
+			ShutdownTELA()
+		})
+
+		// INDEX with a TELA-MD-1 entrypoint linking to a DOC in a subDir
+		mdSCID := fmt.Sprintf("%064x", 1300)
+		readmeSCID := fmt.Sprintf("%064x", 1301)
+		guideSCID := fmt.Sprintf("%064x", 1302)
+		docs := map[string]DOC{
+			readmeSCID: {DocType: DOC_MD, DURL: "md.tela", Code: "# Read Me\n\nSee the [guide](docs/guide) and [top](/docs/guide.md#top), [site](https://example.com) [bad](javascript:alert(1)).\n\n<script>alert(1)</script>", Headers: Headers{NameHdr: "README.md"}},
+			guideSCID:  {DocType: DOC_MD, SubDir: "docs", DURL: "md.tela", Code: "Guide\n=====\n\nBack to [readme](../README.md).", Headers: Headers{NameHdr: "guide.md"}},
+		}
+
+		for scid, doc := range docs {
+			_, doc.CheckC, doc.CheckS, err = ParseSignature(wallet.SignData([]byte(doc.Code)))
+			assert.NoError(t, err, "Signing DOC should not error: %s", err)
+			err = addMemoryDOC(memory, scid, owner, doc)
+			assert.NoError(t, err, "Adding DOC should not error: %s", err)
+		}
+
+		err = addMemoryINDEX(memory, mdSCID, owner, INDEX{DURL: "md.tela", DOCs: []string{readmeSCID, guideSCID}, Headers: Headers{NameHdr: "Markdown"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		handler, err := NewHandler(mdSCID, endpoint)
+		if !assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			return
+		}
+
+		get := func(target string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			return rec
+		}
+
+		rec := get("/")
+		assert.Equal(t, http.StatusFound, rec.Code, "Root should redirect to markdown entrypoint")
+		assert.Equal(t, "./README.md", rec.Header().Get("Location"), "Root should redirect to markdown entrypoint")
+
+		// Rendered to sanitized HTML
+		rec = get("/README.md")
+		assert.Equal(t, http.StatusOK, rec.Code, "Markdown request should be successful")
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"), "Markdown should be served as HTML")
+		assert.Equal(t, `"`+readmeSCID+"-html-"+handler.Info().Commit+`"`, rec.Header().Get("ETag"), "Rendered ETag should include the INDEX commit")
+		body := rec.Body.String()
+		assert.Contains(t, body, "<title>Read Me</title>", "Title should be first heading")
+		assert.Contains(t, body, MARKDOWN_STYLE, "Rendered markdown should have default style")
+		assert.Contains(t, body, `<a href="./docs/guide.md">guide</a>`, "Relative link should resolve to DOC")
+		assert.Contains(t, body, `<a href="./docs/guide.md#top">top</a>`, "Absolute link should be relative to DOC")
+		assert.Contains(t, body, `<a href="https://example.com">site</a>`, "External link should be kept")
+		assert.NotContains(t, body, "javascript:", "Unsafe link should not be rendered")
+		assert.NotContains(t, body, "<script>", "Raw HTML should be escaped")
+		assert.Contains(t, body, "&lt;script&gt;", "Raw HTML should be escaped")
+
+		rec = get("/docs/guide.md")
+		assert.Contains(t, rec.Body.String(), "<title>Guide</title>", "Title should be setext heading")
+		assert.Contains(t, rec.Body.String(), `<a href="../README.md">readme</a>`, "Parent link should resolve")
+
+		// Raw markdown
+		rec = get("/README.md?raw")
+		assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"), "Raw markdown should be served as markdown")
+		assert.Equal(t, docs[readmeSCID].Code, rec.Body.String(), "Raw markdown should be equal to DOC code")
+
+		RenderMarkdown(false)
+		assert.False(t, MarkdownRendered(), "Markdown should not be rendered")
+		rec = get("/README.md")
+		assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"), "Markdown should be served raw when not rendered")
+		assert.Equal(t, `"`+readmeSCID+`"`, rec.Header().Get("ETag"), "Raw ETag should be DOC SCID")
+		RenderMarkdown(true)
+
+		// Rendering
+		render := func(source string) string {
+			return renderMarkdown(source, "test.md", func(link string) string { return "./" + link })
+		}
+
+		assert.Contains(t, render("text"), "<title>test.md</title>", "Title should be name without a heading")
+		assert.Contains(t, render("- one\n- two\n  - nested\n- three\n"), "<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul></li>\n<li>three</li>\n</ul>", "Tight list should be rendered")
+		assert.Contains(t, render("1. a\n\n2. b\n"), "<ol>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ol>", "Loose list should be rendered")
+		assert.Contains(t, render("**bold** _em_ `<b>` snake_case_word"), "<p><strong>bold</strong> <em>em</em> <code>&lt;b&gt;</code> snake_case_word</p>", "Inline markdown should be rendered")
+		assert.Contains(t, render("```go\nfunc main() {}\n```"), "<pre><code class=\"language-go\">func main() {}\n</code></pre>", "Fenced code should be rendered")
+		assert.Contains(t, render("| a | b |\n|:--|--:|\n| 1 | 2 |"), `<td style="text-align:right">2</td>`, "Table should be rendered")
+		assert.Contains(t, render("[ref] ![img](a.png)\n\n[ref]: page.md"), `<a href="./page.md">ref</a> <img src="./a.png" alt="img">`, "Reference links and images should be resolved")
+		assert.Contains(t, render("<https://example.com> <me@example.com>"), `<a href="https://example.com">https://example.com</a> <a href="mailto:me@example.com">me@example.com</a>`, "Autolinks should be rendered")
+		assert.NotContains(t, render(`[x](data:text/html,<script>alert(1)</script>) <img src=x onerror=alert(1)>`), "<script>", "Unsafe content should not be rendered")
+		assert.NotContains(t, render(`[x](data:text/html,x) <img src=x onerror=alert(1)>`), "<img", "Unsafe content should not be rendered")
+
+		host, err := New(Config{Path: filepath.Join(testPath, "markdown"), RawMD: true})
+		if assert.NoError(t, err, "New should not error: %s", err) {
+			assert.False(t, host.MarkdownRendered(), "New with RawMD should not render markdown")
+		}
+	})
+
+	t.Run("Parts", func(t *testing.T) {
+		t.Cleanup(ShutdownTELA)
+
+		// Large file with multibyte characters that does not compress enough to fit a single DOC
+		var large strings.Builder
+		for i := 0; GetCodeSizeInKB(large.String()) < MAX_DOC_CODE_SIZE*2.5; i++ {
+			fmt.Fprintf(&large, "const value%d = \"ünïcödé %x\";\n", i, sha256.Sum256([]byte(fmt.Sprint(i))))
+		}
+
+		code := strings.TrimSpace(large.String())
+		doc := DOC{DocType: DOC_JS, DURL: "parts.tela", Code: code, Headers: Headers{NameHdr: "bundle.js", DescrHdr: "Large bundle"}}
+		_, err := NewInstallArgs(&doc)
+		assert.Error(t, err, "Large DOC should not install as a single DOC")
+
+		parts, err := SplitDOC(doc)
+		if !assert.NoError(t, err, "Splitting DOC should not error: %s", err) || !assert.Len(t, parts, 3, "Large DOC should be split into parts") {
+			return
+		}
+
+		var joined string
+		partSCIDs := make([]string, len(parts))
+		for i := range parts {
+			assert.Equal(t, uint64(i+1), parts[i].Part, "Part should be numbered")
+			assert.Equal(t, uint64(len(parts)), parts[i].Parts, "Part should have total parts")
+			assert.Equal(t, doc.NameHdr, parts[i].NameHdr, "Part should share nameHdr")
+			assert.True(t, utf8.ValidString(parts[i].Code), "Part should not split characters")
+			joined += parts[i].Code
+
+			_, parts[i].CheckC, parts[i].CheckS, err = ParseSignature(wallet.SignData([]byte(parts[i].Code)))
+			assert.NoError(t, err, "Signing part should not error: %s", err)
+			args, err := NewInstallArgs(&parts[i])
+			if assert.NoError(t, err, "Part should install: %s", err) {
+				sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+				assert.Contains(t, sc, fmt.Sprintf(`STORE("docPart", %d)`, i+1), "Part should store its part number")
+				assert.Contains(t, sc, fmt.Sprintf(`STORE("docParts", %d)`, len(parts)), "Part should store its total parts")
+				_, err = EqualSmartContracts(TELA_DOC_1, sc)
+				assert.NoError(t, err, "Part should parse as TELA-DOC-1: %s", err)
+			}
+
+			partSCIDs[i] = fmt.Sprintf("%064x", 1400+i)
+			err = addMemoryDOC(memory, partSCIDs[i], owner, parts[i])
+			assert.NoError(t, err, "Adding part should not error: %s", err)
+		}
+
+		assert.Equal(t, code, joined, "Parts should join to the file")
+
+		small, err := SplitDOC(DOC{DocType: DOC_JS, Code: "small", Headers: Headers{NameHdr: "small.js"}})
+		assert.NoError(t, err, "Splitting small DOC should not error: %s", err)
+		assert.Equal(t, []DOC{{DocType: DOC_JS, Code: "small", Headers: Headers{NameHdr: "small.js"}}}, small, "Small DOC should not be split")
+		_, err = SplitDOC(parts[0])
+		assert.Error(t, err, "Splitting a part should error")
+		_, err = NewInstallArgs(&DOC{DocType: DOC_JS, Code: "part", Part: 3, Parts: 2, Headers: Headers{NameHdr: "part.js"}, Signature: Signature{CheckC: "c", CheckS: "s"}})
+		assert.Error(t, err, "Invalid part should not install")
+		_, err = InstallDOC(nil, 2, &doc)
+		assert.Error(t, err, "InstallDOC without wallet should error")
+
+		info, err := GetDOCInfo(partSCIDs[1], endpoint)
+		assert.NoError(t, err, "Getting part info should not error: %s", err)
+		assert.Equal(t, uint64(2), info.Part, "Part info should have part number")
+		assert.Equal(t, uint64(3), info.Parts, "Part info should have total parts")
+
+		// Parts are joined in part order regardless of their order in the INDEX
+		partsSCID := fmt.Sprintf("%064x", 1410)
+		err = addMemoryINDEX(memory, partsSCID, owner, INDEX{DURL: "parts.tela", DOCs: []string{partSCIDs[2], docSCIDs[0], partSCIDs[0], partSCIDs[1]}, Headers: Headers{NameHdr: "Parts"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		handler, err := NewHandler(partsSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler with parts should not error: %s", err) {
+			assert.Equal(t, "bundle.js", handler.Info().Entrypoint, "Chunked file should be entrypoint")
+			assert.Len(t, handler.Info().Verifications, 4, "Each part should be verified")
+			for _, v := range handler.Info().Verifications {
+				assert.True(t, v.Verified, "Part %s should be verified", v.SCID)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bundle.js", nil))
+			assert.Equal(t, code, rec.Body.String(), "Served file should be joined from parts")
+			assert.NotContains(t, partSCIDs, strings.Trim(rec.Header().Get("ETag"), `"`), "ETag should identify all parts")
+			handler.Close()
+		}
+
+		b, err := fs.ReadFile(NewINDEXFS(partsSCID, endpoint), "bundle.js")
+		assert.NoError(t, err, "Reading chunked FS file should not error: %s", err)
+		assert.Equal(t, code, string(b), "FS file should be joined from parts")
+
+		// Missing and duplicated parts
+		for i, docs := range [][]string{{partSCIDs[0], partSCIDs[2]}, {partSCIDs[0], partSCIDs[1], partSCIDs[1], partSCIDs[2]}} {
+			scid := fmt.Sprintf("%064x", 1411+i)
+			err = addMemoryINDEX(memory, scid, owner, INDEX{DURL: fmt.Sprintf("parts%d.tela", i), DOCs: docs, Headers: Headers{NameHdr: "Parts"}})
+			assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+			_, err = NewHandler(scid, endpoint)
+			assert.ErrorIs(t, err, ErrParts, "Cloning incomplete parts should error")
+			_, err = fs.ReadFile(NewINDEXFS(scid, endpoint), "bundle.js")
+			assert.ErrorIs(t, err, ErrParts, "Reading incomplete FS parts should error")
+		}
+
+		// Each part's signature is verified
+		forged := parts[1]
+		forged.Code = strings.Replace(forged.Code, "const", "let", 1)
+		err = addMemoryDOC(memory, fmt.Sprintf("%064x", 1413), owner, forged)
+		assert.NoError(t, err, "Adding DOC should not error: %s", err)
+		forgedSCID := fmt.Sprintf("%064x", 1414)
+		err = addMemoryINDEX(memory, forgedSCID, owner, INDEX{DURL: "forged.tela", DOCs: []string{partSCIDs[0], fmt.Sprintf("%064x", 1413), partSCIDs[2]}, Headers: Headers{NameHdr: "Parts"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+		SetSignaturePolicy(SIGNATURE_ENFORCE)
+		_, err = NewHandler(forgedSCID, endpoint)
+		assert.ErrorIs(t, err, ErrSignature, "Cloning part with invalid signature should error")
+		SetSignaturePolicy(SIGNATURE_WARN)
+
+		err = Clone(partSCIDs[0], endpoint)
+		assert.ErrorIs(t, err, ErrParts, "Cloning a part should error")
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		t.Cleanup(ShutdownTELA)
+
+		// Compressible code larger than a DOC can hold raw
+		var large strings.Builder
+		for i := 0; GetCodeSizeInKB(large.String()) < MAX_DOC_CODE_SIZE*1.5; i++ {
+			fmt.Fprintf(&large, "<p class=\"row\">Row %d</p>\n", i)
+		}
+
+		code := strings.TrimSpace(large.String())
+		doc := DOC{DocType: DOC_HTML, DURL: "encoded.tela", Code: code, Headers: Headers{NameHdr: "index.html"}}
+		_, doc.CheckC, doc.CheckS, err = ParseSignature(wallet.SignData([]byte(code)))
+		assert.NoError(t, err, "Signing DOC should not error: %s", err)
+
+		args, err := NewInstallArgs(&doc)
+		if assert.NoError(t, err, "Compressed DOC should install: %s", err) {
+			sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+			assert.Contains(t, sc, `STORE("docEncoding", "gzip+base64")`, "DOC should store its encoding")
+			assert.NotContains(t, sc, "Row 1", "DOC code should be encoded")
+			_, err = EqualSmartContracts(TELA_DOC_1, sc)
+			assert.NoError(t, err, "Encoded DOC should parse as TELA-DOC-1: %s", err)
+		}
+
+		raw := doc
+		raw.Encoding = DOC_ENCODING_NONE
+		_, err = NewInstallArgs(&raw)
+		assert.ErrorContains(t, err, fmt.Sprintf("docCode size is to large, max %.2fKB", MAX_DOC_CODE_SIZE), "Raw DOC should exceed max docCode size")
+
+		small := DOC{DocType: DOC_HTML, DURL: "encoded.tela", Code: "<p>small</p>", Signature: doc.Signature, Headers: Headers{NameHdr: "small.html"}}
+		args, err = NewInstallArgs(&small)
+		if assert.NoError(t, err, "Small DOC should install: %s", err) {
+			sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+			assert.NotContains(t, sc, "docEncoding", "Small DOC should not be encoded")
+		}
+
+		// Encoded size is reported with the raw size
+		var random strings.Builder
+		for i := 0; GetCodeSizeInKB(random.String()) < MAX_DOC_CODE_SIZE*2; i++ {
+			fmt.Fprintf(&random, "%x", sha256.Sum256([]byte(fmt.Sprint(i))))
+		}
+
+		forced := DOC{DocType: DOC_HTML, DURL: "encoded.tela", Code: random.String(), Encoding: DOC_ENCODING_GZIP, Signature: doc.Signature, Headers: Headers{NameHdr: "random.html"}}
+		_, err = NewInstallArgs(&forced)
+		assert.ErrorContains(t, err, "(encoded ", "Size error should report encoded size")
+		assert.ErrorContains(t, err, ", raw ", "Size error should report raw size")
+		forced.Encoding = "zip"
+		_, err = NewInstallArgs(&forced)
+		assert.Error(t, err, "Unknown encoding should not install")
+
+		// Cloned content is decoded and its signature verified over the original code
+		encodedSCID := fmt.Sprintf("%064x", 1500)
+		indexSCID := fmt.Sprintf("%064x", 1501)
+		err = addMemoryDOC(memory, encodedSCID, owner, doc)
+		assert.NoError(t, err, "Adding DOC should not error: %s", err)
+		err = addMemoryINDEX(memory, indexSCID, owner, INDEX{DURL: "encoded.tela", DOCs: []string{encodedSCID}, Headers: Headers{NameHdr: "Encoded"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		info, err := GetDOCInfo(encodedSCID, endpoint)
+		assert.NoError(t, err, "Getting DOC info should not error: %s", err)
+		assert.Equal(t, DOC_ENCODING_GZIP, info.Encoding, "DOC info should have encoding")
+
+		handler, err := NewHandler(indexSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			if assert.Len(t, handler.Info().Verifications, 1, "DOC should be verified") {
+				assert.True(t, handler.Info().Verifications[0].Verified, "Encoded DOC signature should verify")
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+			assert.Equal(t, code, rec.Body.String(), "Served DOC should be decoded")
+			handler.Close()
+		}
+
+		b, err := fs.ReadFile(NewINDEXFS(indexSCID, endpoint), "index.html")
+		assert.NoError(t, err, "Reading encoded FS file should not error: %s", err)
+		assert.Equal(t, code, string(b), "FS file should be decoded")
+
+		// Decoded size is limited
+		bomb, _, err := encodeDocCode(strings.Repeat("0", int(MAX_DOC_DECODED_SIZE*1024)+1), DOC_ENCODING_GZIP)
+		assert.NoError(t, err, "Encoding should not error: %s", err)
+		_, err = decodeDocCode(bomb, DOC_ENCODING_GZIP)
+		assert.Error(t, err, "Decoding over max size should error")
+		_, err = decodeDocCode("not base64", DOC_ENCODING_GZIP)
+		assert.Error(t, err, "Decoding invalid code should error")
+
+		// Raw text is trimmed and encoded content is parsed exactly as it was installed, the same as when it is verified
+		for encoding, want := range map[string]string{DOC_ENCODING_NONE: "<p>text</p>", DOC_ENCODING_BASE64: "\n  <p>text</p>\n\n"} {
+			padded := DOC{DocType: DOC_HTML, DURL: "encoded.tela", Code: "\n  <p>text</p>\n\n", Encoding: encoding, Signature: sizingSignature(), Headers: Headers{NameHdr: "padded.html"}}
+			args, err := NewInstallArgs(&padded)
+			if !assert.NoError(t, err, "Padded %s DOC should install: %s", encoding, err) {
+				continue
+			}
+
+			sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+			content, err := parseTELADoc(sc+"\n", DOC_HTML, encoding)
+			assert.NoError(t, err, "Parsing %s DOC should not error: %s", encoding, err)
+			assert.Equal(t, want, content, "Parsed %s DOC should be equal", encoding)
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		t.Cleanup(ShutdownTELA)
+
+		// Binary content that does not compress, with whitespace at its start and end
+		var random strings.Builder
+		random.WriteString(" \x89PNG\r\n\x1a\n")
+		for i := 0; random.Len() < 40*1024; i++ {
+			sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+			random.Write(sum[:])
+		}
+		random.WriteString("\x00\n\t ")
+
+		binary := random.String()
+		small := binary[:4096] + "\n "
+		assert.True(t, IsBinaryCode(small), "Binary content should be binary")
+		assert.False(t, IsBinaryCode("<p>Hello</p>"), "Text content should not be binary")
+		assert.True(t, IsBinaryCode("text\x00"), "Content with NUL should be binary")
+
+		doc := DOC{DocType: DOC_STATIC, DURL: "binary.tela", Code: small, Headers: Headers{NameHdr: "image.png"}}
+		_, doc.CheckC, doc.CheckS, err = ParseSignature(wallet.SignData([]byte(small)))
+		assert.NoError(t, err, "Signing DOC should not error: %s", err)
+
+		args, err := NewInstallArgs(&doc)
+		if assert.NoError(t, err, "Binary DOC should install: %s", err) {
+			sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+			assert.Contains(t, sc, `STORE("docEncoding", "base64")`, "Binary DOC should be base64 encoded")
+			_, err = EqualSmartContracts(TELA_DOC_1, sc)
+			assert.NoError(t, err, "Binary DOC should parse as TELA-DOC-1: %s", err)
+		}
+
+		compressible := doc
+		compressible.Code = strings.Repeat("\x00\x01\x02\x03", 1024)
+		args, err = NewInstallArgs(&compressible)
+		if assert.NoError(t, err, "Compressible binary DOC should install: %s", err) {
+			sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+			assert.Contains(t, sc, `STORE("docEncoding", "gzip+base64")`, "Compressible binary DOC should be gzip encoded")
+		}
+
+		invalid := doc
+		invalid.Encoding = DOC_ENCODING_NONE
+		_, err = NewInstallArgs(&invalid)
+		assert.Error(t, err, "Binary DOC should not install without encoding")
+		invalid.Encoding = ""
+		invalid.DocType = DOC_HTML
+		_, err = NewInstallArgs(&invalid)
+		assert.Error(t, err, "Binary DOC should not install as %s", DOC_HTML)
+
+		// Large binary content is split into base64 encoded parts
+		large := DOC{DocType: DOC_STATIC, DURL: "binary.tela", Code: binary, SubDir: "assets", Headers: Headers{NameHdr: "large.wasm"}}
+		parts, err := SplitDOC(large)
+		assert.NoError(t, err, "Splitting binary DOC should not error: %s", err)
+		assert.Greater(t, len(parts), 2, "Binary DOC should be split into parts")
+
+		docs := []string{fmt.Sprintf("%064x", 1600)}
+		err = addMemoryDOC(memory, docs[0], owner, doc)
+		assert.NoError(t, err, "Adding binary DOC should not error: %s", err)
+		for i, part := range parts {
+			assert.Equal(t, DOC_ENCODING_BASE64, part.Encoding, "Binary part should be base64 encoded")
+			_, part.CheckC, part.CheckS, err = ParseSignature(wallet.SignData([]byte(part.Code)))
+			assert.NoError(t, err, "Signing part should not error: %s", err)
+			scid := fmt.Sprintf("%064x", 1601+i)
+			err = addMemoryDOC(memory, scid, owner, part)
+			assert.NoError(t, err, "Adding binary part %d should not error: %s", part.Part, err)
+			docs = append(docs, scid)
+		}
+
+		indexSCID := fmt.Sprintf("%064x", 1620)
+		err = addMemoryINDEX(memory, indexSCID, owner, INDEX{DURL: "binary.tela", DOCs: docs, Headers: Headers{NameHdr: "Binary"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		// Cloned binary content is the exact original bytes
+		handler, err := NewHandler(indexSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			for _, v := range handler.Info().Verifications {
+				assert.True(t, v.Verified, "Binary DOC %s signature should verify: %s", v.Name, v.Error)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image.png", nil))
+			assert.Equal(t, small, rec.Body.String(), "Served binary DOC should be the original content")
+			assert.Equal(t, "image/png", rec.Header().Get("Content-Type"), "Served binary DOC should have its Content-Type")
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/large.wasm", nil))
+			assert.Equal(t, binary, rec.Body.String(), "Served binary parts should be the original content")
+			handler.Close()
+		}
+
+		b, err := fs.ReadFile(NewINDEXFS(indexSCID, endpoint), "assets/large.wasm")
+		assert.NoError(t, err, "Reading binary FS file should not error: %s", err)
+		assert.Equal(t, binary, string(b), "FS binary file should be the original content")
+
+		assert.Equal(t, "Binary files a and b differ\n", unifiedDiff("a", "b", small, binary), "Binary content should not be diffed by line")
+	})
+
+	t.Run("Escaped", func(t *testing.T) {
+		t.Cleanup(ShutdownTELA)
+
+		for _, s := range []string{"", "*/", "**/", "*\\/", "*\\\\/", "/* a */ b */", "*", "/", "\\*/", "a*\\", "*/*/*//**/"} {
+			escaped := escapeDocCode(s)
+			assert.NotContains(t, escaped, "*/", "Escaped %q should not contain a comment terminator", s)
+			assert.Equal(t, s, unescapeDocCode(escaped), "Escaped %q should be reversible", s)
+		}
+
+		assert.True(t, ContainsCommentEnd("let r = /a*/g"), "Regex should contain a comment terminator")
+		assert.False(t, ContainsCommentEnd("/* comment *\\/"), "Escaped code should not contain a comment terminator")
+
+		// JS and CSS with comment terminators in strings and regex
+		js := `const re = /ab*/g;
+const end = "*/";
+const escaped = "*\\/";
+/* comment */ console.log(re, end, escaped) // **/`
+		css := `.star::after { content: "*/"; }`
+
+		docs := []DOC{
+			{DocType: DOC_JS, DURL: "escaped.tela", Code: js, Headers: Headers{NameHdr: "main.js"}},
+			{DocType: DOC_CSS, DURL: "escaped.tela", Code: css, Headers: Headers{NameHdr: "style.css"}},
+		}
+
+		var scids []string
+		for i, doc := range docs {
+			_, doc.CheckC, doc.CheckS, err = ParseSignature(wallet.SignData([]byte(doc.Code)))
+			assert.NoError(t, err, "Signing DOC should not error: %s", err)
+
+			args, err := NewInstallArgs(&doc)
+			if assert.NoError(t, err, "DOC with comment terminators should install: %s", err) {
+				sc, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+				assert.Contains(t, sc, `STORE("docEncoding", "escaped")`, "DOC should store its encoding")
+				assert.Equal(t, 1, strings.Count(sc, "*/"), "DOC SC should only contain the terminator of its docCode")
+				_, err = EqualSmartContracts(TELA_DOC_1, sc)
+				assert.NoError(t, err, "Escaped DOC should parse as TELA-DOC-1: %s", err)
+			}
+
+			raw := doc
+			raw.Encoding = DOC_ENCODING_NONE
+			_, err = NewInstallArgs(&raw)
+			assert.Error(t, err, "DOC with comment terminators should not install as it is")
+
+			scid := fmt.Sprintf("%064x", 1700+i)
+			err = addMemoryDOC(memory, scid, owner, doc)
+			assert.NoError(t, err, "Adding DOC should not error: %s", err)
+			scids = append(scids, scid)
+		}
+
+		indexSCID := fmt.Sprintf("%064x", 1710)
+		err = addMemoryINDEX(memory, indexSCID, owner, INDEX{DURL: "escaped.tela", DOCs: scids, Headers: Headers{NameHdr: "Escaped"}})
+		assert.NoError(t, err, "Adding INDEX should not error: %s", err)
+
+		// Cloned content is the original content that was signed
+		handler, err := NewHandler(indexSCID, endpoint)
+		if assert.NoError(t, err, "Creating handler should not error: %s", err) {
+			if assert.Len(t, handler.Info().Verifications, 2, "DOCs should be verified") {
+				for _, v := range handler.Info().Verifications {
+					assert.True(t, v.Verified, "Escaped DOC %s signature should verify: %s", v.Name, v.Error)
+				}
+			}
+
+			for _, doc := range docs {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+doc.NameHdr, nil))
+				assert.Equal(t, doc.Code, rec.Body.String(), "Served %s should be the original content", doc.NameHdr)
+			}
+
+			handler.Close()
+		}
+
+		b, err := fs.ReadFile(NewINDEXFS(indexSCID, endpoint), "main.js")
+		assert.NoError(t, err, "Reading escaped FS file should not error: %s", err)
+		assert.Equal(t, js, string(b), "FS file should be the original content")
+
+		// Comment terminators split across parts
+		var large strings.Builder
+		for i := 0; GetCodeSizeInKB(large.String()) < MAX_DOC_CODE_SIZE*1.5; i++ {
+			fmt.Fprintf(&large, "/%x*/", sha256.Sum256([]byte(fmt.Sprint(i))))
+		}
+
+		split := DOC{DocType: DOC_JS, DURL: "escaped.tela", Code: large.String(), Encoding: DOC_ENCODING_ESCAPED, Headers: Headers{NameHdr: "large.js"}}
+		parts, err := SplitDOC(split)
+		assert.NoError(t, err, "Splitting DOC should not error: %s", err)
+		assert.Greater(t, len(parts), 1, "DOC should be split into parts")
+
+		var joined strings.Builder
+		for _, part := range parts {
+			_, part.CheckC, part.CheckS, err = ParseSignature(wallet.SignData([]byte(part.Code)))
+			assert.NoError(t, err, "Signing part should not error: %s", err)
+			_, err = NewInstallArgs(&part)
+			assert.NoError(t, err, "Escaped part %d should install: %s", part.Part, err)
+			joined.WriteString(part.Code)
+		}
+
+		assert.Equal(t, split.Code, joined.String(), "Parts should join to the original content")
+
+		// More comment terminators than the size of a part
+		split.Code = strings.Repeat("a = b*/c;\n", 20000)
+		parts, err = SplitDOC(split)
+		assert.NoError(t, err, "Splitting DOC with many comment terminators should not error: %s", err)
+		joined.Reset()
+		for _, part := range parts {
+			part.Signature = sizingSignature()
+			_, err = NewInstallArgs(&part)
+			assert.NoError(t, err, "Escaped part %d should install: %s", part.Part, err)
+			joined.WriteString(part.Code)
+		}
+
+		assert.Equal(t, split.Code, joined.String(), "Parts should join to the original content")
+		escapedSize := GetCodeSizeInKB(escapeDocCode(split.Code))
+		assert.LessOrEqual(t, float64(len(parts)), math.Ceil(escapedSize/(MAX_DOC_CODE_SIZE*0.9)), "Parts should only be sized with their own escaping")
+
+		_, err = splitCode("code", 0, false)
+		assert.Error(t, err, "Splitting with no chunk size should error")
+		_, err = splitCode("ü", 1, false)
+		assert.Error(t, err, "Splitting with a chunk smaller than a character should error")
+		_, err = splitCode("*/", 1, true)
+		assert.Error(t, err, "Splitting with a chunk smaller than its escaping should error")
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "app")
+		var large strings.Builder
+		for i := 0; GetCodeSizeInKB(large.String()) < MAX_DOC_CODE_SIZE*3; i++ {
+			fmt.Fprintf(&large, "const h%d = \"%x\";\n", i, sha256.Sum256([]byte(fmt.Sprint(i))))
+		}
+
+		files := map[string]string{
+			"index.html":      "<html><body><p>Plan</p></body></html>",
+			"about.html":      "<html><body><p>About</p></body></html>",
+			"style.css":       "p { color: red; }",
+			"js/main.js":      "const re = /ab*/g;",
+			"js/large.js":     large.String(),
+			"assets/logo.png": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff",
+			"notes":           "No docType",
+			"empty.txt":       " \n",
+			"bin.html":        "<html>\x00\xff</html>",
+			".env":            "KEY=value",
+			".git/HEAD":       "ref: refs/heads/main",
+		}
+
+		for name, content := range files {
+			filePath := filepath.Join(dir, filepath.FromSlash(name))
+			err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+			assert.NoError(t, err, "Creating directory should not error: %s", err)
+			err = os.WriteFile(filePath, []byte(content), 0644)
+			assert.NoError(t, err, "Writing file should not error: %s", err)
+		}
+
+		plan, err := PlanInstall(dir, endpoint, PlanConfig{DURL: "plan.tela"})
+		if !assert.NoError(t, err, "Planning install should not error: %s", err) {
+			t.FailNow()
+		}
+
+		assert.Equal(t, "index.html", plan.Entrypoint, "Entrypoint should be index.html")
+		assert.Equal(t, "index.html", plan.DOCs[0].File, "Entrypoint should be DOC1")
+		assert.Equal(t, "app", plan.INDEX.NameHdr, "INDEX nameHdr should default to the directory name")
+		assert.Equal(t, len(plan.DOCs), plan.INDEX.DOCs, "INDEX should embed all DOCs")
+		assert.Equal(t, uint64(len(plan.DOCs)+1)*MINIMUM_GAS_FEE, plan.Gas, "Plan gas should be the total of all contracts")
+
+		planned := map[string]PlannedDOC{}
+		var parts []PlannedDOC
+		for _, doc := range plan.DOCs {
+			assert.LessOrEqual(t, doc.Size, MAX_DOC_INSTALL_SIZE, "%s should be below max DOC install size", doc.File)
+			assert.Equal(t, MINIMUM_GAS_FEE, doc.Gas, "%s gas should be estimated", doc.File)
+			if doc.Parts > 0 {
+				parts = append(parts, doc)
+				continue
+			}
+
+			planned[doc.File] = doc
+		}
+
+		assert.Len(t, planned, 5, "Plan should have a DOC for each file that is not chunked")
+		assert.Equal(t, PlannedDOC{File: "js/main.js", DocType: DOC_JS, SubDir: "js", NameHdr: "main.js", Encoding: DOC_ENCODING_ESCAPED, Size: planned["js/main.js"].Size, Gas: MINIMUM_GAS_FEE}, planned["js/main.js"], "Planned DOC should have its docType, subDir and encoding")
+		assert.Equal(t, DOC_STATIC, planned["assets/logo.png"].DocType, "Binary file should be %s", DOC_STATIC)
+		assert.Equal(t, DOC_ENCODING_BASE64, planned["assets/logo.png"].Encoding, "Binary file should be base64 encoded")
+		assert.Equal(t, DOC_CSS, planned["style.css"].DocType, "CSS file should be %s", DOC_CSS)
+
+		if assert.Greater(t, len(parts), 1, "Large file should be chunked") {
+			for i, part := range parts {
+				assert.Equal(t, "js/large.js", part.File, "Parts should be of the large file")
+				assert.Equal(t, uint64(i+1), part.Part, "Parts should be in order")
+				assert.Equal(t, uint64(len(parts)), part.Parts, "Parts should have the total parts")
+			}
+		}
+
+		unsupported := map[string]string{}
+		for _, f := range plan.Unsupported {
+			unsupported[f.File] = f.Reason
+		}
+
+		assert.Equal(t, map[string]string{
+			".env":      "hidden file",
+			".git":      "hidden directory",
+			"bin.html":  "binary file is not TELA-STATIC-1",
+			"empty.txt": "file is empty",
+			"notes":     "no docType for file",
+		}, unsupported, "Unsupported files should be flagged")
+
+		// Files that cannot be read are unsupported in place of failing the plan
+		_, reason, err := planFile(dir, "missing.js", "plan.tela", func(rpc.Arguments) (uint64, error) { return 0, nil })
+		assert.NoError(t, err, "Planning file that cannot be read should not error: %s", err)
+		assert.True(t, strings.HasPrefix(reason, "file cannot be read"), "File that cannot be read should be unsupported: %s", reason)
+
+		// Plan can be exported as JSON for review
+		b, err := plan.JSON()
+		assert.NoError(t, err, "Exporting plan should not error: %s", err)
+		var exported InstallPlan
+		err = json.Unmarshal(b, &exported)
+		assert.NoError(t, err, "Exported plan should be valid JSON: %s", err)
+		assert.Equal(t, plan, exported, "Exported plan should match plan")
+
+		plan, err = PlanInstall(dir, "", PlanConfig{DURL: "plan.tela", Entrypoint: "about.html", Headers: Headers{NameHdr: "Plan"}})
+		assert.NoError(t, err, "Planning install without gas estimates should not error: %s", err)
+		assert.Equal(t, "about.html", plan.DOCs[0].File, "Entrypoint should be DOC1")
+		assert.Equal(t, "Plan", plan.INDEX.NameHdr, "INDEX should use its headers")
+		assert.Zero(t, plan.Gas, "Gas should not be estimated without an endpoint")
+
+		// Entrypoint defaults to the top most HTML file
+		err = os.Remove(filepath.Join(dir, "index.html"))
+		assert.NoError(t, err, "Removing file should not error: %s", err)
+		plan, err = PlanInstall(dir, "", PlanConfig{DURL: "plan.tela"})
+		assert.NoError(t, err, "Planning install should not error: %s", err)
+		assert.Equal(t, "about.html", plan.Entrypoint, "Entrypoint should be the first HTML file")
+
+		_, err = PlanInstall(dir, "", PlanConfig{DURL: "plan.tela", Entrypoint: "missing.html"})
+		assert.Error(t, err, "Planning install with a missing entrypoint should error")
+		_, err = PlanInstall(dir, "", PlanConfig{DURL: "plan/tela"})
+		assert.Error(t, err, "Planning install with an invalid dURL should error")
+		_, err = PlanInstall(filepath.Join(dir, "style.css"), "", PlanConfig{DURL: "plan.tela"})
+		assert.Error(t, err, "Planning install of a file should error")
+		_, err = PlanInstall(t.TempDir(), "", PlanConfig{DURL: "plan.tela"})
+		assert.Error(t, err, "Planning install of an empty directory should error")
+	})
+
+	t.Run("New", func(t *testing.T) {
+		_, err := New(Config{Path: filepath.Join(testPath, "does", "not", "exist")})
+		assert.Error(t, err, "New with invalid path should error")
+		_, err = New(Config{PortStart: 1})
+		assert.Error(t, err, "New with invalid port should error")
+		_, err = New(Config{Signatures: 99})
+		assert.Error(t, err, "New with invalid signature policy should error")
+
+		defaults, err := New(Config{})
+		assert.NoError(t, err, "New with zero config should not error: %s", err)
+		assert.Equal(t, DEFAULT_PORT_START, defaults.PortStart(), "Port start should be default")
+		assert.Equal(t, DEFAULT_MAX_SERVER, defaults.MaxServers(), "Max servers should be default")
+		assert.Equal(t, DEFAULT_MAX_WORKERS, defaults.MaxWorkers(), "Max workers should be default")
+		assert.Equal(t, SIGNATURE_WARN, defaults.GetSignaturePolicy(), "Signature policy should be default")
+		assert.False(t, defaults.UpdatesAllowed(), "Updates should not be allowed by default")
+
+		// Hosts should be independent of each other and the default host
+		var hosts []*TELA
+		for i, port := range []int{9082, 9182} {
+			path := filepath.Join(datashards, fmt.Sprintf("host%d", i))
+			err := os.MkdirAll(path, os.ModePerm)
+			if err != nil {
+				t.Fatalf("Could not create host directory: %s", err)
+			}
+
+			host, err := New(Config{Path: path, PortStart: port, MaxServers: 2, Updates: true, Signatures: SIGNATURE_ENFORCE, Daemon: memory})
+			if err != nil {
+				t.Fatalf("Could not create host %d: %s", i, err)
+			}
+
+			assert.Equal(t, filepath.Join(path, "datashards", "tela"), host.GetPath(), "Host path should be set")
+			assert.Equal(t, port, host.PortStart(), "Host port should be set")
+			assert.Equal(t, 2, host.MaxServers(), "Host max servers should be set")
+			assert.True(t, host.UpdatesAllowed(), "Host updates should be set")
+
+			link, err := host.ServeTELA(indexSCID, endpoint)
+			assert.NoError(t, err, "Host %d should serve INDEX: %s", i, err)
+			assert.Contains(t, link, fmt.Sprintf(":%d/", port), "Host %d should serve from its own port", i)
+			assert.True(t, host.HasServer(index.DURL), "Host %d should have server", i)
+
+			hosts = append(hosts, host)
+		}
+
+		assert.Empty(t, GetServerInfo(), "Default host should not have servers")
+		assert.NotEqual(t, GetPath(), hosts[0].GetPath(), "Default host path should not change")
+
+		hosts[0].ShutdownTELA()
+		assert.Empty(t, hosts[0].GetServerInfo(), "Host 0 should be shutdown")
+		assert.Len(t, hosts[1].GetServerInfo(), 1, "Host 1 should still be serving")
+		_, err = os.Stat(filepath.Join(hosts[1].GetPath(), index.DURL))
+		assert.NoError(t, err, "Host 1 files should remain: %s", err)
+
+		hosts[1].ShutdownTELA()
+		assert.Empty(t, hosts[1].GetServerInfo(), "Host 1 should be shutdown")
+	})
+
+	t.Run("GetRating", func(t *testing.T) {
+		err := memory.StoreString(indexSCID, owner, "92_100")
+		assert.NoError(t, err, "Storing rating should not error: %s", err)
+		err = memory.StoreString(indexSCID, "likes", uint64(1))
+		assert.NoError(t, err, "Storing likes should not error: %s", err)
+
+		ratings, err := GetRating(indexSCID, endpoint, 0)
+		assert.NoError(t, err, "GetRating should not error: %s", err)
+		assert.Equal(t, uint64(1), ratings.Likes, "Likes should be 1")
+		assert.Equal(t, uint64(0), ratings.Dislikes, "Dislikes should be 0")
+		if assert.Len(t, ratings.Ratings, 1, "Should have one rating") {
+			assert.Equal(t, Rating{Address: owner, Rating: 92, Height: 100}, ratings.Ratings[0], "Rating should be equal")
+		}
+		assert.Equal(t, float64(9), ratings.Average, "Average should be 9")
+
+		ratings, err = GetRating(indexSCID, endpoint, 101)
+		assert.NoError(t, err, "GetRating should not error: %s", err)
+		assert.Empty(t, ratings.Ratings, "Ratings should be filtered by height")
+
+		memory.DeleteString(indexSCID, owner)
+		ratings, err = GetRating(indexSCID, endpoint, 0)
+		assert.NoError(t, err, "GetRating should not error: %s", err)
+		assert.Empty(t, ratings.Ratings, "Rating should have been deleted")
+
+		_, err = GetRating(scDoesNotExist, endpoint, 0)
+		assert.Error(t, err, "GetRating on SCID that does not exist should error")
+	})
+}
+
+// MemoryDaemon which does not respond to GetSC until ctx is done
+type stalledDaemon struct {
+	*MemoryDaemon
+}
+
+func (d *stalledDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	<-ctx.Done()
+	err = ctx.Err()
+
+	return
+}
+
+// MemoryDaemon that can not be reached
+type failingDaemon struct {
+	*MemoryDaemon
+}
+
+func (d *failingDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	err = fmt.Errorf("connection refused")
+
+	return
+}
+
+// MemoryDaemon which does not respond to GetSC for scid until gate is closed
+type gatedDaemon struct {
+	*MemoryDaemon
+	scid string
+	gate chan struct{}
+}
+
+func (d *gatedDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	if params.SCID == d.scid {
+		select {
+		case <-d.gate:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+
+	return d.MemoryDaemon.GetSC(ctx, endpoint, params)
+}
+
+// MemoryDaemon which cancels its context after the first GetSC call succeeds
+type cancelingDaemon struct {
+	*MemoryDaemon
+	cancel context.CancelFunc
+}
+
+func (d *cancelingDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	result, err = d.MemoryDaemon.GetSC(ctx, endpoint, params)
+	d.cancel()
+
+	return
+}
+
+// MemoryDaemon which fails GetSC for each scid in delays once its delay has passed
+type delayedFailDaemon struct {
+	*MemoryDaemon
+	delays map[string]time.Duration
+}
+
+func (d *delayedFailDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	delay, ok := d.delays[params.SCID]
+	if !ok {
+		return d.MemoryDaemon.GetSC(ctx, endpoint, params)
+	}
+
+	select {
+	case <-time.After(delay):
+		err = fmt.Errorf("connection refused")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	return
+}
+
+// MemoryDaemon counting GetSC calls by SCID
+type countingDaemon struct {
+	*MemoryDaemon
+	sync.Mutex
+	calls     map[string]int
+	variables map[string]int // Calls for all variables, which include the SC code
+}
+
+func (c *countingDaemon) GetSC(ctx context.Context, endpoint string, params rpc.GetSC_Params) (rpc.GetSC_Result, error) {
+	c.Mutex.Lock()
+	c.calls[params.SCID]++
+	if params.Variables {
+		c.variables[params.SCID]++
+	}
+	c.Mutex.Unlock()
+
+	return c.MemoryDaemon.GetSC(ctx, endpoint, params)
+}
+
+// Add DOC to MemoryDaemon with the string keys its install would STORE
+func addMemoryDOC(memory *MemoryDaemon, scid, owner string, doc DOC) (err error) {
+	args, err := NewInstallArgs(&doc)
+	if err != nil {
+		return
+	}
+
+	code, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+
+	keys := map[string]interface{}{
+		HEADER_NAME.Trim():        doc.NameHdr,
+		HEADER_DESCRIPTION.Trim(): doc.DescrHdr,
+		HEADER_ICON_URL.Trim():    doc.IconHdr,
+		HEADER_DURL.Trim():        doc.DURL,
+		HEADER_DOCTYPE.Trim():     doc.DocType,
+		HEADER_SUBDIR.Trim():      doc.SubDir,
+		HEADER_CHECK_C.Trim():     doc.CheckC,
+		HEADER_CHECK_S.Trim():     doc.CheckS,
+		HEADER_OWNER.Trim():       owner,
+		"docVersion":              "1.0.0",
+		"hash":                    scid,
+		"likes":                   uint64(0),
+		"dislikes":                uint64(0),
+	}
+
+	if doc.Parts > 0 {
+		keys[HEADER_PART.Trim()] = doc.Part
+		keys[HEADER_PARTS.Trim()] = doc.Parts
+	}
+
+	if _, encoding, _ := encodeDocCode(doc.Code, doc.Encoding); encoding != "" {
+		keys[HEADER_ENCODING.Trim()] = encoding
+	}
+
+	return memory.AddSC(scid, code, keys)
+}
+
+// Add a contract file to MemoryDaemon with the string keys its install would STORE
+func addMemoryContract(memory *MemoryDaemon, scid, path, file string) (err error) {
+	code, err := readFile(path, file)
+	if err != nil {
+		return
+	}
+
+	keys := map[string]interface{}{"hash": scid, "likes": uint64(0), "dislikes": uint64(0)}
+	for _, store := range regexp.MustCompile(`STORE\("(\w+)", "([^"]*)"\)`).FindAllStringSubmatch(code, -1) {
+		keys[store[1]] = store[2]
+	}
+
+	return memory.AddSC(scid, code, keys)
+}
+
+// Add INDEX to MemoryDaemon with the string keys its install would STORE
+func addMemoryINDEX(memory *MemoryDaemon, scid, owner string, index INDEX) (err error) {
+	args, err := NewInstallArgs(&index)
+	if err != nil {
+		return
+	}
+
+	code, _ := args.Value(rpc.SCCODE, rpc.DataString).(string)
+
+	keys := map[string]interface{}{
+		HEADER_NAME.Trim():        index.NameHdr,
+		HEADER_DESCRIPTION.Trim(): index.DescrHdr,
+		HEADER_ICON_URL.Trim():    index.IconHdr,
+		HEADER_DURL.Trim():        index.DURL,
+		HEADER_OWNER.Trim():       owner,
+		"telaVersion":             "1.0.0",
+		"commit":                  uint64(0),
+		"hash":                    scid,
+		"likes":                   uint64(0),
+		"dislikes":                uint64(0),
+	}
+
+	for i, doc := range index.DOCs {
+		keys[HEADER_DOCUMENT.Number(i+1).Trim()] = doc
+	}
+
+	err = memory.AddSC(scid, code, keys)
+	if err != nil {
+		return
+	}
+
+	// Install is commit 0
+	memory.AddTransaction(scid, hex.EncodeToString([]byte(code)), 1)
+
+	return memory.StoreUint64(scid, 0, scid)
+}
+
+// Update INDEX on MemoryDaemon with txid at height, adding the commit as UpdateCode would
+func updateMemoryINDEX(memory *MemoryDaemon, scid, txid string, height int64, commit uint64, index INDEX) (err error) {
+	index.SCID = scid
+	args, err := NewUpdateArgs(&index)
+	if err != nil {
+		return
+	}
+
+	code, _ := args.Value("code", rpc.DataString).(string)
+	memory.AddTransaction(txid, hex.EncodeToString([]byte(code)), height)
+
+	for key, value := range map[string]interface{}{"C": code, "commit": commit, "hash": txid} {
+		err = memory.StoreString(scid, key, value)
+		if err != nil {
+			return
+		}
+	}
+
+	return memory.StoreUint64(scid, commit, txid)
 }
 
 // Create test wallet for simulator
@@ -1361,7 +3867,7 @@ func retry(t *testing.T, print string, f func() (string, error)) (result string,
 // Watch if existing var STORE v changes value to e
 func varChanged(scid, v, e, endpoint string) (scv string, err error) {
 	for retry := 0; retry < 3; retry++ {
-		scv, err = getContractVar(scid, v, endpoint)
+		scv, err = tela.getContractVar(context.Background(), scid, v, endpoint)
 		if err != nil {
 			return
 		}
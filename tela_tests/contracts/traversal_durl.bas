@@ -0,0 +1,56 @@
+Function InitializePrivate() Uint64
+10 IF init() == 0 THEN GOTO 30
+20 RETURN 1
+30 STORE("nameHdr", "Traversal")
+31 STORE("descrHdr", "")
+32 STORE("iconURLHdr", "")
+33 STORE("dURL", "../traversal.tela")
+40 STORE("DOC1", "0000000000000000000000000000000000000000000000000000000000000001")
+1000 RETURN 0
+End Function
+
+Function init() Uint64
+10 IF EXISTS("owner") == 0 THEN GOTO 30
+20 RETURN 1
+30 STORE("owner", address())
+50 STORE("telaVersion", "1.0.0")
+60 STORE("commit", 0)
+70 STORE(0, HEX(TXID()))
+80 STORE("hash", HEX(TXID()))
+85 STORE("likes", 0)
+90 STORE("dislikes", 0)
+100 RETURN 0
+End Function
+
+Function address() String
+10 DIM s as String
+20 LET s = SIGNER()
+30 IF IS_ADDRESS_VALID(s) THEN GOTO 50
+40 RETURN "anon"
+50 RETURN ADDRESS_STRING(s)
+End Function
+
+Function Rate(r Uint64) Uint64
+10 DIM addr as String
+15 LET addr = address()
+16 IF r < 100 && EXISTS(addr) == 0 && addr != "anon" THEN GOTO 30
+20 RETURN 1
+30 STORE(addr, ""+r+"_"+BLOCK_HEIGHT())
+40 IF r < 50 THEN GOTO 70
+50 STORE("likes", LOAD("likes")+1)
+60 RETURN 0
+70 STORE("dislikes", LOAD("dislikes")+1)
+100 RETURN 0
+End Function
+
+Function UpdateCode(code String) Uint64
+10 IF LOAD("owner") == "anon" THEN GOTO 20
+15 IF code == "" THEN GOTO 20
+16 IF LOAD("owner") == address() THEN GOTO 30
+20 RETURN 1
+30 UPDATE_SC_CODE(code)
+40 STORE("commit", LOAD("commit")+1)
+50 STORE(LOAD("commit"), HEX(TXID()))
+60 STORE("hash", HEX(TXID()))
+100 RETURN 0
+End Function
\ No newline at end of file
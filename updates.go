@@ -0,0 +1,320 @@
+package tela
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/civilware/tela/shards"
+	"github.com/deroproject/derohe/rpc"
+)
+
+// Update policy of a TELA-INDEX, it decides which commit of the INDEX is cloned and served
+type UpdatePolicy uint8
+
+const (
+	UPDATE_DEFAULT UpdatePolicy = iota // Updated content is used if AllowUpdates is true, otherwise only the original install is used
+	UPDATE_LATEST                      // Latest commit is always used
+	UPDATE_PIN                         // Pinned commit is always used
+	UPDATE_MANUAL                      // Approved commit is used, each new commit must be approved with ApproveUpdate
+)
+
+// Update rule for a TELA-INDEX SCID or author
+type UpdateRule struct {
+	Policy UpdatePolicy `json:"policy"`           // Update policy of the rule
+	Commit string       `json:"commit,omitempty"` // Pinned or approved commit TXID of a SCID rule, empty is the original install
+}
+
+// Update rules of a TELA host, stored in the datashards at the host's path
+type updateRules struct {
+	sync.Mutex
+	path    string // Datashards path the rules are stored in
+	loaded  bool
+	scids   map[string]UpdateRule
+	authors map[string]UpdateRule
+}
+
+// Stored format of updateRules
+type storedUpdateRules struct {
+	SCIDs   map[string]UpdateRule `json:"scids"`
+	Authors map[string]UpdateRule `json:"authors"`
+}
+
+// Shards tree and key the update rules are stored under
+const updateRulesTree = "updates"
+const updateRulesKey = "rules"
+
+// Returns the string representation of UpdatePolicy
+func (p UpdatePolicy) String() string {
+	switch p {
+	case UPDATE_DEFAULT:
+		return "default"
+	case UPDATE_LATEST:
+		return "latest"
+	case UPDATE_PIN:
+		return "pin"
+	case UPDATE_MANUAL:
+		return "manual"
+	default:
+		return "unknown"
+	}
+}
+
+// Parse a string for its UpdatePolicy
+func ParseUpdatePolicy(policy string) (p UpdatePolicy, err error) {
+	switch strings.ToLower(policy) {
+	case "default":
+		p = UPDATE_DEFAULT
+	case "latest":
+		p = UPDATE_LATEST
+	case "pin":
+		p = UPDATE_PIN
+	case "manual":
+		p = UPDATE_MANUAL
+	default:
+		err = fmt.Errorf("unknown update policy %q", policy)
+	}
+
+	return
+}
+
+// Get the commit the rule will use for scid, the original install is used if no commit is set
+func (r UpdateRule) target(scid string) string {
+	if r.Commit == "" {
+		return scid
+	}
+
+	return r.Commit
+}
+
+// Check if the rule allows commit of scid to be used, updates is the AllowUpdates value of the host
+func (r UpdateRule) allows(scid, commit string, updates bool) bool {
+	switch r.Policy {
+	case UPDATE_LATEST:
+		return true
+	case UPDATE_PIN, UPDATE_MANUAL:
+		return commit == r.target(scid)
+	default:
+		return updates || commit == scid
+	}
+}
+
+// Load the update rules from the datashards at path if they have not been loaded, caller must hold the lock
+func (u *updateRules) load() {
+	if u.loaded {
+		return
+	}
+
+	u.loaded = true
+	u.scids = map[string]UpdateRule{}
+	u.authors = map[string]UpdateRule{}
+
+	stored, err := shards.GetPathValue(u.path, updateRulesTree, []byte(updateRulesKey))
+	if err != nil || len(stored) == 0 {
+		return
+	}
+
+	var rules storedUpdateRules
+	if err := json.Unmarshal(stored, &rules); err != nil {
+		return
+	}
+
+	for scid, rule := range rules.SCIDs {
+		u.scids[scid] = rule
+	}
+
+	for author, rule := range rules.Authors {
+		u.authors[author] = rule
+	}
+}
+
+// Store the update rules in the datashards at path, caller must hold the lock
+func (u *updateRules) store() (err error) {
+	stored, err := json.Marshal(storedUpdateRules{SCIDs: u.scids, Authors: u.authors})
+	if err != nil {
+		return
+	}
+
+	return shards.StorePathValue(u.path, updateRulesTree, []byte(updateRulesKey), stored)
+}
+
+// Set the rule for a SCID or author key, UPDATE_DEFAULT will remove the rule. The rules are stored in shards
+func (u *updateRules) set(key string, author bool, rule UpdateRule) (err error) {
+	u.Lock()
+	defer u.Unlock()
+
+	u.load()
+
+	rules := u.scids
+	if author {
+		rules = u.authors
+	}
+
+	previous, exists := rules[key]
+	if rule.Policy == UPDATE_DEFAULT {
+		delete(rules, key)
+	} else {
+		rules[key] = rule
+	}
+
+	if err = u.store(); err != nil {
+		// Keep rules in sync with what is stored
+		if exists {
+			rules[key] = previous
+		} else {
+			delete(rules, key)
+		}
+	}
+
+	return
+}
+
+// Get the rule for a SCID, if the SCID has no rule the rule for its author is used
+func (u *updateRules) get(scid, author string) (rule UpdateRule) {
+	u.Lock()
+	defer u.Unlock()
+
+	u.load()
+
+	if r, ok := u.scids[scid]; ok {
+		return r
+	}
+
+	if r, ok := u.authors[author]; ok {
+		return UpdateRule{Policy: r.Policy}
+	}
+
+	return
+}
+
+// Reset the rules so they are loaded from shards when next used
+func (u *updateRules) reset() {
+	u.Lock()
+	u.loaded = false
+	u.Unlock()
+}
+
+// Set the datashards path the rules are stored in, the rules are loaded from path when next used
+func (u *updateRules) setPath(path string) {
+	u.Lock()
+	u.path = path
+	u.loaded = false
+	u.Unlock()
+}
+
+// Validate the UpdatePolicy of rule
+func validUpdateRule(rule UpdateRule) (err error) {
+	switch rule.Policy {
+	case UPDATE_DEFAULT, UPDATE_LATEST:
+		if rule.Commit != "" {
+			err = fmt.Errorf("commit cannot be set for %s update policy", rule.Policy)
+		}
+	case UPDATE_PIN, UPDATE_MANUAL:
+	default:
+		err = fmt.Errorf("unknown update policy %d", rule.Policy)
+	}
+
+	return
+}
+
+// SetUpdateRule sets the update rule of a TELA-INDEX SCID, it takes priority over any rule for the SCID's author.
+// UPDATE_PIN and UPDATE_MANUAL rules will use their Commit or the original install if no Commit is set,
+// UPDATE_DEFAULT removes the SCID's rule. Rules are stored in the datashards of the TELA host's path
+func (t *TELA) SetUpdateRule(scid string, rule UpdateRule) (err error) {
+	if len(scid) != 64 {
+		err = newError(ErrInvalidSCID, scid, "", "", fmt.Errorf("invalid INDEX SCID"))
+		return
+	}
+
+	if rule.Commit != "" && len(rule.Commit) != 64 {
+		err = newError(ErrInvalidCommit, scid, "", rule.Commit, nil)
+		return
+	}
+
+	if err = validUpdateRule(rule); err != nil {
+		return
+	}
+
+	return t.rules.set(scid, false, rule)
+}
+
+// SetAuthorUpdateRule sets the update rule used for all TELA-INDEXs owned by author that do not have their own rule.
+// Commits are unique to each SCID so author rules cannot set a Commit, UPDATE_PIN will use the original install of each SCID
+// and UPDATE_MANUAL will use the original install until a commit is approved for the SCID. UPDATE_DEFAULT removes the author's rule
+func (t *TELA) SetAuthorUpdateRule(author string, rule UpdateRule) (err error) {
+	if author != "anon" {
+		if _, err = rpc.NewAddress(author); err != nil {
+			err = fmt.Errorf("invalid author address: %s", err)
+			return
+		}
+	}
+
+	if rule.Commit != "" {
+		err = fmt.Errorf("commit cannot be set for author update rules")
+		return
+	}
+
+	if err = validUpdateRule(rule); err != nil {
+		return
+	}
+
+	return t.rules.set(author, true, rule)
+}
+
+// ApproveUpdate approves commit TXID of a TELA-INDEX SCID, setting the SCID to UPDATE_MANUAL with txid as its approved commit
+func (t *TELA) ApproveUpdate(scid, txid string) (err error) {
+	if len(txid) != 64 {
+		err = newError(ErrInvalidCommit, scid, "", txid, nil)
+		return
+	}
+
+	return t.SetUpdateRule(scid, UpdateRule{Policy: UPDATE_MANUAL, Commit: txid})
+}
+
+// GetUpdateRule returns the update rule used for a TELA-INDEX SCID owned by author
+func (t *TELA) GetUpdateRule(scid, author string) UpdateRule {
+	return t.rules.get(scid, author)
+}
+
+// GetUpdateRules returns all SCID and author update rules
+func (t *TELA) GetUpdateRules() (scids, authors map[string]UpdateRule) {
+	t.rules.Lock()
+	defer t.rules.Unlock()
+
+	t.rules.load()
+
+	scids = make(map[string]UpdateRule, len(t.rules.scids))
+	for scid, rule := range t.rules.scids {
+		scids[scid] = rule
+	}
+
+	authors = make(map[string]UpdateRule, len(t.rules.authors))
+	for author, rule := range t.rules.authors {
+		authors[author] = rule
+	}
+
+	return
+}
+
+// Check that the libraries within dep are allowed by their update rule
+func (t *TELA) checkLibraryUpdates(dep *Dependency) (err error) {
+	for _, entry := range dep.entries {
+		lib := entry.library
+		if lib == nil {
+			continue
+		}
+
+		owner, _ := entry.state.value(HEADER_OWNER.Trim())
+		if !t.GetUpdateRule(lib.SCID, owner).allows(lib.SCID, lib.Commit, t.UpdatesAllowed()) {
+			err = newError(ErrUpdated, lib.SCID, lib.DURL, lib.Commit, fmt.Errorf("library is not allowed by its update rule"))
+			return
+		}
+
+		if err = t.checkLibraryUpdates(lib); err != nil {
+			return
+		}
+	}
+
+	return
+}